@@ -197,8 +197,12 @@ func taskCollect(config limes.Configuration, cluster *limes.Cluster, args []stri
 
 	//start those collector threads which operate over all services simultaneously
 	c := collector.NewCollector(cluster, nil, config.Collector)
+	c.ApplyScheduledChange = api.ApplyScheduledChange
 	go c.CheckConsistency()
 	go c.ScanCapacity()
+	go c.ExpireQuotas()
+	go c.PurgeExpiredPendingChanges()
+	go c.ApplyScheduledChanges()
 	go func() {
 		for {
 			_, err := collector.ScanDomains(cluster, collector.ScanDomainsOpts{ScanAllProjects: true})
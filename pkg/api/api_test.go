@@ -27,8 +27,10 @@ import (
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	policy "github.com/databus23/goslo.policy"
+	"github.com/sapcc/limes/pkg/collector"
 	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/test"
@@ -43,6 +45,12 @@ func init() {
 }
 
 func setupTest(t *testing.T) (*limes.Cluster, http.Handler) {
+	return setupTestWithPolicy(t, nil)
+}
+
+//setupTestWithPolicy is like setupTest, but replaces the given rules in the
+//test policy.
+func setupTestWithPolicy(t *testing.T, policyOverrides map[string]string) (*limes.Cluster, http.Handler) {
 	//load test database
 	test.InitDatabase(t, "../test/migrations")
 	test.ExecSQLFile(t, "fixtures/start-data.sql")
@@ -87,6 +95,16 @@ func setupTest(t *testing.T) (*limes.Cluster, http.Handler) {
 				},
 			},
 		},
+		Ratios: []limes.QuotaRatioConstraint{
+			{
+				ServiceType:     "unshared",
+				ResourceName:    "capacity",
+				RefServiceType:  "unshared",
+				RefResourceName: "things",
+				IsUpperBound:    true,
+				Factor:          4,
+			},
+		},
 	}
 
 	config := limes.Configuration{
@@ -100,6 +118,15 @@ func setupTest(t *testing.T) (*limes.Cluster, http.Handler) {
 				CapacityPlugins:  map[string]limes.CapacityPlugin{},
 				Config:           &limes.ClusterConfiguration{Auth: &limes.AuthParameters{}},
 				QuotaConstraints: &westConstraintSet,
+				QuotaClasses: map[string]limes.QuotaClass{
+					"small": {
+						"shared":   {"capacity": 2, "things": 5},
+						"unshared": {"things": 5},
+					},
+					"large": {
+						"shared": {"capacity": 100},
+					},
+				},
 			},
 			"east": {
 				ID:              "east",
@@ -122,6 +149,9 @@ func setupTest(t *testing.T) (*limes.Cluster, http.Handler) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	for rule, value := range policyOverrides {
+		policyRules[rule] = value
+	}
 	config.API.PolicyEnforcer, err = policy.NewEnforcer(policyRules)
 	if err != nil {
 		t.Fatal(err)
@@ -611,6 +641,27 @@ func Test_DomainOperations(t *testing.T) {
 		},
 	}.Check(t, router)
 
+	//check PutDomain error cases because of ratio constraints
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change unshared/capacity quota: ratio constraint \"unshared/capacity: at most 4 B per unshared/things\" is violated (unshared/capacity quota would be 201 B, unshared/things quota would be 50)\n"),
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{
+						"type": "unshared",
+						"resources": []object{
+							//should fail because of "at most 4 B per unshared/things" constraint
+							{"name": "capacity", "quota": 201},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+
 	//check PutDomain happy path
 	test.APIRequest{
 		Method:           "PUT",
@@ -648,6 +699,42 @@ func Test_DomainOperations(t *testing.T) {
 		},
 	}.Check(t, router)
 	expectDomainQuota(t, "germany", "shared", "capacity", 1<<20)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "capacity", "quota": 1.5, "unit": limes.UnitMebibytes},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+	expectDomainQuota(t, "germany", "shared", "capacity", 3<<19)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 400,
+		ExpectBody:       p2s("request body is not valid JSON: invalid quota value for shared/capacity: value of 0.5 B cannot be represented as integer number of B\n"),
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "capacity", "quota": 0.5, "unit": limes.UnitBytes},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
 
 	//check PutDomain on a missing domain quota (see issue #36)
 	test.APIRequest{
@@ -700,6 +787,20 @@ func Test_ProjectOperations(t *testing.T) {
 		ExpectStatusCode: 200,
 		ExpectJSON:       "./fixtures/project-get-berlin.json",
 	}.Check(t, router)
+	//check ?unit= (values that cannot be converted exactly stay in the native
+	//unit; incompatible units are ignored unless requested for a specific resource)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared&unit=KiB",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-unit-inexact.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared&unit=bit/s&unit=shared/things:MiB",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-unit-incompatible.json",
+	}.Check(t, router)
 	//check rendering of subresources
 	test.APIRequest{
 		Method:           "GET",
@@ -898,6 +999,26 @@ func Test_ProjectOperations(t *testing.T) {
 		},
 	}.Check(t, router)
 
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change unshared/things quota: ratio constraint \"unshared/capacity: at most 4 B per unshared/things\" is violated (unshared/capacity quota would be 10 B, unshared/things quota would be 2)\n"),
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "unshared",
+						"resources": []object{
+							//should fail because unshared/capacity would exceed 4 B per unshared/things
+							{"name": "things", "quota": 2},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+
 	//check PutProject: quota admissible (i.e. will be persisted in DB), but
 	//SetQuota fails for some reason (e.g. backend service down)
 	plugin := cluster.QuotaPlugins["shared"].(*test.Plugin)
@@ -988,44 +1109,1455 @@ func Test_ProjectOperations(t *testing.T) {
 	if !reflect.DeepEqual(expectBackendQuota, backendQuota) {
 		t.Errorf("expected backend quota %#v, but got %#v", expectBackendQuota, backendQuota)
 	}
+
+	//check PutProject with temporary quota raises
+	makeTemporaryRaise := func(quota uint64, expiresAt int64) object {
+		return object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": quota, "expires_at": expiresAt},
+						},
+					},
+				},
+			},
+		}
+	}
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: expiry date must be in the future\n"),
+		RequestJSON:      makeTemporaryRaise(12, 1),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: an expiry date can only be given when raising the quota above 10\n"),
+		RequestJSON:      makeTemporaryRaise(9, 4000000000),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON:      makeTemporaryRaise(11, 3000000000),
+	}.Check(t, router)
+	//replacing a pending temporary raise keeps the original previous quota
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON:      makeTemporaryRaise(12, 4000000000),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared&resource=things",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-temporary-raise.json",
+	}.Check(t, router)
+	//setting a quota without expiry makes it permanent
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 11},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+	var pendingExpiries int
+	err = db.DB.QueryRow(`SELECT COUNT(*) FROM project_resources WHERE quota_expires_at IS NOT NULL`).Scan(&pendingExpiries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pendingExpiries != 0 {
+		t.Errorf("expected no pending quota expiries, but got %d", pendingExpiries)
+	}
 }
 
-func expectStaleProjectServices(t *testing.T, pairs ...string) {
-	queryStr := `
-		SELECT p.name, ps.type
-		  FROM projects p JOIN project_services ps ON ps.project_id = p.id
-		 WHERE ps.stale
-		 ORDER BY p.name, ps.type
-	`
-	var actualPairs []string
+func Test_UnitConversion(t *testing.T) {
+	_, router := setupTest(t)
 
-	err := db.ForeachRow(db.DB, queryStr, nil, func(rows *sql.Rows) error {
-		var (
-			projectName string
-			serviceType string
-		)
-		err := rows.Scan(&projectName, &serviceType)
+	//make all capacity values representable in KiB, so that ?unit=KiB succeeds
+	for _, query := range []string{
+		`UPDATE project_resources SET quota = quota * 1024, usage = usage * 1024, backend_quota = backend_quota * 1024 WHERE name = 'capacity'`,
+		`UPDATE domain_resources SET quota = quota * 1024 WHERE name = 'capacity'`,
+		`UPDATE cluster_resources SET capacity = capacity * 1024 WHERE name = 'capacity'`,
+		//also check conversion of backend quota (which is only shown if it differs from quota)
+		`UPDATE project_resources SET backend_quota = 12288 WHERE service_id = 2 AND name = 'capacity'`,
+	} {
+		_, err := db.DB.Exec(query)
 		if err != nil {
-			return err
+			t.Fatal(err)
 		}
-		actualPairs = append(actualPairs, fmt.Sprintf("%s:%s", projectName, serviceType))
-		return nil
-	})
-	if err != nil {
-		t.Fatal(err.Error())
 	}
 
-	if !reflect.DeepEqual(pairs, actualPairs) {
-		t.Errorf("expected stale project services %v, but got %v", pairs, actualPairs)
-	}
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?unit=KiB",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-unit-kib.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany?unit=KiB",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/domain-get-germany-unit-kib.json",
+	}.Check(t, router)
+	//the resource-specific form only converts the given resource
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/clusters/west?unit=shared/capacity:KiB",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/cluster-get-west-unit-kib.json",
+	}.Check(t, router)
 }
 
-//p2s makes a "pointer to string".
-func p2s(val string) *string {
-	return &val
+func Test_RelativeConstraints(t *testing.T) {
+	cluster, router := setupTest(t)
+
+	//Dresden has 2 usage on both things resources, and Germany has a domain
+	//quota of 30 for shared/things
+	cluster.QuotaConstraints = &limes.QuotaConstraintSet{
+		Projects: map[string]map[string]limes.QuotaConstraints{
+			"germany": {
+				"dresden": {
+					"shared": {
+						"things": {
+							RelativeMinimum: &limes.RelativeQuotaBound{Base: limes.RelativeToUsage, Percent: 150},
+							RelativeMaximum: &limes.RelativeQuotaBound{Base: limes.RelativeToDomainQuota, Percent: 50},
+						},
+					},
+					"unshared": {
+						//the relative lower bound (4) exceeds the absolute upper bound (3),
+						//so the absolute bound wins
+						"things": {
+							Maximum:         p2u64(3),
+							RelativeMinimum: &limes.RelativeQuotaBound{Base: limes.RelativeToUsage, Percent: 200},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	makeRequest := func(serviceType string, quota uint64) object {
+		return object{
+			"project": object{
+				"services": []object{
+					{
+						"type": serviceType,
+						"resources": []object{
+							{"name": "things", "quota": quota},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	//check PutProject error cases because of relative constraints
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: requested value \"2\" contradicts constraint \"at least usage + 50%, at most 50% of domain quota\" for this project and resource\n"),
+		RequestJSON:      makeRequest("shared", 2),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: requested value \"16\" contradicts constraint \"at least usage + 50%, at most 50% of domain quota\" for this project and resource\n"),
+		RequestJSON:      makeRequest("shared", 16),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change unshared/things quota: requested value \"4\" contradicts constraint \"at least 2x usage, at most 3\" for this project and resource\n"),
+		RequestJSON:      makeRequest("unshared", 4),
+	}.Check(t, router)
+
+	//check PutProject happy path with relative constraints
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden",
+		ExpectStatusCode: 200,
+		RequestJSON:      makeRequest("shared", 15),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden",
+		ExpectStatusCode: 200,
+		RequestJSON:      makeRequest("unshared", 3),
+	}.Check(t, router)
 }
 
-//p2u64 makes a "pointer to uint64".
-func p2u64(val uint64) *uint64 {
+func Test_QuotaClasses(t *testing.T) {
+	_, router := setupTest(t)
+
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/quota-classes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-classes-list.json",
+	}.Check(t, router)
+
+	//check error cases
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-classes/medium/apply",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such quota class\n"),
+		RequestJSON:      object{"projects": []string{"uuid-for-berlin"}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-classes/small/apply",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("no projects given\n"),
+		RequestJSON:      object{"projects": []string{}},
+	}.Check(t, router)
+
+	//apply a class to multiple projects (Dresden has a constraint that
+	//conflicts with the class, and Paris is in a different domain)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-classes/small/apply",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-class-apply-small.json",
+		RequestJSON:      object{"projects": []string{"uuid-for-berlin", "uuid-for-dresden", "uuid-for-paris"}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-quota-class.json",
+	}.Check(t, router)
+
+	//changing a quota manually makes the project drift from its class
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 7},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-quota-class-drifted.json",
+	}.Check(t, router)
+
+	//a failed application does not change the project's quota class
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-classes/large/apply",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-class-apply-large.json",
+		RequestJSON:      object{"projects": []string{"uuid-for-berlin"}},
+	}.Check(t, router)
+	var quotaClass string
+	err := db.DB.QueryRow(`SELECT quota_class FROM projects WHERE uuid = 'uuid-for-berlin'`).Scan(&quotaClass)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quotaClass != "small" {
+		t.Errorf("expected Berlin to stay on quota class \"small\", but got %q", quotaClass)
+	}
+}
+
+func Test_TemporaryQuotaRaiseClock(t *testing.T) {
+	cluster, router := setupTest(t)
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+	test.ResetTime()
+
+	//the expiry date is checked against the same clock as everything else
+	//(with the real clock, both expiry dates would be in the past)
+	makeTemporaryRaise := func(expiresAt int64) object {
+		return object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 12, "expires_at": expiresAt},
+						},
+					},
+				},
+			},
+		}
+	}
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: expiry date must be in the future\n"),
+		RequestJSON:      makeTemporaryRaise(0),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON:      makeTemporaryRaise(1000),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 40, "expires_at": 1000},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//setting the raised value again without expiry date makes it permanent
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 12},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{
+						"type": "shared",
+						"resources": []object{
+							{"name": "things", "quota": 40},
+						},
+					},
+				},
+			},
+		},
+	}.Check(t, router)
+	var pendingExpiries int
+	err := db.DB.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM project_resources WHERE quota_expires_at IS NOT NULL OR previous_quota IS NOT NULL)
+		     + (SELECT COUNT(*) FROM domain_resources WHERE quota_expires_at IS NOT NULL OR previous_quota IS NOT NULL)
+	`).Scan(&pendingExpiries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pendingExpiries != 0 {
+		t.Errorf("expected no pending quota expiries, but got %d", pendingExpiries)
+	}
+
+	//the collector does not revert the quotas after the former expiry date
+	c := collector.Collector{
+		Cluster:  cluster,
+		LogError: t.Errorf,
+		TimeNow:  func() time.Time { return time.Unix(2000, 0) },
+		Once:     true,
+	}
+	c.ExpireQuotas()
+	var projectQuota, domainQuota uint64
+	err = db.DB.QueryRow(`SELECT quota FROM project_resources WHERE service_id = 2 AND name = 'things'`).Scan(&projectQuota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DB.QueryRow(`SELECT quota FROM domain_resources WHERE service_id = 2 AND name = 'things'`).Scan(&domainQuota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projectQuota != 12 || domainQuota != 40 {
+		t.Errorf("expected quotas to stay at 12 (project) and 40 (domain), but got %d and %d", projectQuota, domainQuota)
+	}
+}
+
+//applyScheduledChanges runs the collector job for scheduled changes once, as
+//if it was the given point in time. (Failed changes are logged, but are not
+//test failures by themselves.)
+func applyScheduledChanges(t *testing.T, cluster *limes.Cluster, now time.Time) {
+	c := collector.Collector{
+		Cluster:              cluster,
+		LogError:             t.Logf,
+		TimeNow:              func() time.Time { return now },
+		Once:                 true,
+		ApplyScheduledChange: ApplyScheduledChange,
+	}
+	c.ApplyScheduledChanges()
+}
+
+func Test_ScheduledChanges(t *testing.T) {
+	cluster, router := setupTest(t)
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+	test.ResetTime()
+
+	makeChange := func(service, resource string, value, delta *int64, dueAt int64, comment string) object {
+		change := object{"service": service, "resource": resource, "due_at": dueAt}
+		if value != nil {
+			change["value"] = *value
+		}
+		if delta != nil {
+			change["delta"] = *delta
+		}
+		if comment != "" {
+			change["comment"] = comment
+		}
+		return object{"scheduled_change": change}
+	}
+
+	//schedule some changes that will succeed...
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 201,
+		ExpectJSON:       "./fixtures/scheduled-change-create.json",
+		RequestJSON:      makeChange("shared", "things", p2i64(40), nil, 1000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/scheduled-changes",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeChange("shared", "things", nil, p2i64(5), 2000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/clusters/current/scheduled-changes",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeChange("shared", "capacity", nil, p2i64(15), 1000, "new hardware"),
+	}.Check(t, router)
+
+	//...and some that will fail during application
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeChange("shared", "capacity", nil, p2i64(-100), 1000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/scheduled-changes",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeChange("shared", "capacity", p2i64(20), nil, 1000, ""),
+	}.Check(t, router)
+
+	//check validation of new changes
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot schedule shared/things change: exactly one of \"value\" and \"delta\" must be given\ncannot schedule shared/things change: due date must be in the future\n"),
+		RequestJSON:      makeChange("shared", "things", p2i64(40), p2i64(10), 1, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot schedule shared/items change: no such resource\n"),
+		RequestJSON:      makeChange("shared", "items", p2i64(40), nil, 1000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/clusters/current/scheduled-changes",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot schedule shared/capacity change: comment is missing\n"),
+		RequestJSON:      makeChange("shared", "capacity", p2i64(200), nil, 1000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/clusters/unknown/scheduled-changes",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such cluster\n"),
+		RequestJSON:      makeChange("shared", "capacity", p2i64(200), nil, 1000, "test"),
+	}.Check(t, router)
+
+	//check cancellation of pending changes
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeChange("shared", "things", p2i64(50), nil, 5000, ""),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/scheduled-changes/6",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such scheduled change\n"),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes/6",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes/6",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such scheduled change\n"),
+	}.Check(t, router)
+
+	//apply the due changes (the project change is due later than the domain
+	//change, so the domain quota has already been raised when it is applied)
+	applyScheduledChanges(t, cluster, time.Unix(3000, 0))
+
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes/1",
+		ExpectStatusCode: 409,
+		ExpectBody:       p2s("scheduled change has already been processed\n"),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/scheduled-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/scheduled-changes-list-germany.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/scheduled-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/scheduled-changes-list-berlin.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/clusters/current/scheduled-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/scheduled-changes-list-west.json",
+	}.Check(t, router)
+
+	//check that the successful changes have been applied
+	expectQuota := func(query string, expected uint64) {
+		t.Helper()
+		var actual uint64
+		err := db.DB.QueryRow(query).Scan(&actual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("expected %q to return %d, but got %d", query, expected, actual)
+		}
+	}
+	expectQuota(`SELECT quota FROM domain_resources WHERE service_id = 2 AND name = 'things'`, 40)
+	expectQuota(`SELECT quota FROM domain_resources WHERE service_id = 2 AND name = 'capacity'`, 25)
+	expectQuota(`SELECT quota FROM project_resources WHERE service_id = 2 AND name = 'things'`, 15)
+	expectQuota(`SELECT capacity FROM cluster_resources WHERE service_id = 2 AND name = 'capacity'`, 200)
+}
+
+func Test_Commitments(t *testing.T) {
+	_, router := setupTest(t)
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+	test.ResetTime()
+
+	makeCommitment := func(service, resource string, amount uint64, duration string) object {
+		return object{"commitment": object{
+			"service":  service,
+			"resource": resource,
+			"amount":   amount,
+			"duration": duration,
+		}}
+	}
+
+	//happy path
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 201,
+		ExpectJSON:       "./fixtures/commitment-create.json",
+		RequestJSON:      makeCommitment("shared", "capacity", 6, "1 year"),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 201,
+		RequestJSON:      makeCommitment("unshared", "things", 3, "30 days"),
+	}.Check(t, router)
+
+	//check validation of new commitments
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit shared/capacity: amount must be positive\ncannot commit shared/capacity: invalid duration \"forever\" (expected something like \"1 year\", \"6 months\" or \"30 days\")\n"),
+		RequestJSON:      makeCommitment("shared", "capacity", 0, "forever"),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit shared/items: no such resource\n"),
+		RequestJSON:      makeCommitment("shared", "items", 1, "1 year"),
+	}.Check(t, router)
+	//project quota is 10 B, of which 6 B are already committed
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit shared/capacity: commitments may not exceed the project quota (maximum acceptable amount is 4 B)\n"),
+		RequestJSON:      makeCommitment("shared", "capacity", 5, "6 months"),
+	}.Check(t, router)
+	//there is no capacity value for unshared/capacity in this cluster
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit unshared/capacity: no capacity has been reported for this resource\n"),
+		RequestJSON:      makeCommitment("unshared", "capacity", 5, "1 year"),
+	}.Check(t, router)
+	//commitments of other projects count against the same capacity
+	_, err := db.DB.Exec(`UPDATE cluster_resources SET capacity = 10 WHERE service_id = 2 AND name = 'capacity'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-france/projects/uuid-for-paris/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit shared/capacity: not enough capacity (maximum acceptable amount is 4 B)\n"),
+		RequestJSON:      makeCommitment("shared", "capacity", 5, "1 year"),
+	}.Check(t, router)
+
+	//project quota may not be lowered below the active commitments
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/capacity quota: quota may not be lower than active commitments (6 B)\n"),
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "capacity", "quota": 5}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/commitments-list.json",
+	}.Check(t, router)
+
+	//check cancelling of commitments
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden/commitments/1",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such commitment\n"),
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/commitments/1",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "capacity", "quota": 5}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//commitments cannot be created before the consistency check has created
+	//the project_services record for a service
+	_, err = db.DB.Exec(`DELETE FROM project_services WHERE project_id = 2 AND type = 'unshared'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden/commitments",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot commit unshared/things: project has no data for this service yet\n"),
+		RequestJSON:      makeCommitment("unshared", "things", 1, "1 year"),
+	}.Check(t, router)
+}
+
+func Test_Headroom(t *testing.T) {
+	_, router := setupTest(t)
+
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/headroom",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/headroom-germany.json",
+	}.Check(t, router)
+	//france has domain constraints
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-france/headroom",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/headroom-france.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/headroom",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/headroom-berlin.json",
+	}.Check(t, router)
+	//dresden has project constraints that contradict its current quotas
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-dresden/headroom",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/headroom-dresden.json",
+	}.Check(t, router)
+
+	//the ratio constraint "unshared/capacity at most 4 B per unshared/things"
+	//puts a lower bound on the things quota once the capacity quota is raised
+	_, err := db.DB.Exec(`UPDATE project_resources SET quota = 30 WHERE service_id = 1 AND name = 'capacity'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/headroom",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/headroom-berlin-ratio.json",
+	}.Check(t, router)
+}
+
+func Test_QuotaLocks(t *testing.T) {
+	cluster, router := setupTest(t)
+
+	//check validation
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/lock",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("reason is missing\n"),
+		RequestJSON:      object{"lock": object{}},
+	}.Check(t, router)
+
+	//lock a domain and a project
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/lock",
+		ExpectStatusCode: 204,
+		RequestJSON:      object{"lock": object{"reason": "under review"}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/lock",
+		ExpectStatusCode: 204,
+		RequestJSON:      object{"lock": object{"reason": "legal hold"}},
+	}.Check(t, router)
+
+	//locks are shown in reports
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared&resource=things",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-locked.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany?service=shared&resource=things",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/domain-get-germany-locked.json",
+	}.Check(t, router)
+
+	//scheduled changes cannot bypass the locks
+	for _, change := range []db.ScheduledQuotaChange{
+		{DomainID: p2i64(1), ProjectID: p2i64(1), NewValue: p2u64(5)},
+		{DomainID: p2i64(1), NewValue: p2u64(35)},
+	} {
+		change.ClusterID = "west"
+		change.ServiceType = "shared"
+		change.ResourceName = "things"
+		change.DueAt = time.Unix(1000, 0).UTC()
+		change.CreatedAt = time.Unix(0, 0).UTC()
+		change.CanRaise = true
+		change.CanLower = true
+		err := db.DB.Insert(&change)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	applyScheduledChanges(t, cluster, time.Unix(2000, 0))
+	var failureReasons []string
+	_, err := db.DB.Select(&failureReasons, `SELECT failure_reason FROM scheduled_quota_changes ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedReasons := []string{
+		"cannot change quotas: quotas of this project are locked: legal hold",
+		"cannot change quotas: quotas of this domain are locked: under review",
+	}
+	if !reflect.DeepEqual(failureReasons, expectedReasons) {
+		t.Errorf("expected failure reasons %#v, but got %#v", expectedReasons, failureReasons)
+	}
+
+	//users who can manage locks can still change quotas (the test policy allows
+	//everything)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 5}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//remove the locks again
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/lock",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/domains/uuid-for-germany/lock",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	lockedCount, err := db.DB.SelectInt(`SELECT
+		(SELECT COUNT(*) FROM projects WHERE quota_locked OR quota_lock_reason != '') +
+		(SELECT COUNT(*) FROM domains WHERE quota_locked OR quota_lock_reason != '')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lockedCount != 0 {
+		t.Error("expected all locks to be removed")
+	}
+}
+
+func Test_QuotaComments(t *testing.T) {
+	cluster, router := setupTest(t)
+	test.ResetTime()
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+
+	//without comment thresholds, raising quotas does not require a comment
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 31}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//raises within the comment threshold do not require a comment either
+	cluster.CommentPolicy = &limes.CommentPolicy{
+		Thresholds: map[string]map[string]limes.ApprovalThreshold{
+			"shared": {"things": {RelativePermille: 200}},
+		},
+	}
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 32}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//raises above the comment threshold require a comment
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: a comment is required for raises of this size\n"),
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 15}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: a comment is required for raises of this size\n"),
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 40}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin/scheduled-changes",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot schedule shared/things change: comment is missing\n"),
+		RequestJSON: object{
+			"scheduled_change": object{"service": "shared", "resource": "things", "delta": 5, "due_at": 100000},
+		},
+	}.Check(t, router)
+
+	//lowering quotas does not require a comment
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "unshared", "resources": []object{{"name": "things", "quota": 5}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//comments can be given per resource or for the whole request, and the
+	//latest comment is stored with the resource
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"comment": "ticket 1234",
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 40}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"comment": "ticket 1234",
+				"services": []object{
+					{"type": "shared", "resources": []object{
+						{"name": "things", "quota": 15, "comment": "needed for migration"},
+						{"name": "capacity", "quota": 5},
+					}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin?service=shared",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/project-get-berlin-commented.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany?service=shared&resource=things",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/domain-get-germany-commented.json",
+	}.Check(t, router)
+
+	//a quota change without a comment clears the previous comment
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "capacity", "quota": 3}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	comment, err := db.DB.SelectStr(`SELECT quota_comment FROM project_resources WHERE service_id = 2 AND name = 'capacity'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if comment != "" {
+		t.Errorf("expected quota comment to be cleared, but got %q", comment)
+	}
+
+	//users can be exempted from the comment thresholds by policy
+	cluster, router = setupTestWithPolicy(t, map[string]string{
+		"project:raise_without_comment": "@",
+	})
+	cluster.CommentPolicy = &limes.CommentPolicy{
+		Thresholds: map[string]map[string]limes.ApprovalThreshold{
+			"shared": {"things": {RelativePermille: 200}},
+		},
+	}
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany/projects/uuid-for-berlin",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"project": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 15}}},
+				},
+			},
+		},
+	}.Check(t, router)
+}
+
+func Test_ApprovalThresholds(t *testing.T) {
+	cluster, router := setupTest(t)
+	test.ResetTime()
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+
+	cluster.ApprovalPolicy = &limes.ApprovalPolicy{
+		Thresholds: map[string]map[string]limes.ApprovalThreshold{
+			"shared": {
+				"capacity": {Absolute: 100},
+				"things":   {RelativePermille: 500},
+			},
+		},
+		ExpireAfter: 24 * time.Hour,
+	}
+
+	//small raises are applied immediately
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/clusters/west",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"cluster": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "capacity", "capacity": 200, "comment": "recounted"}}},
+				},
+			},
+		},
+	}.Check(t, router)
+
+	//large raises are held for approval
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/clusters/west?service=shared&resource=capacity",
+		ExpectStatusCode: 202,
+		ExpectJSON:       "./fixtures/cluster-put-pending.json",
+		RequestJSON: object{
+			"cluster": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "capacity", "capacity": 400, "comment": "new hardware"}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany?service=shared",
+		ExpectStatusCode: 202,
+		ExpectJSON:       "./fixtures/domain-put-pending.json",
+		RequestJSON: object{
+			"domain": object{
+				"comment": "big customer",
+				"services": []object{
+					{"type": "shared", "resources": []object{
+						{"name": "things", "quota": 60},
+						{"name": "capacity", "quota": 20},
+					}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("cannot change shared/things quota: temporary quota raises cannot exceed the approval threshold\n"),
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 60, "expires_at": 100000}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/clusters/west/pending-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/pending-changes-cluster.json",
+	}.Check(t, router)
+
+	//pending changes cannot be confirmed by the user who requested them
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/pending-changes/2/confirm",
+		ExpectStatusCode: 403,
+		ExpectBody:       p2s("pending change must be confirmed by a different user\n"),
+	}.Check(t, router)
+
+	//confirm the domain quota change as a different user
+	_, err := db.DB.Exec(`UPDATE pending_quota_changes SET creator_uuid = 'uuid-for-alice', creator_name = 'alice'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/pending-changes/2/confirm",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	quota, err := db.DB.SelectInt(`SELECT quota FROM domain_resources WHERE service_id = 2 AND name = 'things'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quota != 60 {
+		t.Errorf("expected confirmed domain quota to be 60, but got %d", quota)
+	}
+
+	//reject the capacity change
+	test.APIRequest{
+		Method:           "DELETE",
+		Path:             "/v1/clusters/west/pending-changes/1",
+		ExpectStatusCode: 204,
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/clusters/west/pending-changes/1/confirm",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such pending change\n"),
+	}.Check(t, router)
+	capacity, err := db.DB.SelectInt(`SELECT capacity FROM cluster_resources WHERE service_id = 2 AND name = 'capacity'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capacity != 200 {
+		t.Errorf("expected capacity to remain at 200, but got %d", capacity)
+	}
+
+	//pending changes cannot be confirmed after they have expired
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 202,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 100, "comment": "even bigger customer"}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	_, err = db.DB.Exec(`UPDATE pending_quota_changes SET creator_uuid = 'uuid-for-alice', creator_name = 'alice', expires_at = created_at`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeID, err := db.DB.SelectInt(`SELECT id FROM pending_quota_changes`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/pending-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/pending-changes-domain-expired.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             fmt.Sprintf("/v1/domains/uuid-for-germany/pending-changes/%d/confirm", changeID),
+		ExpectStatusCode: 409,
+		ExpectBody:       p2s("pending change has expired\n"),
+	}.Check(t, router)
+
+	//pending changes cannot be confirmed after the value has been changed by
+	//someone else
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 202,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 150, "comment": "biggest customer"}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	_, err = db.DB.Exec(`UPDATE pending_quota_changes SET creator_uuid = 'uuid-for-alice', creator_name = 'alice'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.DB.Exec(`UPDATE domain_resources SET quota = 70 WHERE service_id = 2 AND name = 'things'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeID, err = db.DB.SelectInt(`SELECT MAX(id) FROM pending_quota_changes`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.APIRequest{
+		Method:           "POST",
+		Path:             fmt.Sprintf("/v1/domains/uuid-for-germany/pending-changes/%d/confirm", changeID),
+		ExpectStatusCode: 409,
+		ExpectBody:       p2s("pending change is outdated: value has been changed from 60 to 70 since the change was requested\n"),
+	}.Check(t, router)
+
+	//scheduled changes cannot bypass the approval
+	change := db.ScheduledQuotaChange{
+		ClusterID:    "west",
+		DomainID:     p2i64(1),
+		ServiceType:  "shared",
+		ResourceName: "things",
+		NewValue:     p2u64(200),
+		DueAt:        time.Unix(1000, 0).UTC(),
+		CreatedAt:    time.Unix(0, 0).UTC(),
+		CanRaise:     true,
+		CanLower:     true,
+	}
+	err = db.DB.Insert(&change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyScheduledChanges(t, cluster, time.Unix(2000, 0))
+	failureReason, err := db.DB.SelectStr(`SELECT failure_reason FROM scheduled_quota_changes WHERE id = $1`, change.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedReason := "cannot change shared/things: raise exceeds the approval threshold and must be confirmed by a second user"
+	if failureReason != expectedReason {
+		t.Errorf("expected failure reason %q, but got %q", expectedReason, failureReason)
+	}
+}
+
+func Test_QuotaChangeRevert(t *testing.T) {
+	cluster, router := setupTest(t)
+	test.ResetTime()
+	timeNow = test.TimeNow
+	defer func() { timeNow = time.Now }()
+
+	//make some quota changes that end up in the history
+	putProjectThings := func(projectUUID string, quota uint64) {
+		test.APIRequest{
+			Method:           "PUT",
+			Path:             "/v1/domains/uuid-for-germany/projects/" + projectUUID,
+			ExpectStatusCode: 200,
+			RequestJSON: object{
+				"project": object{
+					"services": []object{
+						{"type": "shared", "resources": []object{{"name": "things", "quota": quota}}},
+					},
+				},
+			},
+		}.Check(t, router)
+	}
+	putProjectThings("uuid-for-berlin", 5)
+	putProjectThings("uuid-for-berlin", 4)
+	test.APIRequest{
+		Method:           "PUT",
+		Path:             "/v1/domains/uuid-for-germany",
+		ExpectStatusCode: 200,
+		RequestJSON: object{
+			"domain": object{
+				"services": []object{
+					{"type": "shared", "resources": []object{{"name": "things", "quota": 28}}},
+				},
+			},
+		},
+	}.Check(t, router)
+	putProjectThings("uuid-for-dresden", 7)
+	_, err := db.DB.Exec(`UPDATE quota_change_events SET user_uuid = 'uuid-for-bob', user_name = 'bob' WHERE project_id = 2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putProjectThings("uuid-for-dresden", 8)
+
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-germany.json",
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "GET",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes?user=uuid-for-bob",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-germany-bob.json",
+	}.Check(t, router)
+
+	//invalid requests
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("exactly one of \"event_id\" and \"user_id\" must be given\n"),
+		RequestJSON:      object{"revert": object{}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 422,
+		ExpectBody:       p2s("\"since\" is missing\n"),
+		RequestJSON:      object{"revert": object{"user_id": "uuid-for-bob"}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such quota change event\n"),
+		RequestJSON:      object{"revert": object{"event_id": 42}},
+	}.Check(t, router)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-france/quota-changes/revert",
+		ExpectStatusCode: 404,
+		ExpectBody:       p2s("no such quota change event\n"),
+		RequestJSON:      object{"revert": object{"event_id": 1}},
+	}.Check(t, router)
+
+	//revert a single event
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-revert-event.json",
+		RequestJSON:      object{"revert": object{"event_id": 2}},
+	}.Check(t, router)
+
+	//quotas that have been changed again since are not reverted
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-revert-skipped.json",
+		RequestJSON:      object{"revert": object{"user_id": "uuid-for-bob", "since": 0}},
+	}.Check(t, router)
+
+	//reverting all changes by a user goes back to the quota before the first
+	//change, and runs through the usual validation
+	cluster.ApprovalPolicy = &limes.ApprovalPolicy{
+		Thresholds: map[string]map[string]limes.ApprovalThreshold{
+			"shared": {"things": {Absolute: 1}},
+		},
+		ExpireAfter: 24 * time.Hour,
+	}
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-revert-user.json",
+		RequestJSON:      object{"revert": object{"user_id": "", "since": 0, "comment": "undo accidental bulk change"}},
+	}.Check(t, router)
+
+	//the reverts themselves are recorded in the history
+	revertCount, err := db.DB.SelectInt(`SELECT COUNT(*) FROM quota_change_events WHERE via LIKE 'through revert of %'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revertCount != 3 {
+		t.Errorf("expected 3 recorded reverts, got %d", revertCount)
+	}
+
+	//when reverting all changes by a user, quotas that someone else changed in
+	//between are skipped
+	since := test.TimeNow().Unix()
+	putProjectThings("uuid-for-berlin", 6)
+	putProjectThings("uuid-for-berlin", 7)
+	_, err = db.DB.Exec(`UPDATE quota_change_events SET user_uuid = 'uuid-for-bob', user_name = 'bob' WHERE id = (SELECT MAX(id) FROM quota_change_events)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	putProjectThings("uuid-for-berlin", 8)
+	test.APIRequest{
+		Method:           "POST",
+		Path:             "/v1/domains/uuid-for-germany/quota-changes/revert",
+		ExpectStatusCode: 200,
+		ExpectJSON:       "./fixtures/quota-changes-revert-interleaved.json",
+		RequestJSON:      object{"revert": object{"user_id": "", "since": since}},
+	}.Check(t, router)
+}
+
+func expectStaleProjectServices(t *testing.T, pairs ...string) {
+	queryStr := `
+		SELECT p.name, ps.type
+		  FROM projects p JOIN project_services ps ON ps.project_id = p.id
+		 WHERE ps.stale
+		 ORDER BY p.name, ps.type
+	`
+	var actualPairs []string
+
+	err := db.ForeachRow(db.DB, queryStr, nil, func(rows *sql.Rows) error {
+		var (
+			projectName string
+			serviceType string
+		)
+		err := rows.Scan(&projectName, &serviceType)
+		if err != nil {
+			return err
+		}
+		actualPairs = append(actualPairs, fmt.Sprintf("%s:%s", projectName, serviceType))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !reflect.DeepEqual(pairs, actualPairs) {
+		t.Errorf("expected stale project services %v, but got %v", pairs, actualPairs)
+	}
+}
+
+//p2s makes a "pointer to string".
+func p2s(val string) *string {
+	return &val
+}
+
+//p2u64 makes a "pointer to uint64".
+func p2u64(val uint64) *uint64 {
+	return &val
+}
+
+//p2i64 makes a "pointer to int64".
+func p2i64(val int64) *int64 {
 	return &val
 }
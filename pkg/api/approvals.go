@@ -0,0 +1,488 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+//PendingChange is the API representation of a db.PendingQuotaChange.
+type PendingChange struct {
+	ID           int64           `json:"id"`
+	ServiceType  string          `json:"service"`
+	ResourceName string          `json:"resource"`
+	Unit         limes.Unit      `json:"unit,omitempty"`
+	OldValue     uint64          `json:"old_value"`
+	NewValue     uint64          `json:"new_value"`
+	Comment      string          `json:"comment,omitempty"`
+	CreatedAt    int64           `json:"created_at"`
+	CreatedBy    ScheduledByUser `json:"created_by"`
+	ExpiresAt    int64           `json:"expires_at"`
+	Status       string          `json:"status"`
+}
+
+func renderPendingChange(cluster *limes.Cluster, change db.PendingQuotaChange, now time.Time) PendingChange {
+	result := PendingChange{
+		ID:           change.ID,
+		ServiceType:  change.ServiceType,
+		ResourceName: change.ResourceName,
+		Unit:         cluster.InfoForResource(change.ServiceType, change.ResourceName).Unit,
+		OldValue:     change.OldValue,
+		NewValue:     change.NewValue,
+		Comment:      change.Comment,
+		CreatedAt:    change.CreatedAt.Unix(),
+		CreatedBy: ScheduledByUser{
+			UUID: change.CreatorUUID,
+			Name: change.CreatorName,
+		},
+		ExpiresAt: change.ExpiresAt.Unix(),
+		Status:    "pending",
+	}
+	if !change.ExpiresAt.After(now) {
+		result.Status = "expired"
+	}
+	return result
+}
+
+func renderPendingChanges(cluster *limes.Cluster, changes []db.PendingQuotaChange) []PendingChange {
+	now := timeNow()
+	result := make([]PendingChange, len(changes))
+	for idx, change := range changes {
+		result[idx] = renderPendingChange(cluster, change, now)
+	}
+	return result
+}
+
+//pendingChangeTarget identifies the cluster or domain that pending changes
+//refer to.
+type pendingChangeTarget struct {
+	Cluster   *limes.Cluster
+	ClusterID string
+	Domain    *db.Domain
+}
+
+func (t pendingChangeTarget) whereClause() (string, []interface{}) {
+	if t.Domain != nil {
+		return `domain_id = $1`, []interface{}{t.Domain.ID}
+	}
+	return `cluster_id = $1 AND domain_id IS NULL`, []interface{}{t.ClusterID}
+}
+
+func newPendingChange(cluster *limes.Cluster, clusterID string, domainID *int64, serviceType, resourceName string, oldValue, newValue uint64, comment string, token *Token) db.PendingQuotaChange {
+	now := timeNow().UTC()
+	return db.PendingQuotaChange{
+		ClusterID:    clusterID,
+		DomainID:     domainID,
+		ServiceType:  serviceType,
+		ResourceName: resourceName,
+		OldValue:     oldValue,
+		NewValue:     newValue,
+		Comment:      comment,
+		CreatedAt:    now,
+		CreatorUUID:  token.UserUUID,
+		CreatorName:  token.UserName,
+		ExpiresAt:    now.Add(cluster.ApprovalPolicy.ExpireAfter),
+	}
+}
+
+func sortPendingChanges(changes []db.PendingQuotaChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].ServiceType != changes[j].ServiceType {
+			return changes[i].ServiceType < changes[j].ServiceType
+		}
+		return changes[i].ResourceName < changes[j].ResourceName
+	})
+}
+
+//getDomainQuota returns the current quota of the given domain resource, or 0
+//if the resource does not exist yet.
+func getDomainQuota(domainID int64, serviceType, resourceName string) (uint64, error) {
+	var quota uint64
+	err := db.DB.QueryRow(`
+		SELECT dr.quota FROM domain_resources dr JOIN domain_services ds ON ds.id = dr.service_id
+		 WHERE ds.domain_id = $1 AND ds.type = $2 AND dr.name = $3`,
+		domainID, serviceType, resourceName).Scan(&quota)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return quota, err
+}
+
+//getClusterCapacity returns the current capacity and comment of the given
+//cluster resource. If the resource does not exist yet, exists is false.
+func getClusterCapacity(cluster *limes.Cluster, clusterID, serviceType, resourceName string) (capacity uint64, comment string, exists bool, err error) {
+	if cluster.IsServiceShared[serviceType] {
+		clusterID = "shared"
+	}
+	err = db.DB.QueryRow(`
+		SELECT cr.capacity, cr.comment FROM cluster_resources cr JOIN cluster_services cs ON cs.id = cr.service_id
+		 WHERE cs.cluster_id = $1 AND cs.type = $2 AND cr.name = $3`,
+		clusterID, serviceType, resourceName).Scan(&capacity, &comment)
+	switch {
+	case err == sql.ErrNoRows:
+		return 0, "", false, nil
+	case err != nil:
+		return 0, "", false, err
+	}
+	return capacity, comment, true, nil
+}
+
+//holdDomainQuotasForApproval removes all quota changes from serviceQuotas
+//that exceed the cluster's approval thresholds, and returns them as pending
+//changes instead. Changes that cannot be parsed are left in serviceQuotas, so
+//that updateDomainQuotas() reports them.
+func holdDomainQuotasForApproval(cluster *limes.Cluster, dbDomain *db.Domain, serviceQuotas ServiceQuotas, perms quotaPermissions, token *Token) (pending []db.PendingQuotaChange, errors []string, err error) {
+	if cluster.ApprovalPolicy == nil {
+		return nil, nil, nil
+	}
+
+	for serviceType, resourceQuotas := range serviceQuotas {
+		for resourceName, input := range resourceQuotas {
+			if !cluster.HasResource(serviceType, resourceName) {
+				continue
+			}
+			newQuota, err := input.ConvertFor(cluster, serviceType, resourceName)
+			if err != nil {
+				continue
+			}
+			oldQuota, err := getDomainQuota(dbDomain.ID, serviceType, resourceName)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !cluster.ApprovalPolicy.NeedsApproval(serviceType, resourceName, oldQuota, newQuota) {
+				continue
+			}
+
+			delete(resourceQuotas, resourceName)
+			switch {
+			case !perms.CanRaise:
+				errors = append(errors, fmt.Sprintf("cannot change %s/%s quota: user is not allowed to raise quotas in this domain", serviceType, resourceName))
+			case input.Comment == "" && perms.needsComment(cluster, serviceType, resourceName, oldQuota, newQuota):
+				errors = append(errors, fmt.Sprintf("cannot change %s/%s quota: a comment is required for raises of this size", serviceType, resourceName))
+			case input.ExpiresAt != nil:
+				errors = append(errors, fmt.Sprintf("cannot change %s/%s quota: temporary quota raises cannot exceed the approval threshold", serviceType, resourceName))
+			default:
+				pending = append(pending, newPendingChange(cluster, cluster.ID, &dbDomain.ID,
+					serviceType, resourceName, oldQuota, newQuota, input.Comment, token))
+			}
+		}
+	}
+
+	sort.Strings(errors)
+	sortPendingChanges(pending)
+	return pending, errors, nil
+}
+
+//holdClusterCapacitiesForApproval removes all capacity changes from the
+//given list that exceed the cluster's approval thresholds, and returns them as
+//pending changes instead. Changes that are not acceptable for other reasons
+//are left in the list, so that updateClusterCapacities() reports them.
+func holdClusterCapacitiesForApproval(cluster *limes.Cluster, clusterID string, services []ServiceCapacities, token *Token) (remaining []ServiceCapacities, pending []db.PendingQuotaChange, err error) {
+	if cluster.ApprovalPolicy == nil {
+		return services, nil, nil
+	}
+
+	for _, srv := range services {
+		remainingSrv := ServiceCapacities{Type: srv.Type}
+		for _, res := range srv.Resources {
+			if !cluster.HasResource(srv.Type, res.Name) || res.Capacity < 0 || res.Comment == "" {
+				remainingSrv.Resources = append(remainingSrv.Resources, res)
+				continue
+			}
+			inputUnit := limes.UnitUnspecified
+			if res.Unit != nil {
+				inputUnit = *res.Unit
+			}
+			//int64->uint64 is safe here because `res.Capacity >= 0` has already been established
+			newCapacity, err := limes.ValueWithUnit{Value: uint64(res.Capacity), Unit: inputUnit}.ConvertFor(cluster, srv.Type, res.Name)
+			if err != nil {
+				remainingSrv.Resources = append(remainingSrv.Resources, res)
+				continue
+			}
+
+			//oldComment is empty for capacities that are maintained automatically
+			oldCapacity, oldComment, exists, err := getClusterCapacity(cluster, clusterID, srv.Type, res.Name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if (exists && oldComment == "") || !cluster.ApprovalPolicy.NeedsApproval(srv.Type, res.Name, oldCapacity, newCapacity) {
+				remainingSrv.Resources = append(remainingSrv.Resources, res)
+				continue
+			}
+
+			pending = append(pending, newPendingChange(cluster, clusterID, nil,
+				srv.Type, res.Name, oldCapacity, newCapacity, res.Comment, token))
+		}
+		if len(remainingSrv.Resources) > 0 {
+			remaining = append(remaining, remainingSrv)
+		}
+	}
+
+	sortPendingChanges(pending)
+	return remaining, pending, nil
+}
+
+//insertPendingChanges stores the given pending changes in the database, and
+//returns their API representation.
+func insertPendingChanges(cluster *limes.Cluster, changes []db.PendingQuotaChange) ([]PendingChange, error) {
+	for idx := range changes {
+		err := db.DB.Insert(&changes[idx])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return renderPendingChanges(cluster, changes), nil
+}
+
+//ListClusterPendingChanges handles GET /v1/clusters/:cluster_id/pending-changes.
+func (p *v1Provider) ListClusterPendingChanges(w http.ResponseWriter, r *http.Request) {
+	if !p.CheckToken(r).Require(w, "cluster:show") {
+		return
+	}
+	target, ok := p.findClusterPendingChangeTarget(w, r)
+	if ok {
+		p.listPendingChanges(w, target)
+	}
+}
+
+//ConfirmClusterPendingChange handles POST /v1/clusters/:cluster_id/pending-changes/:change_id/confirm.
+func (p *v1Provider) ConfirmClusterPendingChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "cluster:edit") {
+		return
+	}
+	target, ok := p.findClusterPendingChangeTarget(w, r)
+	if ok {
+		p.confirmPendingChange(w, r, target, token)
+	}
+}
+
+//RejectClusterPendingChange handles DELETE /v1/clusters/:cluster_id/pending-changes/:change_id.
+func (p *v1Provider) RejectClusterPendingChange(w http.ResponseWriter, r *http.Request) {
+	if !p.CheckToken(r).Require(w, "cluster:edit") {
+		return
+	}
+	target, ok := p.findClusterPendingChangeTarget(w, r)
+	if ok {
+		p.rejectPendingChange(w, r, target)
+	}
+}
+
+func (p *v1Provider) findClusterPendingChangeTarget(w http.ResponseWriter, r *http.Request) (pendingChangeTarget, bool) {
+	clusterID := mux.Vars(r)["cluster_id"]
+	if clusterID == "current" {
+		clusterID = p.Cluster.ID
+	}
+	cluster, ok := p.Config.Clusters[clusterID]
+	if !ok {
+		http.Error(w, "no such cluster", 404)
+		return pendingChangeTarget{}, false
+	}
+	return pendingChangeTarget{Cluster: cluster, ClusterID: clusterID}, true
+}
+
+//ListDomainPendingChanges handles GET /v1/domains/:domain_id/pending-changes.
+func (p *v1Provider) ListDomainPendingChanges(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:show") {
+		return
+	}
+	target, ok := p.findDomainPendingChangeTarget(w, r, token)
+	if ok {
+		p.listPendingChanges(w, target)
+	}
+}
+
+//ConfirmDomainPendingChange handles POST /v1/domains/:domain_id/pending-changes/:change_id/confirm.
+func (p *v1Provider) ConfirmDomainPendingChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:raise") {
+		return
+	}
+	target, ok := p.findDomainPendingChangeTarget(w, r, token)
+	if ok {
+		p.confirmPendingChange(w, r, target, token)
+	}
+}
+
+//RejectDomainPendingChange handles DELETE /v1/domains/:domain_id/pending-changes/:change_id.
+func (p *v1Provider) RejectDomainPendingChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:raise") {
+		return
+	}
+	target, ok := p.findDomainPendingChangeTarget(w, r, token)
+	if ok {
+		p.rejectPendingChange(w, r, target)
+	}
+}
+
+func (p *v1Provider) findDomainPendingChangeTarget(w http.ResponseWriter, r *http.Request, token *Token) (pendingChangeTarget, bool) {
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return pendingChangeTarget{}, false
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return pendingChangeTarget{}, false
+	}
+	return pendingChangeTarget{Cluster: cluster, ClusterID: cluster.ID, Domain: dbDomain}, true
+}
+
+func (p *v1Provider) listPendingChanges(w http.ResponseWriter, target pendingChangeTarget) {
+	where, args := target.whereClause()
+	var changes []db.PendingQuotaChange
+	_, err := db.DB.Select(&changes,
+		`SELECT * FROM pending_quota_changes WHERE `+where+` ORDER BY id`, args...)
+	if ReturnError(w, err) {
+		return
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"pending_changes": renderPendingChanges(target.Cluster, changes)})
+}
+
+func (p *v1Provider) findPendingChange(w http.ResponseWriter, r *http.Request, target pendingChangeTarget) (*db.PendingQuotaChange, bool) {
+	changeID, err := strconv.ParseInt(mux.Vars(r)["change_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "no such pending change", 404)
+		return nil, false
+	}
+
+	where, args := target.whereClause()
+	var change db.PendingQuotaChange
+	err = db.DB.SelectOne(&change,
+		`SELECT * FROM pending_quota_changes WHERE `+where+` AND id = $2`,
+		append(args, changeID)...)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "no such pending change", 404)
+		return nil, false
+	case ReturnError(w, err):
+		return nil, false
+	}
+	return &change, true
+}
+
+func (p *v1Provider) confirmPendingChange(w http.ResponseWriter, r *http.Request, target pendingChangeTarget, token *Token) {
+	change, ok := p.findPendingChange(w, r, target)
+	if !ok {
+		return
+	}
+	if !change.ExpiresAt.After(timeNow()) {
+		http.Error(w, "pending change has expired", 409)
+		return
+	}
+	if change.CreatorUUID == token.UserUUID {
+		http.Error(w, "pending change must be confirmed by a different user", 403)
+		return
+	}
+
+	//the confirming user approves the change from the old value to the new
+	//value, so the change is void if the value has been changed since
+	var (
+		currentValue uint64
+		err          error
+	)
+	if target.Domain == nil {
+		currentValue, _, _, err = getClusterCapacity(target.Cluster, change.ClusterID, change.ServiceType, change.ResourceName)
+	} else {
+		currentValue, err = getDomainQuota(target.Domain.ID, change.ServiceType, change.ResourceName)
+	}
+	if ReturnError(w, err) {
+		return
+	}
+	if currentValue != change.OldValue {
+		unit := target.Cluster.InfoForResource(change.ServiceType, change.ResourceName).Unit
+		http.Error(w, fmt.Sprintf("pending change is outdated: value has been changed from %s to %s since the change was requested",
+			limes.ValueWithUnit{Value: change.OldValue, Unit: unit},
+			limes.ValueWithUnit{Value: currentValue, Unit: unit},
+		), 409)
+		return
+	}
+
+	//apply the change with the same validation as the respective PUT request
+	actor := quotaChangeActor{
+		UserUUID: change.CreatorUUID,
+		UserName: change.CreatorName,
+		Via:      fmt.Sprintf("through pending change %d confirmed by %s", change.ID, describeUser(token)),
+	}
+	var errors []string
+	if target.Domain == nil {
+		errors, err = updateClusterCapacities(target.Cluster, change.ClusterID, []ServiceCapacities{{
+			Type: change.ServiceType,
+			Resources: []ResourceCapacity{{
+				Name:     change.ResourceName,
+				Capacity: int64(change.NewValue),
+				Comment:  change.Comment,
+			}},
+		}})
+		if err == nil && len(errors) == 0 {
+			util.LogInfo("set capacity %s.%s = %d for cluster %s by %s",
+				change.ServiceType, change.ResourceName, change.NewValue, change.ClusterID, actor)
+		}
+	} else {
+		//the requirement for a comment was already checked when the change was created
+		perms := token.permissionsFor("domain")
+		perms.CanRaiseWithoutComment = true
+		errors, err = updateDomainQuotas(target.Cluster, target.Domain, ServiceQuotas{
+			change.ServiceType: ResourceQuotas{
+				change.ResourceName: QuotaInput{
+					ValueWithUnit: limes.ValueWithUnit{Value: change.NewValue, Unit: limes.UnitUnspecified},
+					Comment:       change.Comment,
+				},
+			},
+		}, perms, actor)
+	}
+	if ReturnError(w, err) {
+		return
+	}
+	if len(errors) > 0 {
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+
+	_, err = db.DB.Delete(change)
+	if ReturnError(w, err) {
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (p *v1Provider) rejectPendingChange(w http.ResponseWriter, r *http.Request, target pendingChangeTarget) {
+	change, ok := p.findPendingChange(w, r, target)
+	if !ok {
+		return
+	}
+	_, err := db.DB.Delete(change)
+	if ReturnError(w, err) {
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -82,7 +82,8 @@ func (p *v1Provider) GetCluster(w http.ResponseWriter, r *http.Request) {
 
 //PutCluster handles PUT /v1/clusters/:cluster_id.
 func (p *v1Provider) PutCluster(w http.ResponseWriter, r *http.Request) {
-	if !p.CheckToken(r).Require(w, "cluster:edit") {
+	token := p.CheckToken(r)
+	if !token.Require(w, "cluster:edit") {
 		return
 	}
 
@@ -107,16 +108,55 @@ func (p *v1Provider) PutCluster(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//start a transaction for the capacity updates
-	tx, err := db.DB.Begin()
+	//large raises are not applied immediately, but held for approval by a second user
+	services, pendingChanges, err := holdClusterCapacitiesForApproval(cluster, clusterID, parseTarget.Cluster.Services, token)
+	if ReturnError(w, err) {
+		return
+	}
+	errors, err := updateClusterCapacities(cluster, clusterID, services)
+	if ReturnError(w, err) {
+		return
+	}
+
+	//if not legal, report errors to the user
+	if len(errors) > 0 {
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+	renderedPendingChanges, err := insertPendingChanges(cluster, pendingChanges)
 	if ReturnError(w, err) {
 		return
 	}
-	defer db.RollbackUnlessCommitted(tx)
 
-	var errors []string
+	//otherwise, report success
+	clusters, err := reports.GetClusters(p.Config, &clusterID, false, false, db.DB, reports.ReadFilter(r))
+	if ReturnError(w, err) {
+		return
+	}
+	if len(clusters) == 0 {
+		http.Error(w, "no resource data found for cluster", 500)
+		return
+	}
 
-	for _, srv := range parseTarget.Cluster.Services {
+	if len(renderedPendingChanges) > 0 {
+		ReturnJSON(w, 202, map[string]interface{}{"cluster": clusters[0], "pending_changes": renderedPendingChanges})
+		return
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"cluster": clusters[0]})
+}
+
+//updateClusterCapacities validates and applies the given capacity changes to
+//the given cluster. If any of the changes is not acceptable, nothing is
+//written, and the validation errors are returned.
+func updateClusterCapacities(cluster *limes.Cluster, clusterID string, services []ServiceCapacities) (errors []string, err error) {
+	//start a transaction for the capacity updates
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	for _, srv := range services {
 		//check that this service is configured for this cluster
 		if !cluster.HasService(srv.Type) {
 			for _, res := range srv.Resources {
@@ -128,8 +168,8 @@ func (p *v1Provider) PutCluster(w http.ResponseWriter, r *http.Request) {
 		}
 
 		service, err := findClusterService(tx, srv, clusterID, cluster.IsServiceShared[srv.Type])
-		if ReturnError(w, err) {
-			return
+		if err != nil {
+			return nil, err
 		}
 		if service == nil {
 			//this should only occur if a service was added, and users try to
@@ -145,8 +185,8 @@ func (p *v1Provider) PutCluster(w http.ResponseWriter, r *http.Request) {
 
 		for _, res := range srv.Resources {
 			msg, err := writeClusterResource(tx, cluster, srv, service, res)
-			if ReturnError(w, err) {
-				return
+			if err != nil {
+				return nil, err
 			}
 			if msg != "" {
 				errors = append(errors,
@@ -159,27 +199,11 @@ func (p *v1Provider) PutCluster(w http.ResponseWriter, r *http.Request) {
 		//cluster_services record, cleanup the cluster_services record, too
 	}
 
-	//if not legal, report errors to the user
+	//if not legal, do not write anything
 	if len(errors) > 0 {
-		http.Error(w, strings.Join(errors, "\n"), 422)
-		return
+		return errors, nil
 	}
-	err = tx.Commit()
-	if ReturnError(w, err) {
-		return
-	}
-
-	//otherwise, report success
-	clusters, err := reports.GetClusters(p.Config, &clusterID, false, false, db.DB, reports.ReadFilter(r))
-	if ReturnError(w, err) {
-		return
-	}
-	if len(clusters) == 0 {
-		http.Error(w, "no resource data found for cluster", 500)
-		return
-	}
-
-	ReturnJSON(w, 200, map[string]interface{}{"cluster": clusters[0]})
+	return nil, tx.Commit()
 }
 
 func findClusterService(tx *gorp.Transaction, srv ServiceCapacities, clusterID string, shared bool) (*db.ClusterService, error) {
@@ -0,0 +1,377 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	gorp "gopkg.in/gorp.v2"
+
+	"github.com/gorilla/mux"
+	"github.com/sapcc/limes/pkg/datamodel"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+//Commitment is the API representation of a db.ProjectCommitment.
+type Commitment struct {
+	ID           int64           `json:"id"`
+	ServiceType  string          `json:"service"`
+	ResourceName string          `json:"resource"`
+	Unit         limes.Unit      `json:"unit,omitempty"`
+	Amount       uint64          `json:"amount"`
+	Duration     string          `json:"duration"`
+	CreatedAt    int64           `json:"created_at"`
+	CreatedBy    ScheduledByUser `json:"created_by"`
+	ExpiresAt    int64           `json:"expires_at"`
+	State        string          `json:"state"`
+}
+
+func renderCommitment(cluster *limes.Cluster, commitment db.ProjectCommitment, now time.Time) Commitment {
+	result := Commitment{
+		ID:           commitment.ID,
+		ServiceType:  commitment.ServiceType,
+		ResourceName: commitment.ResourceName,
+		Unit:         cluster.InfoForResource(commitment.ServiceType, commitment.ResourceName).Unit,
+		Amount:       commitment.Amount,
+		Duration:     commitment.Duration,
+		CreatedAt:    commitment.CreatedAt.Unix(),
+		CreatedBy: ScheduledByUser{
+			UUID: commitment.CreatorUUID,
+			Name: commitment.CreatorName,
+		},
+		ExpiresAt: commitment.ExpiresAt.Unix(),
+		State:     "active",
+	}
+	if !commitment.ExpiresAt.After(now) {
+		result.State = "expired"
+	}
+	return result
+}
+
+var commitmentDurationRx = regexp.MustCompile(`^([1-9][0-9]*) (year|month|day)s?$`)
+
+//parseCommitmentDuration parses durations like "1 year", "6 months" or
+//"30 days", and returns the expiry date for a commitment of that duration
+//that starts at the given time.
+func parseCommitmentDuration(input string, start time.Time) (time.Time, error) {
+	match := commitmentDurationRx.FindStringSubmatch(input)
+	if match == nil {
+		return time.Time{}, fmt.Errorf(`invalid duration %q (expected something like "1 year", "6 months" or "30 days")`, input)
+	}
+	count, err := strconv.Atoi(match[1])
+	if err != nil {
+		return time.Time{}, err
+	}
+	switch match[2] {
+	case "year":
+		return start.AddDate(count, 0, 0), nil
+	case "month":
+		return start.AddDate(0, count, 0), nil
+	default:
+		return start.AddDate(0, 0, count), nil
+	}
+}
+
+//query that finds the sum of all active commitments for a resource in all
+//projects of a cluster
+var clusterCommitmentsQuery = `
+	SELECT COALESCE(SUM(pc.amount), 0)
+	  FROM project_commitments pc
+	  JOIN projects p ON p.id = pc.project_id
+	  JOIN domains d ON d.id = p.domain_id
+	 WHERE d.cluster_id = $1 AND pc.service_type = $2 AND pc.resource_name = $3 AND pc.expires_at > $4
+`
+
+//same as above, but for shared services (where commitments in all clusters
+//count against the same capacity)
+var sharedCommitmentsQuery = `
+	SELECT COALESCE(SUM(amount), 0)
+	  FROM project_commitments
+	 WHERE service_type = $1 AND resource_name = $2 AND expires_at > $3
+`
+
+var clusterCapacityQuery = `
+	SELECT cr.capacity
+	  FROM cluster_services cs
+	  JOIN cluster_resources cr ON cr.service_id = cs.id
+	 WHERE cs.cluster_id = $1 AND cs.type = $2 AND cr.name = $3
+`
+
+//ListProjectCommitments handles GET /v1/domains/:domain_id/projects/:project_id/commitments.
+func (p *v1Provider) ListProjectCommitments(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:show") {
+		return
+	}
+	cluster, dbProject, ok := p.findCommitmentTarget(w, r, token)
+	if !ok {
+		return
+	}
+
+	var commitments []db.ProjectCommitment
+	_, err := db.DB.Select(&commitments,
+		`SELECT * FROM project_commitments WHERE project_id = $1 ORDER BY created_at, id`, dbProject.ID)
+	if ReturnError(w, err) {
+		return
+	}
+
+	now := timeNow()
+	result := make([]Commitment, len(commitments))
+	for idx, commitment := range commitments {
+		result[idx] = renderCommitment(cluster, commitment, now)
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"commitments": result})
+}
+
+//CreateProjectCommitment handles POST /v1/domains/:domain_id/projects/:project_id/commitments.
+func (p *v1Provider) CreateProjectCommitment(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:commit") {
+		return
+	}
+	cluster, dbProject, ok := p.findCommitmentTarget(w, r, token)
+	if !ok {
+		return
+	}
+
+	//parse request body
+	var parseTarget struct {
+		Commitment struct {
+			ServiceType  string      `json:"service"`
+			ResourceName string      `json:"resource"`
+			Amount       uint64      `json:"amount"`
+			Unit         *limes.Unit `json:"unit"`
+			Duration     string      `json:"duration"`
+		} `json:"commitment"`
+	}
+	if !RequireJSON(w, r, &parseTarget) {
+		return
+	}
+	input := parseTarget.Commitment
+
+	//validate request
+	now := timeNow().UTC()
+	var errors []string
+	if !cluster.HasResource(input.ServiceType, input.ResourceName) {
+		errors = append(errors, "no such resource")
+	}
+	if input.Amount == 0 {
+		errors = append(errors, "amount must be positive")
+	}
+	expiresAt, err := parseCommitmentDuration(input.Duration, now)
+	if err != nil {
+		errors = append(errors, err.Error())
+	}
+	var amount uint64
+	if len(errors) == 0 {
+		inputUnit := limes.UnitUnspecified
+		if input.Unit != nil {
+			inputUnit = *input.Unit
+		}
+		amount, err = limes.ValueWithUnit{Value: input.Amount, Unit: inputUnit}.ConvertFor(cluster, input.ServiceType, input.ResourceName)
+		if err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	tx, err := db.DB.Begin()
+	if ReturnError(w, err) {
+		return
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	//check that the commitment fits into the project quota and the cluster capacity
+	if len(errors) == 0 {
+		unit := cluster.InfoForResource(input.ServiceType, input.ResourceName).Unit
+		msgs, err := checkCommitment(cluster, tx, dbProject, input.ServiceType, input.ResourceName, unit, amount, now)
+		if ReturnError(w, err) {
+			return
+		}
+		errors = append(errors, msgs...)
+	}
+
+	if len(errors) > 0 {
+		for idx, msg := range errors {
+			errors[idx] = fmt.Sprintf("cannot commit %s/%s: %s", input.ServiceType, input.ResourceName, msg)
+		}
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+
+	commitment := db.ProjectCommitment{
+		ProjectID:    dbProject.ID,
+		ServiceType:  input.ServiceType,
+		ResourceName: input.ResourceName,
+		Amount:       amount,
+		Duration:     input.Duration,
+		CreatedAt:    now,
+		CreatorUUID:  token.UserUUID,
+		CreatorName:  token.UserName,
+		ExpiresAt:    expiresAt,
+	}
+	err = tx.Insert(&commitment)
+	if ReturnError(w, err) {
+		return
+	}
+	err = tx.Commit()
+	if ReturnError(w, err) {
+		return
+	}
+
+	var auditTrail util.AuditTrail
+	auditTrail.Add("commit %s.%s = %d for %s in project %s by %s",
+		commitment.ServiceType, commitment.ResourceName, commitment.Amount, commitment.Duration,
+		dbProject.UUID, describeUser(token),
+	)
+	auditTrail.Commit()
+
+	ReturnJSON(w, 201, map[string]interface{}{"commitment": renderCommitment(cluster, commitment, now)})
+}
+
+//checkCommitment checks whether a new commitment fits into the project quota
+//and into the cluster capacity that is not yet committed to other projects.
+func checkCommitment(cluster *limes.Cluster, tx *gorp.Transaction, dbProject *db.Project, serviceType, resourceName string, unit limes.Unit, amount uint64, now time.Time) ([]string, error) {
+	var errors []string
+
+	//all commitments of the project must be covered by its quota
+	var srv db.ProjectService
+	err := tx.SelectOne(&srv,
+		`SELECT * FROM project_services WHERE project_id = $1 AND type = $2`, dbProject.ID, serviceType)
+	if err == sql.ErrNoRows {
+		//the service was added to the configuration, but the consistency check
+		//has not created the project_services record yet
+		return []string{"project has no data for this service yet"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commitments, err := datamodel.GetProjectCommitments(tx, srv.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	quota, err := tx.SelectInt(
+		`SELECT quota FROM project_resources WHERE service_id = $1 AND name = $2`, srv.ID, resourceName)
+	if err != nil {
+		return nil, err
+	}
+	//quota is never negative (and 0 if the resource has not been scraped yet)
+	if commitments[resourceName]+amount > uint64(quota) {
+		maxAmount := uint64(0)
+		if uint64(quota) > commitments[resourceName] {
+			maxAmount = uint64(quota) - commitments[resourceName]
+		}
+		errors = append(errors, fmt.Sprintf("commitments may not exceed the project quota (maximum acceptable amount is %s)",
+			limes.ValueWithUnit{Value: maxAmount, Unit: unit}))
+	}
+
+	//all commitments in the cluster must be covered by its capacity
+	var committed int64
+	capacityClusterID := cluster.ID
+	if cluster.IsServiceShared[serviceType] {
+		capacityClusterID = "shared"
+		committed, err = tx.SelectInt(sharedCommitmentsQuery, serviceType, resourceName, now)
+	} else {
+		committed, err = tx.SelectInt(clusterCommitmentsQuery, cluster.ID, serviceType, resourceName, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	capacity, err := tx.SelectNullInt(clusterCapacityQuery, capacityClusterID, serviceType, resourceName)
+	if err != nil {
+		return nil, err
+	}
+	if !capacity.Valid {
+		errors = append(errors, "no capacity has been reported for this resource")
+	} else if uint64(committed)+amount > uint64(capacity.Int64) {
+		maxAmount := uint64(0)
+		if capacity.Int64 > committed {
+			maxAmount = uint64(capacity.Int64 - committed)
+		}
+		errors = append(errors, fmt.Sprintf("not enough capacity (maximum acceptable amount is %s)",
+			limes.ValueWithUnit{Value: maxAmount, Unit: unit}))
+	}
+
+	return errors, nil
+}
+
+//CancelProjectCommitment handles DELETE /v1/domains/:domain_id/projects/:project_id/commitments/:commitment_id.
+func (p *v1Provider) CancelProjectCommitment(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:uncommit") {
+		return
+	}
+	_, dbProject, ok := p.findCommitmentTarget(w, r, token)
+	if !ok {
+		return
+	}
+
+	commitmentID, err := strconv.ParseInt(mux.Vars(r)["commitment_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "no such commitment", 404)
+		return
+	}
+	var commitment db.ProjectCommitment
+	err = db.DB.SelectOne(&commitment,
+		`SELECT * FROM project_commitments WHERE project_id = $1 AND id = $2`, dbProject.ID, commitmentID)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "no such commitment", 404)
+		return
+	case ReturnError(w, err):
+		return
+	}
+
+	_, err = db.DB.Delete(&commitment)
+	if ReturnError(w, err) {
+		return
+	}
+
+	var auditTrail util.AuditTrail
+	auditTrail.Add("cancel commitment %d (%s.%s = %d) for project %s by %s",
+		commitment.ID, commitment.ServiceType, commitment.ResourceName, commitment.Amount,
+		dbProject.UUID, describeUser(token),
+	)
+	auditTrail.Commit()
+	w.WriteHeader(204)
+}
+
+func (p *v1Provider) findCommitmentTarget(w http.ResponseWriter, r *http.Request, token *Token) (*limes.Cluster, *db.Project, bool) {
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return nil, nil, false
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return nil, nil, false
+	}
+	dbProject := p.FindProjectFromRequest(w, r, dbDomain)
+	if dbProject == nil {
+		return nil, nil, false
+	}
+	return cluster, dbProject, true
+}
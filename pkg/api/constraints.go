@@ -0,0 +1,185 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+var domainQuotasQuery = `
+	SELECT ds.type, dr.name, dr.quota
+	  FROM domain_services ds
+	  JOIN domain_resources dr ON dr.service_id = ds.id
+	 WHERE ds.domain_id = $1
+`
+
+var projectQuotasQuery = `
+	SELECT ps.type, pr.name, pr.quota
+	  FROM project_services ps
+	  JOIN project_resources pr ON pr.service_id = ps.id
+	 WHERE ps.project_id = $1
+`
+
+func hasQuotaRatios(cluster *limes.Cluster) bool {
+	return cluster.QuotaConstraints != nil && len(cluster.QuotaConstraints.Ratios) > 0
+}
+
+//getQuotasForRatios returns the current quotas of a single domain or project,
+//using the given query, which must select service type, resource name and
+//quota for the domain or project with the given ID.
+//
+//The result is indexed by service type, then by resource name.
+func getQuotasForRatios(dbi db.Interface, query string, id int64) (map[string]map[string]uint64, error) {
+	quotas := make(map[string]map[string]uint64)
+	err := db.ForeachRow(dbi, query, []interface{}{id}, func(rows *sql.Rows) error {
+		var (
+			serviceType  string
+			resourceName string
+			quota        uint64
+		)
+		err := rows.Scan(&serviceType, &resourceName, &quota)
+		if err != nil {
+			return err
+		}
+		if quotas[serviceType] == nil {
+			quotas[serviceType] = make(map[string]uint64)
+		}
+		quotas[serviceType][resourceName] = quota
+		return nil
+	})
+	return quotas, err
+}
+
+//checkQuotaRatios checks the requested quota values for a single domain or
+//project against the ratio constraints of this cluster. Only those ratio
+//constraints are checked that involve at least one of the resources in
+//`newQuotas`. The quotas of all other resources are taken from the database
+//using getQuotasForRatios().
+//
+//Quota maps are indexed by service type, then by resource name.
+func checkQuotaRatios(cluster *limes.Cluster, dbi db.Interface, query string, id int64, newQuotas map[string]map[string]uint64) ([]string, error) {
+	if !hasQuotaRatios(cluster) {
+		return nil, nil
+	}
+
+	quotas, err := getQuotasForRatios(dbi, query, id)
+	if err != nil {
+		return nil, err
+	}
+	for serviceType, serviceQuotas := range newQuotas {
+		if quotas[serviceType] == nil {
+			quotas[serviceType] = make(map[string]uint64)
+		}
+		for resourceName, quota := range serviceQuotas {
+			quotas[serviceType][resourceName] = quota
+		}
+	}
+
+	var errors []string
+	for _, ratio := range cluster.QuotaConstraints.Ratios {
+		_, isChanged := newQuotas[ratio.ServiceType][ratio.ResourceName]
+		_, isRefChanged := newQuotas[ratio.RefServiceType][ratio.RefResourceName]
+		if !isChanged && !isRefChanged {
+			continue
+		}
+
+		quota := quotas[ratio.ServiceType][ratio.ResourceName]
+		refQuota := quotas[ratio.RefServiceType][ratio.RefResourceName]
+		if ratio.Allows(quota, refQuota) {
+			continue
+		}
+
+		//report the error on the resource whose change caused the violation
+		changedServiceType, changedResourceName := ratio.ServiceType, ratio.ResourceName
+		if !isChanged {
+			changedServiceType, changedResourceName = ratio.RefServiceType, ratio.RefResourceName
+		}
+		unit := cluster.InfoForResource(ratio.ServiceType, ratio.ResourceName).Unit
+		refUnit := cluster.InfoForResource(ratio.RefServiceType, ratio.RefResourceName).Unit
+		errors = append(errors, fmt.Sprintf(
+			"cannot change %s/%s quota: ratio constraint %q is violated (%s/%s quota would be %s, %s/%s quota would be %s)",
+			changedServiceType, changedResourceName, ratio.ToString(unit),
+			ratio.ServiceType, ratio.ResourceName, limes.ValueWithUnit{Value: quota, Unit: unit},
+			ratio.RefServiceType, ratio.RefResourceName, limes.ValueWithUnit{Value: refQuota, Unit: refUnit},
+		))
+	}
+	return errors, nil
+}
+
+//applyQuotaRatios narrows the given constraint for a single resource, such
+//that all quota values allowed by it also satisfy the ratio constraints of
+//this cluster, assuming that the quotas of all other resources stay as they
+//are in `quotas` (as returned by getQuotasForRatios()). The constraint must
+//already have been evaluated. If no quota value satisfies the ratio
+//constraints, the result contradicts itself (i.e. Minimum > Maximum).
+func applyQuotaRatios(cluster *limes.Cluster, quotas map[string]map[string]uint64, serviceType, resourceName string, constraint limes.QuotaConstraint) limes.QuotaConstraint {
+	if !hasQuotaRatios(cluster) {
+		return constraint
+	}
+
+	var minimum, maximum *uint64
+	if constraint.Minimum != nil {
+		val := *constraint.Minimum
+		minimum = &val
+	}
+	if constraint.Maximum != nil {
+		val := *constraint.Maximum
+		maximum = &val
+	}
+	raiseMinimum := func(val uint64) {
+		if minimum == nil || *minimum < val {
+			minimum = &val
+		}
+	}
+	lowerMaximum := func(val uint64) {
+		if maximum == nil || *maximum > val {
+			maximum = &val
+		}
+	}
+
+	for _, ratio := range cluster.QuotaConstraints.Ratios {
+		if ratio.ServiceType == serviceType && ratio.ResourceName == resourceName {
+			bound := ratio.Bound(quotas[ratio.RefServiceType][ratio.RefResourceName])
+			if ratio.IsUpperBound {
+				lowerMaximum(bound)
+			} else {
+				raiseMinimum(bound)
+			}
+		}
+		if ratio.RefServiceType == serviceType && ratio.RefResourceName == resourceName {
+			bound, ok := ratio.RefBound(quotas[ratio.ServiceType][ratio.ResourceName])
+			switch {
+			case !ok:
+				raiseMinimum(1)
+				lowerMaximum(0)
+			case ratio.IsUpperBound:
+				raiseMinimum(bound)
+			default:
+				lowerMaximum(bound)
+			}
+		}
+	}
+
+	return limes.QuotaConstraint{Minimum: minimum, Maximum: maximum, Expected: constraint.Expected}
+}
@@ -84,17 +84,46 @@ func NewV1Router(cluster *limes.Cluster, config limes.Configuration) (http.Handl
 	r.Methods("GET").Path("/v1/clusters").HandlerFunc(p.ListClusters)
 	r.Methods("GET").Path("/v1/clusters/{cluster_id}").HandlerFunc(p.GetCluster)
 	r.Methods("PUT").Path("/v1/clusters/{cluster_id}").HandlerFunc(p.PutCluster)
+	r.Methods("GET").Path("/v1/clusters/{cluster_id}/scheduled-changes").HandlerFunc(p.ListClusterScheduledChanges)
+	r.Methods("POST").Path("/v1/clusters/{cluster_id}/scheduled-changes").HandlerFunc(p.CreateClusterScheduledChange)
+	r.Methods("DELETE").Path("/v1/clusters/{cluster_id}/scheduled-changes/{change_id}").HandlerFunc(p.CancelClusterScheduledChange)
+	r.Methods("GET").Path("/v1/clusters/{cluster_id}/pending-changes").HandlerFunc(p.ListClusterPendingChanges)
+	r.Methods("POST").Path("/v1/clusters/{cluster_id}/pending-changes/{change_id}/confirm").HandlerFunc(p.ConfirmClusterPendingChange)
+	r.Methods("DELETE").Path("/v1/clusters/{cluster_id}/pending-changes/{change_id}").HandlerFunc(p.RejectClusterPendingChange)
 
 	r.Methods("GET").Path("/v1/domains").HandlerFunc(p.ListDomains)
 	r.Methods("GET").Path("/v1/domains/{domain_id}").HandlerFunc(p.GetDomain)
 	r.Methods("POST").Path("/v1/domains/discover").HandlerFunc(p.DiscoverDomains)
 	r.Methods("PUT").Path("/v1/domains/{domain_id}").HandlerFunc(p.PutDomain)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/headroom").HandlerFunc(p.GetDomainHeadroom)
+	r.Methods("PUT").Path("/v1/domains/{domain_id}/lock").HandlerFunc(p.LockDomain)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/lock").HandlerFunc(p.UnlockDomain)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/scheduled-changes").HandlerFunc(p.ListDomainScheduledChanges)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/scheduled-changes").HandlerFunc(p.CreateDomainScheduledChange)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/scheduled-changes/{change_id}").HandlerFunc(p.CancelDomainScheduledChange)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/pending-changes").HandlerFunc(p.ListDomainPendingChanges)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/pending-changes/{change_id}/confirm").HandlerFunc(p.ConfirmDomainPendingChange)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/pending-changes/{change_id}").HandlerFunc(p.RejectDomainPendingChange)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/quota-changes").HandlerFunc(p.ListQuotaChanges)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/quota-changes/revert").HandlerFunc(p.RevertQuotaChanges)
+
+	r.Methods("GET").Path("/v1/domains/{domain_id}/quota-classes").HandlerFunc(p.ListQuotaClasses)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/quota-classes/{class_name}/apply").HandlerFunc(p.ApplyQuotaClass)
 
 	r.Methods("GET").Path("/v1/domains/{domain_id}/projects").HandlerFunc(p.ListProjects)
 	r.Methods("GET").Path("/v1/domains/{domain_id}/projects/{project_id}").HandlerFunc(p.GetProject)
 	r.Methods("POST").Path("/v1/domains/{domain_id}/projects/discover").HandlerFunc(p.DiscoverProjects)
 	r.Methods("POST").Path("/v1/domains/{domain_id}/projects/{project_id}/sync").HandlerFunc(p.SyncProject)
 	r.Methods("PUT").Path("/v1/domains/{domain_id}/projects/{project_id}").HandlerFunc(p.PutProject)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/projects/{project_id}/headroom").HandlerFunc(p.GetProjectHeadroom)
+	r.Methods("PUT").Path("/v1/domains/{domain_id}/projects/{project_id}/lock").HandlerFunc(p.LockProject)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/projects/{project_id}/lock").HandlerFunc(p.UnlockProject)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/projects/{project_id}/scheduled-changes").HandlerFunc(p.ListProjectScheduledChanges)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/projects/{project_id}/scheduled-changes").HandlerFunc(p.CreateProjectScheduledChange)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/projects/{project_id}/scheduled-changes/{change_id}").HandlerFunc(p.CancelProjectScheduledChange)
+	r.Methods("GET").Path("/v1/domains/{domain_id}/projects/{project_id}/commitments").HandlerFunc(p.ListProjectCommitments)
+	r.Methods("POST").Path("/v1/domains/{domain_id}/projects/{project_id}/commitments").HandlerFunc(p.CreateProjectCommitment)
+	r.Methods("DELETE").Path("/v1/domains/{domain_id}/projects/{project_id}/commitments/{commitment_id}").HandlerFunc(p.CancelProjectCommitment)
 
 	return r, p.VersionData
 }
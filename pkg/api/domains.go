@@ -27,6 +27,7 @@ import (
 	gorp "gopkg.in/gorp.v2"
 
 	"github.com/sapcc/limes/pkg/collector"
+	"github.com/sapcc/limes/pkg/datamodel"
 	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/reports"
@@ -105,9 +106,8 @@ func (p *v1Provider) DiscoverDomains(w http.ResponseWriter, r *http.Request) {
 //PutDomain handles PUT /v1/domains/:domain_id.
 func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 	token := p.CheckToken(r)
-	canRaise := token.Check("domain:raise")
-	canLower := token.Check("domain:lower")
-	if !canRaise && !canLower {
+	perms := token.permissionsFor("domain")
+	if !perms.CanRaise && !perms.CanLower {
 		token.Require(w, "domain:raise") //produce standard Unauthorized response
 		return
 	}
@@ -125,6 +125,7 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 	var parseTarget struct {
 		Domain struct {
 			Services ServiceQuotas `json:"services"`
+			Comment  string        `json:"comment"`
 		} `json:"domain"`
 	}
 	parseTarget.Domain.Services = make(ServiceQuotas)
@@ -132,42 +133,87 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	serviceQuotas := parseTarget.Domain.Services
+	serviceQuotas.SetDefaultComment(parseTarget.Domain.Comment)
 
-	//start a transaction for the quota updates
-	tx, err := db.DB.Begin()
+	//large raises are not applied immediately, but held for approval by a second user
+	pendingChanges, errors, err := holdDomainQuotasForApproval(cluster, dbDomain, serviceQuotas, perms, token)
 	if ReturnError(w, err) {
 		return
 	}
-	defer db.RollbackUnlessCommitted(tx)
+	if len(errors) == 0 {
+		errors, err = updateDomainQuotas(cluster, dbDomain, serviceQuotas, perms, actorFor(token))
+		if ReturnError(w, err) {
+			return
+		}
+	}
 
-	var constraints limes.QuotaConstraints
-	if cluster.QuotaConstraints != nil {
-		constraints = cluster.QuotaConstraints.Domains[dbDomain.Name]
+	//if not legal, report errors to the user
+	if len(errors) > 0 {
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+	renderedPendingChanges, err := insertPendingChanges(cluster, pendingChanges)
+	if ReturnError(w, err) {
+		return
 	}
 
-	//gather a report on the domain's quotas to decide whether a quota update is legal
-	domainReports, err := reports.GetDomains(cluster, &dbDomain.ID, db.DB, reports.Filter{})
+	//otherwise, report success
+	domains, err := reports.GetDomains(cluster, &dbDomain.ID, db.DB, reports.ReadFilter(r))
 	if ReturnError(w, err) {
 		return
 	}
-	if len(domainReports) == 0 {
+	if len(domains) == 0 {
 		http.Error(w, "no resource data found for domain", 500)
 		return
 	}
-	domainReport := domainReports[0]
+
+	if len(renderedPendingChanges) > 0 {
+		ReturnJSON(w, 202, map[string]interface{}{"domain": domains[0], "pending_changes": renderedPendingChanges})
+		return
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"domain": domains[0]})
+}
+
+//updateDomainQuotas validates and applies the given quota changes to the given
+//domain. If any of the changes is not acceptable, nothing is written, and the
+//validation errors are returned. The actor is used in the audit trail to
+//describe who requested the changes.
+func updateDomainQuotas(cluster *limes.Cluster, dbDomain *db.Domain, serviceQuotas ServiceQuotas, perms quotaPermissions, actor quotaChangeActor) (errors []string, err error) {
+	if dbDomain.QuotaLocked && !perms.CanBypassLock {
+		return []string{"cannot change quotas: quotas of this domain are locked: " + dbDomain.QuotaLockReason}, nil
+	}
+
+	//start a transaction for the quota updates
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	var constraints limes.QuotaConstraints
+	if cluster.QuotaConstraints != nil {
+		constraints = cluster.QuotaConstraints.Domains[dbDomain.Name]
+	}
+
+	//gather a report on the domain's quotas to decide whether a quota update is legal
+	domainReport, err := getDomainReport(cluster, dbDomain)
+	if err != nil {
+		return nil, err
+	}
 
 	//check all services for resources to update
 	var services []db.DomainService
 	_, err = tx.Select(&services,
 		`SELECT * FROM domain_services WHERE domain_id = $1 ORDER BY type`, dbDomain.ID)
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, err
 	}
 	var resourcesToUpdate []db.DomainResource
 	var resourcesToUpdateAsUntyped []interface{}
-	var errors []string
+	newQuotas := make(map[string]map[string]uint64)
 
 	var auditTrail util.AuditTrail
+	var history datamodel.QuotaChangeHistory
 	for _, srv := range services {
 		resourceQuotas, exists := serviceQuotas[srv.Type]
 		if !exists {
@@ -179,8 +225,8 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 		var resources []db.DomainResource
 		_, err = tx.Select(&resources,
 			`SELECT * FROM domain_resources WHERE service_id = $1 ORDER BY name`, srv.ID)
-		if ReturnError(w, err) {
-			return
+		if err != nil {
+			return nil, err
 		}
 		for _, res := range resources {
 			isExistingResource[res.Name] = true
@@ -193,13 +239,21 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 				errors = append(errors, fmt.Sprintf("cannot change %s/%s quota: %s", srv.Type, res.Name, err.Error()))
 				continue
 			}
-			if res.Quota == newQuota {
+			//(if a temporary raise is pending, setting the same value without
+			//expiry date still needs to be written to make it permanent)
+			if res.Quota == newQuota && newQuotaInput.ExpiresAt == nil && res.QuotaExpiresAt == nil {
 				continue //nothing to do
 			}
 
 			resInfo := cluster.InfoForResource(srv.Type, res.Name)
 			constraint := constraints[srv.Type][res.Name]
-			err = checkDomainQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, newQuota, canRaise, canLower)
+			commentMissing := newQuotaInput.Comment == "" && perms.needsComment(cluster, srv.Type, res.Name, res.Quota, newQuota)
+			err = checkDomainQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, newQuota, commentMissing, perms)
+			if err != nil {
+				errors = append(errors, err.Error())
+				continue
+			}
+			expiresAt, previousQuota, err := checkQuotaExpiry(srv.Type, res.Name, resInfo.Unit, newQuotaInput, newQuota, res.Quota, res.QuotaExpiresAt, res.PreviousQuota)
 			if err != nil {
 				errors = append(errors, err.Error())
 				continue
@@ -209,11 +263,19 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 			//we didn't take a copy manually, the resourcesToUpdateAsUntyped list
 			//would contain only identical pointers)
 			res := res
-			auditTrail.Add("set quota %s.%s = %d -> %d for domain %s by user %s (%s)",
-				srv.Type, res.Name, res.Quota, newQuota,
-				dbDomain.UUID, token.UserUUID, token.UserName,
+			auditTrail.Add("set quota %s.%s = %d -> %d%s for domain %s by %s%s",
+				srv.Type, res.Name, res.Quota, newQuota, describeQuotaExpiry(expiresAt),
+				dbDomain.UUID, actor, describeQuotaComment(newQuotaInput.Comment),
 			)
+			history.Add(srv.Type, res.Name, res.Quota, newQuota)
 			res.Quota = newQuota
+			res.QuotaExpiresAt = expiresAt
+			res.PreviousQuota = previousQuota
+			res.QuotaComment = newQuotaInput.Comment
+			if newQuotas[srv.Type] == nil {
+				newQuotas[srv.Type] = make(map[string]uint64)
+			}
+			newQuotas[srv.Type][res.Name] = newQuota
 			resourcesToUpdate = append(resourcesToUpdate, res)
 			resourcesToUpdateAsUntyped = append(resourcesToUpdateAsUntyped, &res)
 		}
@@ -243,59 +305,86 @@ func (p *v1Provider) PutDomain(w http.ResponseWriter, r *http.Request) {
 			}
 			resInfo := cluster.InfoForResource(srv.Type, res.Name)
 			constraint := constraints[srv.Type][res.Name]
-			err = checkDomainQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, newQuota, canRaise, canLower)
+			commentMissing := newQuotaInput.Comment == "" && perms.needsComment(cluster, srv.Type, res.Name, res.Quota, newQuota)
+			err = checkDomainQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, newQuota, commentMissing, perms)
+			if err != nil {
+				errors = append(errors, err.Error())
+				continue
+			}
+			expiresAt, previousQuota, err := checkQuotaExpiry(srv.Type, res.Name, resInfo.Unit, newQuotaInput, newQuota, res.Quota, nil, nil)
 			if err != nil {
 				errors = append(errors, err.Error())
 				continue
 			}
 
-			auditTrail.Add("set quota %s.%s = %d -> %d for domain %s by user %s (%s)",
-				srv.Type, res.Name, res.Quota, newQuota,
-				dbDomain.UUID, token.UserUUID, token.UserName,
+			auditTrail.Add("set quota %s.%s = %d -> %d%s for domain %s by %s%s",
+				srv.Type, res.Name, res.Quota, newQuota, describeQuotaExpiry(expiresAt),
+				dbDomain.UUID, actor, describeQuotaComment(newQuotaInput.Comment),
 			)
+			history.Add(srv.Type, res.Name, res.Quota, newQuota)
 			res.Quota = newQuota
+			res.QuotaExpiresAt = expiresAt
+			res.PreviousQuota = previousQuota
+			res.QuotaComment = newQuotaInput.Comment
+			if newQuotas[srv.Type] == nil {
+				newQuotas[srv.Type] = make(map[string]uint64)
+			}
+			newQuotas[srv.Type][res.Name] = newQuota
 			err = tx.Insert(&res)
-			if ReturnError(w, err) {
-				return
+			if err != nil {
+				return nil, err
 			}
 		}
 	}
 
-	//if not legal, report errors to the user
+	//check the new quotas against the ratio constraints between resources
+	ratioErrors, err := checkQuotaRatios(cluster, tx, domainQuotasQuery, dbDomain.ID, newQuotas)
+	if err != nil {
+		return nil, err
+	}
+	errors = append(errors, ratioErrors...)
+
+	//if not legal, do not write anything
 	if len(errors) > 0 {
-		http.Error(w, strings.Join(errors, "\n"), 422)
-		return
+		return errors, nil
 	}
 
 	//update the DB with the new quotas
 	onlyQuota := func(c *gorp.ColumnMap) bool {
-		return c.ColumnName == "quota"
+		switch c.ColumnName {
+		case "quota", "quota_expires_at", "previous_quota", "quota_comment":
+			return true
+		default:
+			return false
+		}
 	}
 	_, err = tx.UpdateColumns(onlyQuota, resourcesToUpdateAsUntyped...)
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, err
+	}
+	err = saveQuotaChangeHistory(tx, history, dbDomain.ClusterID, dbDomain.ID, nil, actor)
+	if err != nil {
+		return nil, err
 	}
 	err = tx.Commit()
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, err
 	}
 	auditTrail.Commit()
+	return nil, nil
+}
 
-	//otherwise, report success
-	domains, err := reports.GetDomains(cluster, &dbDomain.ID, db.DB, reports.ReadFilter(r))
-	if ReturnError(w, err) {
-		return
-	}
-	if len(domains) == 0 {
-		http.Error(w, "no resource data found for domain", 500)
-		return
+func checkDomainQuotaUpdate(srv db.DomainService, res db.DomainResource, unit limes.Unit, domain *reports.Domain, constraint limes.QuotaConstraint, newQuota uint64, commentMissing bool, perms quotaPermissions) error {
+	projectsQuota := uint64(0)
+	usage := uint64(0)
+	if domainService, exists := domain.Services[srv.Type]; exists {
+		if domainResource, exists := domainService.Resources[res.Name]; exists {
+			projectsQuota = domainResource.ProjectsQuota
+			usage = domainResource.Usage
+		}
 	}
 
-	ReturnJSON(w, 200, map[string]interface{}{"domain": domains[0]})
-}
-
-func checkDomainQuotaUpdate(srv db.DomainService, res db.DomainResource, unit limes.Unit, domain *reports.Domain, constraint limes.QuotaConstraint, newQuota uint64, canRaise, canLower bool) error {
-	if !constraint.Allows(newQuota) {
+	if !constraint.Evaluate(usage, 0).Allows(newQuota) {
 		return fmt.Errorf("cannot change %s/%s quota: requested value %q contradicts constraint %q for this domain and resource",
 			srv.Type, res.Name, limes.ValueWithUnit{Value: newQuota, Unit: unit}, constraint.ToString(unit))
 	}
@@ -304,23 +393,20 @@ func checkDomainQuotaUpdate(srv db.DomainService, res db.DomainResource, unit li
 	//domain quota over the cluster capacity is explicitly allowed because
 	//capacity measurements are usually to be taken with a grain of salt)
 	if res.Quota < newQuota {
-		if canRaise {
-			return nil
+		if !perms.CanRaise {
+			return fmt.Errorf("cannot change %s/%s quota: user is not allowed to raise quotas in this project", srv.Type, res.Name)
+		}
+		if commentMissing {
+			return fmt.Errorf("cannot change %s/%s quota: a comment is required for raises of this size", srv.Type, res.Name)
 		}
-		return fmt.Errorf("cannot change %s/%s quota: user is not allowed to raise quotas in this project", srv.Type, res.Name)
+		return nil
 	}
 
 	//if quota is being lowered, permission is required and the domain quota may
 	//not be less than the sum of quotas that the domain gives out to projects
-	if !canLower {
+	if !perms.CanLower {
 		return fmt.Errorf("cannot change %s/%s quota: user is not allowed to lower quotas in this project", srv.Type, res.Name)
 	}
-	projectsQuota := uint64(0)
-	if domainService, exists := domain.Services[srv.Type]; exists {
-		if domainResource, exists := domainService.Resources[res.Name]; exists {
-			projectsQuota = domainResource.ProjectsQuota
-		}
-	}
 	if newQuota < projectsQuota {
 		return fmt.Errorf(
 			"cannot change %s/%s quota: domain quota may not be smaller than sum of project quotas in that domain (%s)",
@@ -331,3 +417,16 @@ func checkDomainQuotaUpdate(srv db.DomainService, res db.DomainResource, unit li
 
 	return nil
 }
+
+//getDomainReport returns the report for the given domain, as used for the
+//validation of quota changes.
+func getDomainReport(cluster *limes.Cluster, dbDomain *db.Domain) (*reports.Domain, error) {
+	domainReports, err := reports.GetDomains(cluster, &dbDomain.ID, db.DB, reports.Filter{})
+	if err != nil {
+		return nil, err
+	}
+	if len(domainReports) == 0 {
+		return nil, fmt.Errorf("no resource data found for domain")
+	}
+	return domainReports[0], nil
+}
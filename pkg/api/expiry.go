@@ -0,0 +1,66 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+//checkQuotaExpiry validates the expiry date of a temporary quota raise (if
+//one was requested), and returns the values for the quota_expires_at and
+//previous_quota columns of the resource. The current quota value and the
+//current values of these columns are given as arguments.
+//
+//If no expiry date was requested, the new quota is permanent and both
+//returned values are nil (i.e. a pending expiry will be discarded).
+func checkQuotaExpiry(serviceType, resourceName string, unit limes.Unit, input QuotaInput, newQuota, currentQuota uint64, currentExpiresAt *time.Time, currentPreviousQuota *uint64) (*time.Time, *uint64, error) {
+	if input.ExpiresAt == nil {
+		return nil, nil, nil
+	}
+	if !input.ExpiresAt.After(timeNow()) {
+		return nil, nil, fmt.Errorf("cannot change %s/%s quota: expiry date must be in the future", serviceType, resourceName)
+	}
+
+	//when a temporary quota raise is already pending, it gets replaced, but the
+	//quota will still revert to the value from before the first raise
+	previousQuota := currentQuota
+	if currentExpiresAt != nil && currentPreviousQuota != nil {
+		previousQuota = *currentPreviousQuota
+	}
+	if newQuota <= previousQuota {
+		return nil, nil, fmt.Errorf("cannot change %s/%s quota: an expiry date can only be given when raising the quota above %s",
+			serviceType, resourceName, limes.ValueWithUnit{Value: previousQuota, Unit: unit})
+	}
+
+	expiresAt := *input.ExpiresAt
+	return &expiresAt, &previousQuota, nil
+}
+
+//describeQuotaExpiry returns a suffix for audit log messages that describes
+//a temporary quota raise.
+func describeQuotaExpiry(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return " (expires at " + expiresAt.UTC().Format(time.RFC3339) + ")"
+}
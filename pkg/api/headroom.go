@@ -0,0 +1,248 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/sapcc/limes/pkg/datamodel"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+//ServiceHeadroom appears in the response of the headroom endpoints.
+type ServiceHeadroom struct {
+	Type      string             `json:"type"`
+	Resources []ResourceHeadroom `json:"resources"`
+}
+
+//ResourceHeadroom appears in type ServiceHeadroom. It contains the range of
+//quota values that the user can currently set for this resource.
+type ResourceHeadroom struct {
+	Name     string     `json:"name"`
+	Unit     limes.Unit `json:"unit,omitempty"`
+	Quota    uint64     `json:"quota"`
+	MinQuota uint64     `json:"min_quota"`
+	MaxQuota *uint64    `json:"max_quota,omitempty"` //nil if there is no upper bound
+}
+
+//quotaRange computes the range of quota values that a user can set for a
+//resource with the given current quota. The lowerBound and upperBound come
+//from the domain or project structure (e.g. usage or domain quota), and are
+//only relevant if the user is allowed to lower or raise the quota,
+//respectively. An upperBound of nil means that there is no upper bound. The
+//constraint must already have been evaluated and narrowed down by
+//applyQuotaRatios().
+//
+//If no value except for the current quota is acceptable (e.g. because the
+//current quota contradicts a constraint), the range only contains the current
+//quota.
+func quotaRange(quota, lowerBound uint64, upperBound *uint64, constraint limes.QuotaConstraint, canRaise, canLower bool) (uint64, *uint64) {
+	minQuota := quota
+	if canLower && lowerBound < quota {
+		minQuota = lowerBound
+	}
+	maxQuota := &quota
+	if canRaise {
+		if upperBound == nil {
+			maxQuota = nil
+		} else if *upperBound > quota {
+			maxQuota = upperBound
+		}
+	}
+
+	if constraint.Minimum != nil && *constraint.Minimum > minQuota {
+		minQuota = *constraint.Minimum
+	}
+	if constraint.Maximum != nil && (maxQuota == nil || *constraint.Maximum < *maxQuota) {
+		value := *constraint.Maximum
+		maxQuota = &value
+	}
+
+	if maxQuota != nil && minQuota > *maxQuota {
+		return quota, &quota
+	}
+	return minQuota, maxQuota
+}
+
+//GetDomainHeadroom handles GET /v1/domains/:domain_id/headroom.
+func (p *v1Provider) GetDomainHeadroom(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:show") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+	canRaise := token.Check("domain:raise")
+	canLower := token.Check("domain:lower")
+	if dbDomain.QuotaLocked && !token.Check("quota:lock") {
+		canRaise, canLower = false, false
+	}
+
+	domainReport, err := getDomainReport(cluster, dbDomain)
+	if ReturnError(w, err) {
+		return
+	}
+	var constraints limes.QuotaConstraints
+	if cluster.QuotaConstraints != nil {
+		constraints = cluster.QuotaConstraints.Domains[dbDomain.Name]
+	}
+	var quotas map[string]map[string]uint64
+	if hasQuotaRatios(cluster) {
+		quotas, err = getQuotasForRatios(db.DB, domainQuotasQuery, dbDomain.ID)
+		if ReturnError(w, err) {
+			return
+		}
+	}
+
+	serviceTypes := make([]string, 0, len(domainReport.Services))
+	for serviceType := range domainReport.Services {
+		serviceTypes = append(serviceTypes, serviceType)
+	}
+	sort.Strings(serviceTypes)
+
+	result := make([]ServiceHeadroom, 0, len(serviceTypes))
+	for _, serviceType := range serviceTypes {
+		srvReport := domainReport.Services[serviceType]
+		resourceNames := make([]string, 0, len(srvReport.Resources))
+		for resourceName := range srvReport.Resources {
+			resourceNames = append(resourceNames, resourceName)
+		}
+		sort.Strings(resourceNames)
+
+		srvHeadroom := ServiceHeadroom{Type: serviceType, Resources: []ResourceHeadroom{}}
+		for _, resourceName := range resourceNames {
+			resReport := srvReport.Resources[resourceName]
+			//the domain quota may not be lower than the sum of project quotas,
+			//but there is no upper bound except for constraints (see checkDomainQuotaUpdate)
+			constraint := constraints[serviceType][resourceName].Evaluate(resReport.Usage, 0)
+			constraint = applyQuotaRatios(cluster, quotas, serviceType, resourceName, constraint)
+			minQuota, maxQuota := quotaRange(resReport.DomainQuota, resReport.ProjectsQuota, nil, constraint, canRaise, canLower)
+			srvHeadroom.Resources = append(srvHeadroom.Resources, ResourceHeadroom{
+				Name:     resourceName,
+				Unit:     resReport.Unit,
+				Quota:    resReport.DomainQuota,
+				MinQuota: minQuota,
+				MaxQuota: maxQuota,
+			})
+		}
+		result = append(result, srvHeadroom)
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"services": result})
+}
+
+//GetProjectHeadroom handles GET /v1/domains/:domain_id/projects/:project_id/headroom.
+func (p *v1Provider) GetProjectHeadroom(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:show") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+	dbProject := p.FindProjectFromRequest(w, r, dbDomain)
+	if dbProject == nil {
+		return
+	}
+	canRaise := token.Check("project:raise")
+	canLower := token.Check("project:lower")
+	if dbProject.QuotaLocked && !token.Check("quota:lock") {
+		canRaise, canLower = false, false
+	}
+
+	domainReport, err := getDomainReport(cluster, dbDomain)
+	if ReturnError(w, err) {
+		return
+	}
+	var constraints limes.QuotaConstraints
+	if cluster.QuotaConstraints != nil {
+		constraints = cluster.QuotaConstraints.Projects[dbDomain.Name][dbProject.Name]
+	}
+	var quotas map[string]map[string]uint64
+	if hasQuotaRatios(cluster) {
+		quotas, err = getQuotasForRatios(db.DB, projectQuotasQuery, dbProject.ID)
+		if ReturnError(w, err) {
+			return
+		}
+	}
+
+	var services []db.ProjectService
+	_, err = db.DB.Select(&services,
+		`SELECT * FROM project_services WHERE project_id = $1 ORDER BY type`, dbProject.ID)
+	if ReturnError(w, err) {
+		return
+	}
+
+	result := make([]ServiceHeadroom, 0, len(services))
+	for _, srv := range services {
+		if !cluster.HasService(srv.Type) {
+			continue
+		}
+		var resources []db.ProjectResource
+		_, err = db.DB.Select(&resources,
+			`SELECT * FROM project_resources WHERE service_id = $1 ORDER BY name`, srv.ID)
+		if ReturnError(w, err) {
+			return
+		}
+		commitments, err := datamodel.GetProjectCommitments(db.DB, srv.ID, timeNow())
+		if ReturnError(w, err) {
+			return
+		}
+
+		srvHeadroom := ServiceHeadroom{Type: srv.Type, Resources: []ResourceHeadroom{}}
+		for _, res := range resources {
+			if !cluster.HasResource(srv.Type, res.Name) {
+				continue
+			}
+			//the project quota may not be lower than the usage or the active
+			//commitments, and may not exceed the part of the domain quota that is
+			//not given out to other projects (see checkProjectQuotaUpdate)
+			domainQuota, upperBound := projectQuotaBounds(srv.Type, res, domainReport)
+			lowerBound := res.Usage
+			if lowerBound < commitments[res.Name] {
+				lowerBound = commitments[res.Name]
+			}
+			constraint := constraints[srv.Type][res.Name].Evaluate(res.Usage, domainQuota)
+			constraint = applyQuotaRatios(cluster, quotas, srv.Type, res.Name, constraint)
+			minQuota, maxQuota := quotaRange(res.Quota, lowerBound, &upperBound, constraint, canRaise, canLower)
+			srvHeadroom.Resources = append(srvHeadroom.Resources, ResourceHeadroom{
+				Name:     res.Name,
+				Unit:     cluster.InfoForResource(srv.Type, res.Name).Unit,
+				Quota:    res.Quota,
+				MinQuota: minQuota,
+				MaxQuota: maxQuota,
+			})
+		}
+		result = append(result, srvHeadroom)
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"services": result})
+}
@@ -0,0 +1,451 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/sapcc/limes/pkg/datamodel"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	gorp "gopkg.in/gorp.v2"
+)
+
+//saveQuotaChangeHistory writes the changes made by a single call to
+//updateDomainQuotas or updateProjectQuotas into the quota change history. If
+//projectID is nil, the changes refer to the domain quotas.
+func saveQuotaChangeHistory(tx *gorp.Transaction, history datamodel.QuotaChangeHistory, clusterID string, domainID int64, projectID *int64, actor quotaChangeActor) error {
+	return history.Save(tx, db.QuotaChangeEvent{
+		ClusterID: clusterID,
+		DomainID:  domainID,
+		ProjectID: projectID,
+		UserUUID:  actor.UserUUID,
+		UserName:  actor.UserName,
+		Via:       actor.Via,
+		CreatedAt: timeNow().UTC(),
+	})
+}
+
+//QuotaChangeEvent is the API representation of a db.QuotaChangeEvent.
+type QuotaChangeEvent struct {
+	ID          int64           `json:"id"`
+	ProjectUUID string          `json:"project_id,omitempty"`
+	CreatedAt   int64           `json:"created_at"`
+	CreatedBy   ScheduledByUser `json:"created_by"`
+	Via         string          `json:"via,omitempty"`
+	Changes     []QuotaChange   `json:"changes"`
+}
+
+//QuotaChange is the API representation of a db.QuotaChange.
+type QuotaChange struct {
+	ServiceType  string     `json:"service"`
+	ResourceName string     `json:"resource"`
+	Unit         limes.Unit `json:"unit,omitempty"`
+	OldQuota     uint64     `json:"old_quota"`
+	NewQuota     uint64     `json:"new_quota"`
+}
+
+//QuotaRevertResult describes the outcome of reverting the quota of a single
+//resource.
+type QuotaRevertResult struct {
+	ProjectUUID   string     `json:"project_id,omitempty"`
+	ServiceType   string     `json:"service"`
+	ResourceName  string     `json:"resource"`
+	Unit          limes.Unit `json:"unit,omitempty"`
+	CurrentQuota  uint64     `json:"current_quota"`
+	PreviousQuota uint64     `json:"previous_quota"`
+	//one of "reverted", "skipped" or "failed"
+	Status        string   `json:"status"`
+	Errors        []string `json:"errors,omitempty"`
+	BackendErrors []string `json:"backend_errors,omitempty"`
+}
+
+//getDomainProjects returns all projects in the given domain, indexed by ID.
+func getDomainProjects(dbDomain *db.Domain) (map[int64]db.Project, error) {
+	var projects []db.Project
+	_, err := db.DB.Select(&projects, `SELECT * FROM projects WHERE domain_id = $1`, dbDomain.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int64]db.Project, len(projects))
+	for _, project := range projects {
+		result[project.ID] = project
+	}
+	return result, nil
+}
+
+//getQuotaChanges returns the changes belonging to the given events, indexed by event ID.
+func getQuotaChanges(events []db.QuotaChangeEvent) (map[int64][]db.QuotaChange, error) {
+	result := make(map[int64][]db.QuotaChange, len(events))
+	for _, event := range events {
+		var changes []db.QuotaChange
+		_, err := db.DB.Select(&changes,
+			`SELECT * FROM quota_changes WHERE event_id = $1 ORDER BY service_type, resource_name`, event.ID)
+		if err != nil {
+			return nil, err
+		}
+		result[event.ID] = changes
+	}
+	return result, nil
+}
+
+//ListQuotaChanges handles GET /v1/domains/:domain_id/quota-changes.
+func (p *v1Provider) ListQuotaChanges(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:show") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+
+	query := `SELECT * FROM quota_change_events WHERE domain_id = $1 ORDER BY id`
+	args := []interface{}{dbDomain.ID}
+	if userUUID := r.URL.Query().Get("user"); userUUID != "" {
+		query = `SELECT * FROM quota_change_events WHERE domain_id = $1 AND user_uuid = $2 ORDER BY id`
+		args = append(args, userUUID)
+	}
+	var events []db.QuotaChangeEvent
+	_, err := db.DB.Select(&events, query, args...)
+	if ReturnError(w, err) {
+		return
+	}
+	changes, err := getQuotaChanges(events)
+	if ReturnError(w, err) {
+		return
+	}
+	projects, err := getDomainProjects(dbDomain)
+	if ReturnError(w, err) {
+		return
+	}
+
+	result := make([]QuotaChangeEvent, len(events))
+	for idx, event := range events {
+		result[idx] = QuotaChangeEvent{
+			ID:        event.ID,
+			CreatedAt: event.CreatedAt.Unix(),
+			CreatedBy: ScheduledByUser{
+				UUID: event.UserUUID,
+				Name: event.UserName,
+			},
+			Via:     event.Via,
+			Changes: []QuotaChange{},
+		}
+		if event.ProjectID != nil {
+			result[idx].ProjectUUID = projects[*event.ProjectID].UUID
+		}
+		for _, change := range changes[event.ID] {
+			result[idx].Changes = append(result[idx].Changes, QuotaChange{
+				ServiceType:  change.ServiceType,
+				ResourceName: change.ResourceName,
+				Unit:         cluster.InfoForResource(change.ServiceType, change.ResourceName).Unit,
+				OldQuota:     change.OldQuota,
+				NewQuota:     change.NewQuota,
+			})
+		}
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"quota_changes": result})
+}
+
+//quotaRevertKey identifies a single resource whose quota shall be reverted.
+type quotaRevertKey struct {
+	ProjectID    int64 //0 for domain quotas
+	ServiceType  string
+	ResourceName string
+}
+
+//quotaRevert describes how the quota of a single resource shall be reverted.
+type quotaRevert struct {
+	Key quotaRevertKey
+	//the quota before the first of the reverted changes
+	PreviousQuota uint64
+	//the quota after the last of the reverted changes
+	ExpectedQuota uint64
+	//the IDs of the events containing the first and last of the reverted changes
+	FirstEventID int64
+	LastEventID  int64
+	//whether someone else changed the quota between the reverted changes
+	ChangedInBetween bool
+}
+
+//RevertQuotaChanges handles POST /v1/domains/:domain_id/quota-changes/revert.
+func (p *v1Provider) RevertQuotaChanges(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	domainPerms := token.permissionsFor("domain")
+	if !domainPerms.CanRaise && !domainPerms.CanLower {
+		token.Require(w, "domain:raise") //produce standard Unauthorized response
+		return
+	}
+	projectPerms := token.permissionsFor("project")
+
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+
+	//parse request body
+	var parseTarget struct {
+		Revert struct {
+			EventID  *int64  `json:"event_id"`
+			UserUUID *string `json:"user_id"`
+			Since    *int64  `json:"since"`
+			Until    *int64  `json:"until"`
+			Comment  string  `json:"comment"`
+		} `json:"revert"`
+	}
+	if !RequireJSON(w, r, &parseTarget) {
+		return
+	}
+	input := parseTarget.Revert
+
+	//find the events that shall be reverted
+	var (
+		events []db.QuotaChangeEvent
+		via    string
+		err    error
+	)
+	switch {
+	case input.EventID != nil && input.UserUUID == nil:
+		_, err = db.DB.Select(&events,
+			`SELECT * FROM quota_change_events WHERE domain_id = $1 AND id = $2`,
+			dbDomain.ID, *input.EventID)
+		if ReturnError(w, err) {
+			return
+		}
+		if len(events) == 0 {
+			http.Error(w, "no such quota change event", 404)
+			return
+		}
+		via = fmt.Sprintf("through revert of quota change event %d", *input.EventID)
+	case input.EventID == nil && input.UserUUID != nil:
+		if input.Since == nil {
+			http.Error(w, `"since" is missing`, 422)
+			return
+		}
+		until := timeNow()
+		if input.Until != nil {
+			until = time.Unix(*input.Until, 0)
+		}
+		_, err = db.DB.Select(&events, `
+			SELECT * FROM quota_change_events
+			 WHERE domain_id = $1 AND user_uuid = $2 AND created_at >= $3 AND created_at <= $4
+			 ORDER BY id`,
+			dbDomain.ID, *input.UserUUID, time.Unix(*input.Since, 0).UTC(), until.UTC())
+		if ReturnError(w, err) {
+			return
+		}
+		via = fmt.Sprintf("through revert of quota changes by user %s", *input.UserUUID)
+	default:
+		http.Error(w, `exactly one of "event_id" and "user_id" must be given`, 422)
+		return
+	}
+
+	changes, err := getQuotaChanges(events)
+	if ReturnError(w, err) {
+		return
+	}
+	projects, err := getDomainProjects(dbDomain)
+	if ReturnError(w, err) {
+		return
+	}
+
+	//for each resource, revert to the quota before the first matching change
+	//(events are ordered by ID, so the first change seen is the oldest one)
+	reverts := make(map[quotaRevertKey]*quotaRevert)
+	for _, event := range events {
+		for _, change := range changes[event.ID] {
+			key := quotaRevertKey{ServiceType: change.ServiceType, ResourceName: change.ResourceName}
+			if event.ProjectID != nil {
+				key.ProjectID = *event.ProjectID
+			}
+			if revert, exists := reverts[key]; exists {
+				revert.ExpectedQuota = change.NewQuota
+				revert.LastEventID = event.ID
+			} else {
+				reverts[key] = &quotaRevert{
+					Key:           key,
+					PreviousQuota: change.OldQuota,
+					ExpectedQuota: change.NewQuota,
+					FirstEventID:  event.ID,
+					LastEventID:   event.ID,
+				}
+			}
+		}
+	}
+
+	//when reverting all changes by a user, quotas that someone else (or
+	//limes-collect) changed in between must not be reverted, since that would
+	//silently undo the other changes as well
+	if input.UserUUID != nil && len(events) > 1 {
+		isReverted := make(map[int64]bool, len(events))
+		for _, event := range events {
+			isReverted[event.ID] = true
+		}
+		var otherEvents []db.QuotaChangeEvent
+		_, err = db.DB.Select(&otherEvents, `
+			SELECT * FROM quota_change_events WHERE domain_id = $1 AND id > $2 AND id < $3`,
+			dbDomain.ID, events[0].ID, events[len(events)-1].ID)
+		if ReturnError(w, err) {
+			return
+		}
+		otherChanges, err := getQuotaChanges(otherEvents)
+		if ReturnError(w, err) {
+			return
+		}
+		for _, event := range otherEvents {
+			if isReverted[event.ID] {
+				continue
+			}
+			for _, change := range otherChanges[event.ID] {
+				key := quotaRevertKey{ServiceType: change.ServiceType, ResourceName: change.ResourceName}
+				if event.ProjectID != nil {
+					key.ProjectID = *event.ProjectID
+				}
+				revert, exists := reverts[key]
+				if exists && revert.FirstEventID < event.ID && event.ID < revert.LastEventID {
+					revert.ChangedInBetween = true
+				}
+			}
+		}
+	}
+
+	//revert project quotas before domain quotas, since lowering a domain quota
+	//is only possible once the project quotas have been lowered
+	sortedReverts := make([]*quotaRevert, 0, len(reverts))
+	for _, revert := range reverts {
+		sortedReverts = append(sortedReverts, revert)
+	}
+	sort.Slice(sortedReverts, func(i, j int) bool {
+		ki, kj := sortedReverts[i].Key, sortedReverts[j].Key
+		if (ki.ProjectID == 0) != (kj.ProjectID == 0) {
+			return kj.ProjectID == 0
+		}
+		if ki.ProjectID != kj.ProjectID {
+			return projects[ki.ProjectID].Name < projects[kj.ProjectID].Name
+		}
+		if ki.ServiceType != kj.ServiceType {
+			return ki.ServiceType < kj.ServiceType
+		}
+		return ki.ResourceName < kj.ResourceName
+	})
+
+	actor := actorFor(token)
+	actor.Via = via
+	results := make([]QuotaRevertResult, len(sortedReverts))
+	for idx, revert := range sortedReverts {
+		var dbProject *db.Project
+		if revert.Key.ProjectID != 0 {
+			project := projects[revert.Key.ProjectID]
+			dbProject = &project
+		}
+		results[idx], err = revertQuota(cluster, dbDomain, dbProject, *revert, input.Comment, domainPerms, projectPerms, actor)
+		if ReturnError(w, err) {
+			return
+		}
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"results": results})
+}
+
+//revertQuota reverts the quota of a single domain or project resource. Quotas
+//that have been changed again since the reverted changes are left alone.
+func revertQuota(cluster *limes.Cluster, dbDomain *db.Domain, dbProject *db.Project, revert quotaRevert, comment string, domainPerms, projectPerms quotaPermissions, actor quotaChangeActor) (QuotaRevertResult, error) {
+	key := revert.Key
+	result := QuotaRevertResult{
+		ServiceType:   key.ServiceType,
+		ResourceName:  key.ResourceName,
+		Unit:          cluster.InfoForResource(key.ServiceType, key.ResourceName).Unit,
+		PreviousQuota: revert.PreviousQuota,
+		Status:        "skipped",
+	}
+
+	var err error
+	if dbProject == nil {
+		err = db.DB.QueryRow(`
+			SELECT dr.quota FROM domain_resources dr JOIN domain_services ds ON ds.id = dr.service_id
+			 WHERE ds.domain_id = $1 AND ds.type = $2 AND dr.name = $3`,
+			dbDomain.ID, key.ServiceType, key.ResourceName).Scan(&result.CurrentQuota)
+	} else {
+		result.ProjectUUID = dbProject.UUID
+		err = db.DB.QueryRow(`
+			SELECT pr.quota FROM project_resources pr JOIN project_services ps ON ps.id = pr.service_id
+			 WHERE ps.project_id = $1 AND ps.type = $2 AND pr.name = $3`,
+			dbProject.ID, key.ServiceType, key.ResourceName).Scan(&result.CurrentQuota)
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		result.Errors = []string{"resource does not exist anymore"}
+		return result, nil
+	case err != nil:
+		return result, err
+	}
+
+	switch {
+	case revert.ChangedInBetween:
+		result.Errors = []string{"quota has been changed by someone else in between"}
+		return result, nil
+	case result.CurrentQuota != revert.ExpectedQuota:
+		result.Errors = []string{"quota has been changed again since"}
+		return result, nil
+	case result.CurrentQuota == revert.PreviousQuota:
+		result.Errors = []string{"quota already has the previous value"}
+		return result, nil
+	}
+
+	serviceQuotas := ServiceQuotas{
+		key.ServiceType: ResourceQuotas{
+			key.ResourceName: QuotaInput{
+				ValueWithUnit: limes.ValueWithUnit{Value: revert.PreviousQuota, Unit: limes.UnitUnspecified},
+				Comment:       comment,
+			},
+		},
+	}
+	if dbProject == nil {
+		if cluster.ApprovalPolicy.NeedsApproval(key.ServiceType, key.ResourceName, result.CurrentQuota, revert.PreviousQuota) {
+			result.Errors = []string{"raise exceeds the approval threshold and must be confirmed by a second user"}
+		} else {
+			result.Errors, err = updateDomainQuotas(cluster, dbDomain, serviceQuotas, domainPerms, actor)
+		}
+	} else {
+		result.Errors, result.BackendErrors, err = updateProjectQuotas(cluster, dbDomain, dbProject, serviceQuotas, projectPerms, actor)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if len(result.Errors) > 0 {
+		result.Status = "failed"
+	} else {
+		result.Status = "reverted"
+	}
+	return result, nil
+}
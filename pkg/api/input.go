@@ -21,6 +21,9 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/sapcc/limes/pkg/limes"
 )
@@ -33,16 +36,30 @@ type ServiceQuotas map[string]ResourceQuotas
 //ResourceQuotas contains new quota values for the resources in a single
 //service. The map key is the resource name. This type is used to unserialize
 //JSON request bodies in PUT requests.
-type ResourceQuotas map[string]limes.ValueWithUnit
+type ResourceQuotas map[string]QuotaInput
+
+//QuotaInput contains the new quota value for a single resource, as given in
+//the request body of a PUT request.
+type QuotaInput struct {
+	limes.ValueWithUnit
+	//ExpiresAt is only set for temporary quota raises. When this time has
+	//passed, the collector reverts the quota to its previous value.
+	ExpiresAt *time.Time
+	//Comment is an optional justification for the quota change. It is stored
+	//with the resource and recorded in the audit trail.
+	Comment string
+}
 
 //UnmarshalJSON implements the json.Unmarshaler interface.
 func (sq *ServiceQuotas) UnmarshalJSON(input []byte) error {
 	var data []struct {
 		Type      string `json:"type"`
 		Resources []struct {
-			Name  string      `json:"name"`
-			Quota uint64      `json:"quota"`
-			Unit  *limes.Unit `json:"unit"`
+			Name      string      `json:"name"`
+			Quota     json.Number `json:"quota"`
+			Unit      *limes.Unit `json:"unit"`
+			ExpiresAt *int64      `json:"expires_at"`
+			Comment   string      `json:"comment"`
 		} `json:"resources"`
 	}
 	err := json.Unmarshal(input, &data)
@@ -63,16 +80,56 @@ func (sq *ServiceQuotas) UnmarshalJSON(input []byte) error {
 			if res.Unit != nil {
 				unit = *res.Unit
 			}
-			rq[res.Name] = limes.ValueWithUnit{
-				Value: res.Quota,
-				Unit:  unit,
+			//fractional values (e.g. 1.5 TiB) are only allowed with an explicit unit
+			if unit == limes.UnitUnspecified && strings.Contains(string(res.Quota), ".") {
+				return fmt.Errorf("invalid quota value for %s/%s: fractional values require a unit", srv.Type, res.Name)
+			}
+			number := string(res.Quota)
+			if number == "" {
+				number = "0"
+			}
+			value, err := limes.ParseValueWithUnit(number, unit)
+			if err != nil {
+				return fmt.Errorf("invalid quota value for %s/%s: %s", srv.Type, res.Name, err.Error())
 			}
+			input := QuotaInput{ValueWithUnit: value, Comment: res.Comment}
+			if res.ExpiresAt != nil {
+				expiresAt := time.Unix(*res.ExpiresAt, 0).UTC()
+				input.ExpiresAt = &expiresAt
+			}
+			rq[res.Name] = input
 		}
 		(*sq)[srv.Type] = rq
 	}
 	return nil
 }
 
+//SetDefaultComment sets the given comment on all quota inputs that do not
+//have a comment of their own. This is used when a comment is given for the
+//whole request instead of for individual resources.
+func (sq ServiceQuotas) SetDefaultComment(comment string) {
+	if comment == "" {
+		return
+	}
+	for _, rq := range sq {
+		for resName, input := range rq {
+			if input.Comment == "" {
+				input.Comment = comment
+				rq[resName] = input
+			}
+		}
+	}
+}
+
+//describeQuotaComment returns a suffix for audit log messages that contains
+//the comment given for a quota change.
+func describeQuotaComment(comment string) string {
+	if comment == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (comment: %q)", comment)
+}
+
 //ServiceCapacities contains updated capacity values for some or all resources
 //in a single service.
 type ServiceCapacities struct {
@@ -0,0 +1,140 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+//LockDomain handles PUT /v1/domains/:domain_id/lock.
+func (p *v1Provider) LockDomain(w http.ResponseWriter, r *http.Request) {
+	p.setDomainLock(w, r, true)
+}
+
+//UnlockDomain handles DELETE /v1/domains/:domain_id/lock.
+func (p *v1Provider) UnlockDomain(w http.ResponseWriter, r *http.Request) {
+	p.setDomainLock(w, r, false)
+}
+
+func (p *v1Provider) setDomainLock(w http.ResponseWriter, r *http.Request, locked bool) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "quota:lock") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+
+	reason, ok := parseLockReason(w, r, locked)
+	if !ok {
+		return
+	}
+	_, err := db.DB.Exec(`UPDATE domains SET quota_locked = $1, quota_lock_reason = $2 WHERE id = $3`,
+		locked, reason, dbDomain.ID)
+	if ReturnError(w, err) {
+		return
+	}
+
+	var auditTrail util.AuditTrail
+	if locked {
+		auditTrail.Add("lock quotas for domain %s by %s: %s", dbDomain.UUID, describeUser(token), reason)
+	} else {
+		auditTrail.Add("unlock quotas for domain %s by %s", dbDomain.UUID, describeUser(token))
+	}
+	auditTrail.Commit()
+	w.WriteHeader(204)
+}
+
+//LockProject handles PUT /v1/domains/:domain_id/projects/:project_id/lock.
+func (p *v1Provider) LockProject(w http.ResponseWriter, r *http.Request) {
+	p.setProjectLock(w, r, true)
+}
+
+//UnlockProject handles DELETE /v1/domains/:domain_id/projects/:project_id/lock.
+func (p *v1Provider) UnlockProject(w http.ResponseWriter, r *http.Request) {
+	p.setProjectLock(w, r, false)
+}
+
+func (p *v1Provider) setProjectLock(w http.ResponseWriter, r *http.Request, locked bool) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "quota:lock") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+	dbProject := p.FindProjectFromRequest(w, r, dbDomain)
+	if dbProject == nil {
+		return
+	}
+
+	reason, ok := parseLockReason(w, r, locked)
+	if !ok {
+		return
+	}
+	_, err := db.DB.Exec(`UPDATE projects SET quota_locked = $1, quota_lock_reason = $2 WHERE id = $3`,
+		locked, reason, dbProject.ID)
+	if ReturnError(w, err) {
+		return
+	}
+
+	var auditTrail util.AuditTrail
+	if locked {
+		auditTrail.Add("lock quotas for project %s by %s: %s", dbProject.UUID, describeUser(token), reason)
+	} else {
+		auditTrail.Add("unlock quotas for project %s by %s", dbProject.UUID, describeUser(token))
+	}
+	auditTrail.Commit()
+	w.WriteHeader(204)
+}
+
+//parseLockReason reads the reason for a new lock from the request body. When
+//a lock is removed, there is no request body and the reason is empty.
+func parseLockReason(w http.ResponseWriter, r *http.Request, locked bool) (string, bool) {
+	if !locked {
+		return "", true
+	}
+	var parseTarget struct {
+		Lock struct {
+			Reason string `json:"reason"`
+		} `json:"lock"`
+	}
+	if !RequireJSON(w, r, &parseTarget) {
+		return "", false
+	}
+	if parseTarget.Lock.Reason == "" {
+		http.Error(w, "reason is missing", 422)
+		return "", false
+	}
+	return parseTarget.Lock.Reason, true
+}
@@ -28,6 +28,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/sapcc/limes/pkg/collector"
+	"github.com/sapcc/limes/pkg/datamodel"
 	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/reports"
@@ -174,9 +175,8 @@ func (p *v1Provider) SyncProject(w http.ResponseWriter, r *http.Request) {
 //PutProject handles PUT /v1/domains/:domain_id/projects/:project_id.
 func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 	token := p.CheckToken(r)
-	canRaise := token.Check("project:raise")
-	canLower := token.Check("project:lower")
-	if !canRaise && !canLower {
+	perms := token.permissionsFor("project")
+	if !perms.CanRaise && !perms.CanLower {
 		token.Require(w, "project:raise") //produce standard Unauthorized response
 		return
 	}
@@ -198,6 +198,7 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 	var parseTarget struct {
 		Project struct {
 			Services ServiceQuotas `json:"services"`
+			Comment  string        `json:"comment"`
 		} `json:"project"`
 	}
 	parseTarget.Project.Services = make(ServiceQuotas)
@@ -205,24 +206,62 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	serviceQuotas := parseTarget.Project.Services
+	serviceQuotas.SetDefaultComment(parseTarget.Project.Comment)
 
-	//start a transaction for the quota updates
-	tx, err := db.DB.Begin()
+	errors, backendErrors, err := updateProjectQuotas(cluster, dbDomain, dbProject, serviceQuotas, perms, actorFor(token))
 	if ReturnError(w, err) {
 		return
 	}
-	defer db.RollbackUnlessCommitted(tx)
 
-	//gather a report on the domain's quotas to decide whether a quota update is legal
-	domainReports, err := reports.GetDomains(cluster, &dbDomain.ID, db.DB, reports.Filter{})
+	//if not legal, report errors to the user
+	if len(errors) > 0 {
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+
+	//report any backend errors to the user
+	if len(backendErrors) > 0 {
+		msg := "quotas have been accepted, but some error(s) occurred while trying to write the quotas into the backend services:"
+		http.Error(w, msg+"\n"+strings.Join(backendErrors, "\n"), 202)
+		return
+	}
+
+	//otherwise, report success
+	projects, err := reports.GetProjects(cluster, dbDomain.ID, &dbProject.ID, db.DB, reports.Filter{}, false)
 	if ReturnError(w, err) {
 		return
 	}
-	if len(domainReports) == 0 {
-		http.Error(w, "no resource data found for domain", 500)
+	if len(projects) == 0 {
+		http.Error(w, "no resource data found for project", 500)
 		return
 	}
-	domainReport := domainReports[0]
+
+	ReturnJSON(w, 200, map[string]interface{}{"project": projects[0]})
+}
+
+//updateProjectQuotas validates and applies the given quota changes to the
+//given project. If any of the changes is not acceptable, nothing is written,
+//and the validation errors are returned. Otherwise, the new quotas are written
+//into the backend, and any errors that occur while doing so are returned as
+//backendErrors. The actor is used in the audit trail to describe who requested
+//the changes.
+func updateProjectQuotas(cluster *limes.Cluster, dbDomain *db.Domain, dbProject *db.Project, serviceQuotas ServiceQuotas, perms quotaPermissions, actor quotaChangeActor) (errors, backendErrors []string, err error) {
+	if dbProject.QuotaLocked && !perms.CanBypassLock {
+		return []string{"cannot change quotas: quotas of this project are locked: " + dbProject.QuotaLockReason}, nil, nil
+	}
+
+	//start a transaction for the quota updates
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	//gather a report on the domain's quotas to decide whether a quota update is legal
+	domainReport, err := getDomainReport(cluster, dbDomain)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var constraints limes.QuotaConstraints
 	if cluster.QuotaConstraints != nil {
@@ -233,15 +272,16 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 	var services []db.ProjectService
 	_, err = tx.Select(&services,
 		`SELECT * FROM project_services WHERE project_id = $1 ORDER BY type`, dbProject.ID)
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, nil, err
 	}
 	var resourcesToUpdate []db.ProjectResource
 	var resourcesToUpdateAsUntyped []interface{}
 	servicesToUpdate := make(map[string]bool)
-	var errors []string
+	newQuotas := make(map[string]map[string]uint64)
 
 	var auditTrail util.AuditTrail
+	var history datamodel.QuotaChangeHistory
 	for _, srv := range services {
 		resourceQuotas, exists := serviceQuotas[srv.Type]
 		if !exists {
@@ -252,8 +292,12 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 		var resources []db.ProjectResource
 		_, err = tx.Select(&resources,
 			`SELECT * FROM project_resources WHERE service_id = $1 ORDER BY name`, srv.ID)
-		if ReturnError(w, err) {
-			return
+		if err != nil {
+			return nil, nil, err
+		}
+		commitments, err := datamodel.GetProjectCommitments(tx, srv.ID, timeNow())
+		if err != nil {
+			return nil, nil, err
 		}
 		for _, res := range resources {
 			newQuotaInput, exists := resourceQuotas[res.Name]
@@ -265,13 +309,21 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 				errors = append(errors, fmt.Sprintf("cannot change %s/%s quota: %s", srv.Type, res.Name, err.Error()))
 				continue
 			}
-			if res.Quota == newQuota {
+			//(if a temporary raise is pending, setting the same value without
+			//expiry date still needs to be written to make it permanent)
+			if res.Quota == newQuota && newQuotaInput.ExpiresAt == nil && res.QuotaExpiresAt == nil {
 				continue //nothing to do
 			}
 
 			resInfo := cluster.InfoForResource(srv.Type, res.Name)
 			constraint := constraints[srv.Type][res.Name]
-			err = checkProjectQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, newQuota, canRaise, canLower)
+			commentMissing := newQuotaInput.Comment == "" && perms.needsComment(cluster, srv.Type, res.Name, res.Quota, newQuota)
+			err = checkProjectQuotaUpdate(srv, res, resInfo.Unit, domainReport, constraint, commitments[res.Name], newQuota, commentMissing, perms)
+			if err != nil {
+				errors = append(errors, err.Error())
+				continue
+			}
+			expiresAt, previousQuota, err := checkQuotaExpiry(srv.Type, res.Name, resInfo.Unit, newQuotaInput, newQuota, res.Quota, res.QuotaExpiresAt, res.PreviousQuota)
 			if err != nil {
 				errors = append(errors, err.Error())
 				continue
@@ -281,34 +333,57 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 			//we didn't take a copy manually, the resourcesToUpdateAsUntyped list
 			//would contain only identical pointers)
 			res := res
-			auditTrail.Add("set quota %s.%s = %d -> %d for project %s by user %s (%s)",
-				srv.Type, res.Name, res.Quota, newQuota,
-				dbProject.UUID, token.UserUUID, token.UserName,
+			auditTrail.Add("set quota %s.%s = %d -> %d%s for project %s by %s%s",
+				srv.Type, res.Name, res.Quota, newQuota, describeQuotaExpiry(expiresAt),
+				dbProject.UUID, actor, describeQuotaComment(newQuotaInput.Comment),
 			)
+			history.Add(srv.Type, res.Name, res.Quota, newQuota)
 			res.Quota = newQuota
+			res.QuotaExpiresAt = expiresAt
+			res.PreviousQuota = previousQuota
+			res.QuotaComment = newQuotaInput.Comment
+			if newQuotas[srv.Type] == nil {
+				newQuotas[srv.Type] = make(map[string]uint64)
+			}
+			newQuotas[srv.Type][res.Name] = newQuota
 			resourcesToUpdate = append(resourcesToUpdate, res)
 			resourcesToUpdateAsUntyped = append(resourcesToUpdateAsUntyped, &res)
 			servicesToUpdate[srv.Type] = true
 		}
 	}
 
-	//if not legal, report errors to the user
+	//check the new quotas against the ratio constraints between resources
+	ratioErrors, err := checkQuotaRatios(cluster, tx, projectQuotasQuery, dbProject.ID, newQuotas)
+	if err != nil {
+		return nil, nil, err
+	}
+	errors = append(errors, ratioErrors...)
+
+	//if not legal, do not write anything
 	if len(errors) > 0 {
-		http.Error(w, strings.Join(errors, "\n"), 422)
-		return
+		return errors, nil, nil
 	}
 
 	//update the DB with the new quotas
 	onlyQuota := func(c *gorp.ColumnMap) bool {
-		return c.ColumnName == "quota"
+		switch c.ColumnName {
+		case "quota", "quota_expires_at", "previous_quota", "quota_comment":
+			return true
+		default:
+			return false
+		}
 	}
 	_, err = tx.UpdateColumns(onlyQuota, resourcesToUpdateAsUntyped...)
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, nil, err
+	}
+	err = saveQuotaChangeHistory(tx, history, dbDomain.ClusterID, dbDomain.ID, &dbProject.ID, actor)
+	if err != nil {
+		return nil, nil, err
 	}
 	err = tx.Commit()
-	if ReturnError(w, err) {
-		return
+	if err != nil {
+		return nil, nil, err
 	}
 	auditTrail.Commit()
 
@@ -318,7 +393,6 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 	//fails, then subsequent scraping tasks will try to apply the quota again
 	//until the operation succeeds. What's important is that the approved quota
 	//budget inside Limes is redistributed.
-	errors = nil
 	for _, srv := range services {
 		if !servicesToUpdate[srv.Type] {
 			continue
@@ -326,7 +400,7 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 
 		plugin := cluster.QuotaPlugins[srv.Type]
 		if plugin == nil {
-			errors = append(errors, fmt.Sprintf("no quota plugin registered for service type %s", srv.Type))
+			backendErrors = append(backendErrors, fmt.Sprintf("no quota plugin registered for service type %s", srv.Type))
 			continue
 		}
 
@@ -337,8 +411,8 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 		var resources []db.ProjectResource
 		_, err = db.DB.Select(&resources,
 			`SELECT * FROM project_resources WHERE service_id = $1`, srv.ID)
-		if ReturnError(w, err) {
-			return
+		if err != nil {
+			return nil, nil, err
 		}
 		for _, res := range resources {
 			quotaValues[res.Name] = res.Quota
@@ -348,7 +422,7 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 			cluster.ID, dbDomain.UUID, dbProject.UUID, quotaValues,
 		)
 		if err != nil {
-			errors = append(errors, err.Error())
+			backendErrors = append(backendErrors, err.Error())
 			continue
 		}
 
@@ -356,33 +430,43 @@ func (p *v1Provider) PutProject(w http.ResponseWriter, r *http.Request) {
 		_, err = db.DB.Exec(
 			`UPDATE project_resources SET backend_quota = quota WHERE service_id = $1`,
 			srv.ID)
-		if ReturnError(w, err) {
-			return
+		if err != nil {
+			return nil, nil, err
 		}
 	}
 
-	//report any backend errors to the user
-	if len(errors) > 0 {
-		msg := "quotas have been accepted, but some error(s) occurred while trying to write the quotas into the backend services:"
-		http.Error(w, msg+"\n"+strings.Join(errors, "\n"), 202)
-		return
-	}
+	return nil, backendErrors, nil
+}
 
-	//otherwise, report success
-	projects, err := reports.GetProjects(cluster, dbDomain.ID, &dbProject.ID, db.DB, reports.Filter{}, false)
-	if ReturnError(w, err) {
-		return
-	}
-	if len(projects) == 0 {
-		http.Error(w, "no resource data found for project", 500)
-		return
+//projectQuotaBounds returns the domain quota for the given project resource,
+//and the largest project quota that fits into the part of the domain quota
+//that is not given out to other projects.
+func projectQuotaBounds(serviceType string, res db.ProjectResource, domain *reports.Domain) (domainQuota, maxQuota uint64) {
+	projectsQuota := uint64(0)
+	if domainService, exists := domain.Services[serviceType]; exists {
+		if domainResource, exists := domainService.Resources[res.Name]; exists {
+			domainQuota = domainResource.DomainQuota
+			projectsQuota = domainResource.ProjectsQuota
+		}
 	}
 
-	ReturnJSON(w, 200, map[string]interface{}{"project": projects[0]})
+	//NOTE: It looks like an arithmetic overflow (or rather, underflow) is
+	//possible here, but it isn't. projectsQuota is the sum over all current
+	//project quotas, including res.Quota, and thus is always bigger (since these
+	//quotas are all unsigned). Also, we're doing everything in a transaction, so
+	//an overflow because of concurrent quota changes is also out of the
+	//question.
+	otherProjectsQuota := projectsQuota - res.Quota
+	if domainQuota < otherProjectsQuota {
+		return domainQuota, 0
+	}
+	return domainQuota, domainQuota - otherProjectsQuota
 }
 
-func checkProjectQuotaUpdate(srv db.ProjectService, res db.ProjectResource, unit limes.Unit, domain *reports.Domain, constraint limes.QuotaConstraint, newQuota uint64, canRaise, canLower bool) error {
-	if !constraint.Allows(newQuota) {
+func checkProjectQuotaUpdate(srv db.ProjectService, res db.ProjectResource, unit limes.Unit, domain *reports.Domain, constraint limes.QuotaConstraint, committed, newQuota uint64, commentMissing bool, perms quotaPermissions) error {
+	domainQuota, maxQuota := projectQuotaBounds(srv.Type, res, domain)
+
+	if !constraint.Evaluate(res.Usage, domainQuota).Allows(newQuota) {
 		return fmt.Errorf("cannot change %s/%s quota: requested value %q contradicts constraint %q for this project and resource",
 			srv.Type, res.Name, limes.ValueWithUnit{Value: newQuota, Unit: unit}, constraint.ToString(unit))
 	}
@@ -391,39 +475,28 @@ func checkProjectQuotaUpdate(srv db.ProjectService, res db.ProjectResource, unit
 	//(note that both res.Quota and newQuota are uint64, so we do not need to
 	//cover the case of infinite quotas)
 	if res.Quota > newQuota {
-		if !canLower {
+		if !perms.CanLower {
 			return fmt.Errorf("cannot change %s/%s quota: user is not allowed to lower quotas in this project", srv.Type, res.Name)
 		}
 		if res.Usage > newQuota {
 			return fmt.Errorf("cannot change %s/%s quota: quota may not be lower than current usage", srv.Type, res.Name)
 		}
+		if committed > newQuota {
+			return fmt.Errorf("cannot change %s/%s quota: quota may not be lower than active commitments (%s)",
+				srv.Type, res.Name, limes.ValueWithUnit{Value: committed, Unit: unit})
+		}
 		return nil
 	}
 
-	//if quota is being raised, permission is required and also the domain quota may not be exceeded
-	if !canRaise {
+	//if quota is being raised, permission (and possibly a comment) is required
+	//and also the domain quota may not be exceeded
+	if !perms.CanRaise {
 		return fmt.Errorf("cannot change %s/%s quota: user is not allowed to raise quotas in this project", srv.Type, res.Name)
 	}
-	domainQuota := uint64(0)
-	projectsQuota := uint64(0)
-	if domainService, exists := domain.Services[srv.Type]; exists {
-		if domainResource, exists := domainService.Resources[res.Name]; exists {
-			domainQuota = domainResource.DomainQuota
-			projectsQuota = domainResource.ProjectsQuota
-		}
+	if commentMissing {
+		return fmt.Errorf("cannot change %s/%s quota: a comment is required for raises of this size", srv.Type, res.Name)
 	}
-	//NOTE: It looks like an arithmetic overflow (or rather, underflow) is
-	//possible here, but it isn't. projectsQuota is the sum over all current
-	//project quotas, including res.Quota, and thus is always bigger (since these
-	//quotas are all unsigned). Also, we're doing everything in a transaction, so
-	//an overflow because of concurrent quota changes is also out of the
-	//question.
-	newProjectsQuota := projectsQuota - res.Quota + newQuota
-	if newProjectsQuota > domainQuota {
-		maxQuota := domainQuota - (projectsQuota - res.Quota)
-		if domainQuota < projectsQuota-res.Quota {
-			maxQuota = 0
-		}
+	if newQuota > maxQuota {
 		return fmt.Errorf("cannot change %s/%s quota: domain quota exceeded (maximum acceptable project quota is %s)",
 			srv.Type, res.Name,
 			limes.ValueWithUnit{Value: maxQuota, Unit: unit},
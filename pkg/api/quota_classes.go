@@ -0,0 +1,209 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+//QuotaClassReport is the API representation of a limes.QuotaClass.
+type QuotaClassReport struct {
+	Name     string                    `json:"name"`
+	Services []QuotaClassServiceReport `json:"services"`
+}
+
+//QuotaClassServiceReport appears in type QuotaClassReport.
+type QuotaClassServiceReport struct {
+	Type      string                     `json:"type"`
+	Resources []QuotaClassResourceReport `json:"resources"`
+}
+
+//QuotaClassResourceReport appears in type QuotaClassServiceReport.
+type QuotaClassResourceReport struct {
+	Name  string     `json:"name"`
+	Unit  limes.Unit `json:"unit,omitempty"`
+	Quota uint64     `json:"quota"`
+}
+
+//QuotaClassApplyResult describes the result of applying a quota class to a
+//single project.
+type QuotaClassApplyResult struct {
+	ProjectUUID   string   `json:"id"`
+	ProjectName   string   `json:"name,omitempty"`
+	Status        string   `json:"status"`
+	Errors        []string `json:"errors,omitempty"`
+	BackendErrors []string `json:"backend_errors,omitempty"`
+}
+
+//ListQuotaClasses handles GET /v1/domains/:domain_id/quota-classes.
+func (p *v1Provider) ListQuotaClasses(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:list") {
+		return
+	}
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+
+	//render with sorted keys to ensure testcase stability
+	classNames := make([]string, 0, len(cluster.QuotaClasses))
+	for className := range cluster.QuotaClasses {
+		classNames = append(classNames, className)
+	}
+	sort.Strings(classNames)
+
+	result := make([]QuotaClassReport, 0, len(classNames))
+	for _, className := range classNames {
+		class := cluster.QuotaClasses[className]
+		report := QuotaClassReport{Name: className, Services: []QuotaClassServiceReport{}}
+
+		serviceTypes := make([]string, 0, len(class))
+		for serviceType := range class {
+			serviceTypes = append(serviceTypes, serviceType)
+		}
+		sort.Strings(serviceTypes)
+
+		for _, serviceType := range serviceTypes {
+			resourceNames := make([]string, 0, len(class[serviceType]))
+			for resourceName := range class[serviceType] {
+				resourceNames = append(resourceNames, resourceName)
+			}
+			sort.Strings(resourceNames)
+
+			srvReport := QuotaClassServiceReport{Type: serviceType}
+			for _, resourceName := range resourceNames {
+				srvReport.Resources = append(srvReport.Resources, QuotaClassResourceReport{
+					Name:  resourceName,
+					Unit:  cluster.InfoForResource(serviceType, resourceName).Unit,
+					Quota: class[serviceType][resourceName],
+				})
+			}
+			report.Services = append(report.Services, srvReport)
+		}
+		result = append(result, report)
+	}
+
+	ReturnJSON(w, 200, map[string]interface{}{"quota_classes": result})
+}
+
+//ApplyQuotaClass handles POST /v1/domains/:domain_id/quota-classes/:class_name/apply.
+func (p *v1Provider) ApplyQuotaClass(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	perms := token.permissionsFor("project")
+	if !perms.CanRaise && !perms.CanLower {
+		token.Require(w, "project:raise") //produce standard Unauthorized response
+		return
+	}
+
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return
+	}
+	className := mux.Vars(r)["class_name"]
+	class, exists := cluster.QuotaClasses[className]
+	if !exists {
+		http.Error(w, "no such quota class", 404)
+		return
+	}
+
+	//parse request body
+	var parseTarget struct {
+		ProjectUUIDs []string `json:"projects"`
+		Comment      string   `json:"comment"`
+	}
+	if !RequireJSON(w, r, &parseTarget) {
+		return
+	}
+	if len(parseTarget.ProjectUUIDs) == 0 {
+		http.Error(w, "no projects given", 422)
+		return
+	}
+
+	//all projects are updated separately, so one project's validation errors do
+	//not prevent the class from being applied to the other projects
+	serviceQuotas := make(ServiceQuotas, len(class))
+	for serviceType, resources := range class {
+		resourceQuotas := make(ResourceQuotas, len(resources))
+		for resourceName, value := range resources {
+			resourceQuotas[resourceName] = QuotaInput{
+				ValueWithUnit: limes.ValueWithUnit{Value: value, Unit: limes.UnitUnspecified},
+				Comment:       parseTarget.Comment,
+			}
+		}
+		serviceQuotas[serviceType] = resourceQuotas
+	}
+	actor := actorFor(token)
+	actor.Via = "through quota class " + className
+
+	results := make([]QuotaClassApplyResult, len(parseTarget.ProjectUUIDs))
+	for idx, projectUUID := range parseTarget.ProjectUUIDs {
+		result := QuotaClassApplyResult{ProjectUUID: projectUUID}
+
+		var dbProject db.Project
+		err := db.DB.SelectOne(&dbProject,
+			`SELECT * FROM projects WHERE uuid = $1 AND domain_id = $2`, projectUUID, dbDomain.ID)
+		if err == sql.ErrNoRows {
+			result.Status = "failed"
+			result.Errors = []string{"no such project"}
+			results[idx] = result
+			continue
+		}
+		if ReturnError(w, err) {
+			return
+		}
+		result.ProjectName = dbProject.Name
+
+		errors, backendErrors, err := updateProjectQuotas(cluster, dbDomain, &dbProject, serviceQuotas, perms, actor)
+		if ReturnError(w, err) {
+			return
+		}
+		if len(errors) > 0 {
+			result.Status = "failed"
+			result.Errors = errors
+			results[idx] = result
+			continue
+		}
+
+		_, err = db.DB.Exec(`UPDATE projects SET quota_class = $1 WHERE id = $2`, className, dbProject.ID)
+		if ReturnError(w, err) {
+			return
+		}
+		result.Status = "applied"
+		result.BackendErrors = backendErrors
+		results[idx] = result
+	}
+
+	ReturnJSON(w, 200, map[string]interface{}{"projects": results})
+}
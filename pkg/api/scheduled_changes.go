@@ -0,0 +1,554 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+//timeNow is replaced by unit tests to get reproducible timestamps.
+var timeNow = time.Now
+
+//ScheduledChange is the API representation of a db.ScheduledQuotaChange.
+type ScheduledChange struct {
+	ID            int64           `json:"id"`
+	ServiceType   string          `json:"service"`
+	ResourceName  string          `json:"resource"`
+	Unit          limes.Unit      `json:"unit,omitempty"`
+	Value         *uint64         `json:"value,omitempty"`
+	Delta         *int64          `json:"delta,omitempty"`
+	Comment       string          `json:"comment,omitempty"`
+	DueAt         int64           `json:"due_at"`
+	CreatedAt     int64           `json:"created_at"`
+	CreatedBy     ScheduledByUser `json:"created_by"`
+	Status        string          `json:"status"`
+	ProcessedAt   *int64          `json:"processed_at,omitempty"`
+	FailureReason string          `json:"failure_reason,omitempty"`
+}
+
+//ScheduledByUser appears in types ScheduledChange and Commitment.
+type ScheduledByUser struct {
+	UUID string `json:"id"`
+	Name string `json:"name"`
+}
+
+//scheduledChangeTarget identifies the cluster, domain or project that
+//scheduled changes refer to.
+type scheduledChangeTarget struct {
+	Cluster   *limes.Cluster
+	ClusterID string
+	DomainID  *int64
+	ProjectID *int64
+}
+
+func (t scheduledChangeTarget) whereClause() (string, []interface{}) {
+	switch {
+	case t.ProjectID != nil:
+		return `project_id = $1`, []interface{}{*t.ProjectID}
+	case t.DomainID != nil:
+		return `domain_id = $1 AND project_id IS NULL`, []interface{}{*t.DomainID}
+	default:
+		return `cluster_id = $1 AND domain_id IS NULL`, []interface{}{t.ClusterID}
+	}
+}
+
+func renderScheduledChange(cluster *limes.Cluster, change db.ScheduledQuotaChange) ScheduledChange {
+	result := ScheduledChange{
+		ID:           change.ID,
+		ServiceType:  change.ServiceType,
+		ResourceName: change.ResourceName,
+		Unit:         cluster.InfoForResource(change.ServiceType, change.ResourceName).Unit,
+		Value:        change.NewValue,
+		Delta:        change.Delta,
+		Comment:      change.Comment,
+		DueAt:        change.DueAt.Unix(),
+		CreatedAt:    change.CreatedAt.Unix(),
+		CreatedBy: ScheduledByUser{
+			UUID: change.CreatorUUID,
+			Name: change.CreatorName,
+		},
+		Status:        "pending",
+		FailureReason: change.FailureReason,
+	}
+	if change.ProcessedAt != nil {
+		processedAt := change.ProcessedAt.Unix()
+		result.ProcessedAt = &processedAt
+		if change.FailureReason == "" {
+			result.Status = "applied"
+		} else {
+			result.Status = "failed"
+		}
+	}
+	return result
+}
+
+//ListClusterScheduledChanges handles GET /v1/clusters/:cluster_id/scheduled-changes.
+func (p *v1Provider) ListClusterScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	if !p.CheckToken(r).Require(w, "cluster:show") {
+		return
+	}
+	target, ok := p.findClusterScheduledChangeTarget(w, r)
+	if ok {
+		p.listScheduledChanges(w, target)
+	}
+}
+
+//CreateClusterScheduledChange handles POST /v1/clusters/:cluster_id/scheduled-changes.
+func (p *v1Provider) CreateClusterScheduledChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "cluster:edit") {
+		return
+	}
+	target, ok := p.findClusterScheduledChangeTarget(w, r)
+	if ok {
+		p.createScheduledChange(w, r, target, token, quotaPermissions{CanRaise: true, CanLower: true, CanRaiseWithoutComment: true})
+	}
+}
+
+//CancelClusterScheduledChange handles DELETE /v1/clusters/:cluster_id/scheduled-changes/:change_id.
+func (p *v1Provider) CancelClusterScheduledChange(w http.ResponseWriter, r *http.Request) {
+	if !p.CheckToken(r).Require(w, "cluster:edit") {
+		return
+	}
+	target, ok := p.findClusterScheduledChangeTarget(w, r)
+	if ok {
+		p.cancelScheduledChange(w, r, target)
+	}
+}
+
+func (p *v1Provider) findClusterScheduledChangeTarget(w http.ResponseWriter, r *http.Request) (scheduledChangeTarget, bool) {
+	clusterID := mux.Vars(r)["cluster_id"]
+	if clusterID == "current" {
+		clusterID = p.Cluster.ID
+	}
+	cluster, ok := p.Config.Clusters[clusterID]
+	if !ok {
+		http.Error(w, "no such cluster", 404)
+		return scheduledChangeTarget{}, false
+	}
+	return scheduledChangeTarget{Cluster: cluster, ClusterID: clusterID}, true
+}
+
+//ListDomainScheduledChanges handles GET /v1/domains/:domain_id/scheduled-changes.
+func (p *v1Provider) ListDomainScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "domain:show") {
+		return
+	}
+	target, ok := p.findDomainScheduledChangeTarget(w, r, token)
+	if ok {
+		p.listScheduledChanges(w, target)
+	}
+}
+
+//CreateDomainScheduledChange handles POST /v1/domains/:domain_id/scheduled-changes.
+func (p *v1Provider) CreateDomainScheduledChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	perms := token.permissionsFor("domain")
+	if !perms.CanRaise && !perms.CanLower {
+		token.Require(w, "domain:raise") //produce standard Unauthorized response
+		return
+	}
+	target, ok := p.findDomainScheduledChangeTarget(w, r, token)
+	if ok {
+		p.createScheduledChange(w, r, target, token, perms)
+	}
+}
+
+//CancelDomainScheduledChange handles DELETE /v1/domains/:domain_id/scheduled-changes/:change_id.
+func (p *v1Provider) CancelDomainScheduledChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Check("domain:raise") && !token.Check("domain:lower") {
+		token.Require(w, "domain:raise") //produce standard Unauthorized response
+		return
+	}
+	target, ok := p.findDomainScheduledChangeTarget(w, r, token)
+	if ok {
+		p.cancelScheduledChange(w, r, target)
+	}
+}
+
+func (p *v1Provider) findDomainScheduledChangeTarget(w http.ResponseWriter, r *http.Request, token *Token) (scheduledChangeTarget, bool) {
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return scheduledChangeTarget{}, false
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return scheduledChangeTarget{}, false
+	}
+	return scheduledChangeTarget{Cluster: cluster, ClusterID: cluster.ID, DomainID: &dbDomain.ID}, true
+}
+
+//ListProjectScheduledChanges handles GET /v1/domains/:domain_id/projects/:project_id/scheduled-changes.
+func (p *v1Provider) ListProjectScheduledChanges(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Require(w, "project:show") {
+		return
+	}
+	target, ok := p.findProjectScheduledChangeTarget(w, r, token)
+	if ok {
+		p.listScheduledChanges(w, target)
+	}
+}
+
+//CreateProjectScheduledChange handles POST /v1/domains/:domain_id/projects/:project_id/scheduled-changes.
+func (p *v1Provider) CreateProjectScheduledChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	perms := token.permissionsFor("project")
+	if !perms.CanRaise && !perms.CanLower {
+		token.Require(w, "project:raise") //produce standard Unauthorized response
+		return
+	}
+	target, ok := p.findProjectScheduledChangeTarget(w, r, token)
+	if ok {
+		p.createScheduledChange(w, r, target, token, perms)
+	}
+}
+
+//CancelProjectScheduledChange handles DELETE /v1/domains/:domain_id/projects/:project_id/scheduled-changes/:change_id.
+func (p *v1Provider) CancelProjectScheduledChange(w http.ResponseWriter, r *http.Request) {
+	token := p.CheckToken(r)
+	if !token.Check("project:raise") && !token.Check("project:lower") {
+		token.Require(w, "project:raise") //produce standard Unauthorized response
+		return
+	}
+	target, ok := p.findProjectScheduledChangeTarget(w, r, token)
+	if ok {
+		p.cancelScheduledChange(w, r, target)
+	}
+}
+
+func (p *v1Provider) findProjectScheduledChangeTarget(w http.ResponseWriter, r *http.Request, token *Token) (scheduledChangeTarget, bool) {
+	cluster := p.FindClusterFromRequest(w, r, token)
+	if cluster == nil {
+		return scheduledChangeTarget{}, false
+	}
+	dbDomain := p.FindDomainFromRequest(w, r, cluster)
+	if dbDomain == nil {
+		return scheduledChangeTarget{}, false
+	}
+	dbProject := p.FindProjectFromRequest(w, r, dbDomain)
+	if dbProject == nil {
+		return scheduledChangeTarget{}, false
+	}
+	return scheduledChangeTarget{Cluster: cluster, ClusterID: cluster.ID, DomainID: &dbDomain.ID, ProjectID: &dbProject.ID}, true
+}
+
+func (p *v1Provider) listScheduledChanges(w http.ResponseWriter, target scheduledChangeTarget) {
+	where, args := target.whereClause()
+	var changes []db.ScheduledQuotaChange
+	_, err := db.DB.Select(&changes,
+		`SELECT * FROM scheduled_quota_changes WHERE `+where+` ORDER BY due_at, id`, args...)
+	if ReturnError(w, err) {
+		return
+	}
+
+	result := make([]ScheduledChange, len(changes))
+	for idx, change := range changes {
+		result[idx] = renderScheduledChange(target.Cluster, change)
+	}
+	ReturnJSON(w, 200, map[string]interface{}{"scheduled_changes": result})
+}
+
+func (p *v1Provider) createScheduledChange(w http.ResponseWriter, r *http.Request, target scheduledChangeTarget, token *Token, perms quotaPermissions) {
+	//parse request body
+	var parseTarget struct {
+		Change struct {
+			ServiceType  string      `json:"service"`
+			ResourceName string      `json:"resource"`
+			Value        *uint64     `json:"value"`
+			Delta        *int64      `json:"delta"`
+			Unit         *limes.Unit `json:"unit"`
+			Comment      string      `json:"comment"`
+			DueAt        int64       `json:"due_at"`
+		} `json:"scheduled_change"`
+	}
+	if !RequireJSON(w, r, &parseTarget) {
+		return
+	}
+	input := parseTarget.Change
+
+	//validate request
+	var errors []string
+	if !target.Cluster.HasResource(input.ServiceType, input.ResourceName) {
+		errors = append(errors, "no such resource")
+	}
+	if (input.Value == nil) == (input.Delta == nil) {
+		errors = append(errors, `exactly one of "value" and "delta" must be given`)
+	}
+	dueAt := time.Unix(input.DueAt, 0).UTC()
+	if !dueAt.After(timeNow()) {
+		errors = append(errors, "due date must be in the future")
+	}
+	//changes that may raise a quota need a comment if a comment threshold is
+	//configured for the resource (since the size of the raise is only known
+	//when the change is due), unless the user is allowed to raise quotas
+	//without one (capacity changes always need a comment)
+	mayRaise := input.Value != nil || (input.Delta != nil && *input.Delta > 0)
+	needsComment := mayRaise && !perms.CanRaiseWithoutComment && target.Cluster.CommentPolicy.HasThreshold(input.ServiceType, input.ResourceName)
+	if input.Comment == "" && (target.DomainID == nil || needsComment) {
+		errors = append(errors, "comment is missing")
+	}
+
+	//convert to target unit if required
+	change := db.ScheduledQuotaChange{
+		ClusterID:    target.ClusterID,
+		DomainID:     target.DomainID,
+		ProjectID:    target.ProjectID,
+		ServiceType:  input.ServiceType,
+		ResourceName: input.ResourceName,
+		Comment:      input.Comment,
+		DueAt:        dueAt,
+		CreatedAt:    timeNow().UTC(),
+		CreatorUUID:  token.UserUUID,
+		CreatorName:  token.UserName,
+		CanRaise:     perms.CanRaise,
+		CanLower:     perms.CanLower,
+	}
+	if len(errors) == 0 {
+		inputUnit := limes.UnitUnspecified
+		if input.Unit != nil {
+			inputUnit = *input.Unit
+		}
+		if input.Value != nil {
+			value, err := limes.ValueWithUnit{Value: *input.Value, Unit: inputUnit}.ConvertFor(target.Cluster, input.ServiceType, input.ResourceName)
+			if err == nil {
+				change.NewValue = &value
+			} else {
+				errors = append(errors, err.Error())
+			}
+		} else {
+			magnitude := *input.Delta
+			if magnitude < 0 {
+				magnitude = -magnitude
+			}
+			//int64->uint64 is safe here because `magnitude >= 0` has already been established
+			value, err := limes.ValueWithUnit{Value: uint64(magnitude), Unit: inputUnit}.ConvertFor(target.Cluster, input.ServiceType, input.ResourceName)
+			if err == nil {
+				delta := int64(value)
+				if *input.Delta < 0 {
+					delta = -delta
+				}
+				change.Delta = &delta
+			} else {
+				errors = append(errors, err.Error())
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		for idx, msg := range errors {
+			errors[idx] = fmt.Sprintf("cannot schedule %s/%s change: %s", input.ServiceType, input.ResourceName, msg)
+		}
+		http.Error(w, strings.Join(errors, "\n"), 422)
+		return
+	}
+
+	err := db.DB.Insert(&change)
+	if ReturnError(w, err) {
+		return
+	}
+	ReturnJSON(w, 201, map[string]interface{}{"scheduled_change": renderScheduledChange(target.Cluster, change)})
+}
+
+func (p *v1Provider) cancelScheduledChange(w http.ResponseWriter, r *http.Request, target scheduledChangeTarget) {
+	changeID, err := strconv.ParseInt(mux.Vars(r)["change_id"], 10, 64)
+	if err != nil {
+		http.Error(w, "no such scheduled change", 404)
+		return
+	}
+
+	where, args := target.whereClause()
+	var change db.ScheduledQuotaChange
+	err = db.DB.SelectOne(&change,
+		`SELECT * FROM scheduled_quota_changes WHERE `+where+` AND id = $2`,
+		append(args, changeID)...)
+	switch {
+	case err == sql.ErrNoRows:
+		http.Error(w, "no such scheduled change", 404)
+		return
+	case ReturnError(w, err):
+		return
+	}
+
+	if change.ProcessedAt != nil {
+		http.Error(w, "scheduled change has already been processed", 409)
+		return
+	}
+	_, err = db.DB.Delete(&change)
+	if ReturnError(w, err) {
+		return
+	}
+	w.WriteHeader(204)
+}
+
+//ApplyScheduledChange applies a single scheduled change that is due, using
+//the same validation as the respective PUT requests. This is called by the
+//ApplyScheduledChanges job of `limes collect` (see type collector.Collector).
+//
+//A non-empty failure reason is returned if the change was rejected by
+//validation. Internal errors are returned as `err`. Scheduled changes to
+//locked domains or projects always fail.
+func ApplyScheduledChange(cluster *limes.Cluster, change db.ScheduledQuotaChange) (failureReason string, err error) {
+	actor := quotaChangeActor{
+		UserUUID: change.CreatorUUID,
+		UserName: change.CreatorName,
+		Via:      fmt.Sprintf("through scheduled change %d", change.ID),
+	}
+
+	var (
+		query string
+		args  []interface{}
+	)
+	switch {
+	case change.ProjectID != nil:
+		query = `
+			SELECT pr.quota FROM project_resources pr JOIN project_services ps ON ps.id = pr.service_id
+			 WHERE ps.project_id = $1 AND ps.type = $2 AND pr.name = $3`
+		args = []interface{}{*change.ProjectID, change.ServiceType, change.ResourceName}
+	case change.DomainID != nil:
+		query = `
+			SELECT dr.quota FROM domain_resources dr JOIN domain_services ds ON ds.id = dr.service_id
+			 WHERE ds.domain_id = $1 AND ds.type = $2 AND dr.name = $3`
+		args = []interface{}{*change.DomainID, change.ServiceType, change.ResourceName}
+	default:
+		clusterID := change.ClusterID
+		if cluster.IsServiceShared[change.ServiceType] {
+			clusterID = "shared"
+		}
+		query = `
+			SELECT cr.capacity FROM cluster_resources cr JOIN cluster_services cs ON cs.id = cr.service_id
+			 WHERE cs.cluster_id = $1 AND cs.type = $2 AND cr.name = $3`
+		args = []interface{}{clusterID, change.ServiceType, change.ResourceName}
+	}
+	var currentValue *uint64
+	err = db.DB.QueryRow(query, args...).Scan(&currentValue)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	//compute the absolute target value
+	var newValue uint64
+	if change.NewValue != nil {
+		newValue = *change.NewValue
+	} else {
+		current := int64(0)
+		if currentValue != nil {
+			current = int64(*currentValue)
+		}
+		if current+*change.Delta < 0 {
+			unit := cluster.InfoForResource(change.ServiceType, change.ResourceName).Unit
+			return fmt.Sprintf("cannot change %s/%s by -%s: current value is only %s",
+				change.ServiceType, change.ResourceName, unit.Format(uint64(-*change.Delta)), unit.Format(uint64(current))), nil
+		}
+		newValue = uint64(current + *change.Delta)
+	}
+
+	//scheduled changes cannot be used to bypass the approval of large raises
+	if change.ProjectID == nil {
+		current := uint64(0)
+		if currentValue != nil {
+			current = *currentValue
+		}
+		if cluster.ApprovalPolicy.NeedsApproval(change.ServiceType, change.ResourceName, current, newValue) {
+			return fmt.Sprintf("cannot change %s/%s: raise exceeds the approval threshold and must be confirmed by a second user",
+				change.ServiceType, change.ResourceName), nil
+		}
+	}
+
+	var errors []string
+	switch {
+	case change.ProjectID != nil:
+		if currentValue == nil {
+			return fmt.Sprintf("cannot change %s/%s quota: no such resource in this project", change.ServiceType, change.ResourceName), nil
+		}
+		var dbDomain db.Domain
+		err = db.DB.SelectOne(&dbDomain, `SELECT * FROM domains WHERE id = $1`, *change.DomainID)
+		if err != nil {
+			return "", err
+		}
+		var dbProject db.Project
+		err = db.DB.SelectOne(&dbProject, `SELECT * FROM projects WHERE id = $1`, *change.ProjectID)
+		if err != nil {
+			return "", err
+		}
+		var backendErrors []string
+		errors, backendErrors, err = updateProjectQuotas(cluster, &dbDomain, &dbProject,
+			scheduledQuotaInput(change, newValue), scheduledQuotaPermissions(change), actor)
+		for _, msg := range backendErrors {
+			util.LogError("scheduled change %d: %s", change.ID, msg)
+		}
+	case change.DomainID != nil:
+		var dbDomain db.Domain
+		err = db.DB.SelectOne(&dbDomain, `SELECT * FROM domains WHERE id = $1`, *change.DomainID)
+		if err != nil {
+			return "", err
+		}
+		errors, err = updateDomainQuotas(cluster, &dbDomain,
+			scheduledQuotaInput(change, newValue), scheduledQuotaPermissions(change), actor)
+	default:
+		errors, err = updateClusterCapacities(cluster, change.ClusterID, []ServiceCapacities{{
+			Type: change.ServiceType,
+			Resources: []ResourceCapacity{{
+				Name:     change.ResourceName,
+				Capacity: int64(newValue),
+				Comment:  change.Comment,
+			}},
+		}})
+		if err == nil && len(errors) == 0 {
+			util.LogInfo("set capacity %s.%s = %d for cluster %s by %s",
+				change.ServiceType, change.ResourceName, newValue, change.ClusterID, actor)
+		}
+	}
+	return strings.Join(errors, "\n"), err
+}
+
+func scheduledQuotaInput(change db.ScheduledQuotaChange, newValue uint64) ServiceQuotas {
+	return ServiceQuotas{
+		change.ServiceType: ResourceQuotas{
+			change.ResourceName: QuotaInput{
+				ValueWithUnit: limes.ValueWithUnit{Value: newValue, Unit: limes.UnitUnspecified},
+				Comment:       change.Comment,
+			},
+		},
+	}
+}
+
+//scheduledQuotaPermissions returns the permissions that a scheduled change is
+//applied with. The requirement for a comment was already checked when the
+//change was created, and locks are never bypassed.
+func scheduledQuotaPermissions(change db.ScheduledQuotaChange) quotaPermissions {
+	return quotaPermissions{
+		CanRaise:               change.CanRaise,
+		CanLower:               change.CanLower,
+		CanRaiseWithoutComment: true,
+	}
+}
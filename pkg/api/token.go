@@ -21,11 +21,13 @@ package api
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 
 	policy "github.com/databus23/goslo.policy"
 	"github.com/gophercloud/gophercloud"
 	"github.com/gorilla/mux"
+	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/util"
 )
 
@@ -110,3 +112,61 @@ func extractErrorMessage(err error) string {
 		return err.Error()
 	}
 }
+
+//quotaPermissions describes which kinds of quota changes a user may make in a
+//domain or project.
+type quotaPermissions struct {
+	CanRaise               bool
+	CanLower               bool
+	CanBypassLock          bool
+	CanRaiseWithoutComment bool
+}
+
+//permissionsFor returns the token's permissions for changing the quotas of
+//the given kind of object ("domain" or "project").
+func (t *Token) permissionsFor(object string) quotaPermissions {
+	return quotaPermissions{
+		CanRaise:               t.Check(object + ":raise"),
+		CanLower:               t.Check(object + ":lower"),
+		CanBypassLock:          t.Check("quota:lock"),
+		CanRaiseWithoutComment: t.Check(object + ":raise_without_comment"),
+	}
+}
+
+//needsComment returns whether raising the given quota from oldQuota to
+//newQuota needs to be justified with a comment. This is only the case for
+//raises that exceed the cluster's comment thresholds, unless the user is
+//allowed to raise quotas without a comment.
+func (p quotaPermissions) needsComment(cluster *limes.Cluster, serviceType, resourceName string, oldQuota, newQuota uint64) bool {
+	return !p.CanRaiseWithoutComment && cluster.CommentPolicy.NeedsComment(serviceType, resourceName, oldQuota, newQuota)
+}
+
+//describeUser returns a description of the token's user for use in the audit
+//trail.
+func describeUser(t *Token) string {
+	return fmt.Sprintf("user %s (%s)", t.UserUUID, t.UserName)
+}
+
+//quotaChangeActor describes who requested a quota change. It is used in the
+//audit trail and in the quota change history.
+type quotaChangeActor struct {
+	UserUUID string
+	UserName string
+	//Via describes how the change was requested if the user did not request it
+	//directly, e.g. "through quota class small".
+	Via string
+}
+
+//actorFor returns a quotaChangeActor for changes requested directly by the
+//token's user.
+func actorFor(t *Token) quotaChangeActor {
+	return quotaChangeActor{UserUUID: t.UserUUID, UserName: t.UserName}
+}
+
+//String implements the fmt.Stringer interface.
+func (a quotaChangeActor) String() string {
+	if a.Via == "" {
+		return fmt.Sprintf("user %s (%s)", a.UserUUID, a.UserName)
+	}
+	return fmt.Sprintf("user %s (%s) %s", a.UserUUID, a.UserName, a.Via)
+}
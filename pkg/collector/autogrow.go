@@ -0,0 +1,73 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+//applyAutogrow computes the new quota of a project resource that has an
+//autogrow policy, and the new value for its `low_usage_since` column.
+//
+//The quota is raised to the policy's target value as soon as the usage
+//requires it, but never beyond the given maxQuota (i.e. the part of the domain
+//quota that is not given out to other projects). The quota is only lowered
+//once it has stayed above the target value for the duration given in the
+//policy. In both directions, the target value is adjusted to satisfy the
+//given constraint.
+func applyAutogrow(policy limes.AutogrowPolicy, constraint limes.QuotaConstraint, quota, usage, maxQuota uint64, lowUsageSince *time.Time, now time.Time) (uint64, *time.Time) {
+	target := constraint.ApplyTo(policy.TargetQuota(usage))
+
+	switch {
+	case target > quota:
+		if target > maxQuota {
+			target = maxQuota
+		}
+		if target < quota {
+			return quota, nil
+		}
+		return target, nil
+	case target < quota:
+		if policy.ShrinkAfter == 0 {
+			return quota, nil
+		}
+		if lowUsageSince == nil {
+			return quota, &now
+		}
+		if now.Sub(*lowUsageSince) < policy.ShrinkAfter {
+			return quota, lowUsageSince
+		}
+		return target, nil
+	default:
+		return quota, nil
+	}
+}
+
+//autogrowMaxQuota returns the largest quota that autogrow may give to a
+//project, given the domain quota and the sum of the quotas of all other
+//projects in the domain.
+func autogrowMaxQuota(domainQuota, otherProjectsQuota uint64) uint64 {
+	if domainQuota < otherProjectsQuota {
+		return 0
+	}
+	return domainQuota - otherProjectsQuota
+}
@@ -22,6 +22,7 @@ package collector
 import (
 	"time"
 
+	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/util"
 )
@@ -41,6 +42,11 @@ type Collector struct {
 	//When set to true, suppresses the usual non-returning behavior of
 	//collector jobs.
 	Once bool
+	//Applies a single scheduled change in ApplyScheduledChanges(). This is
+	//api.ApplyScheduledChange, which cannot be referenced here directly since
+	//package api imports this package. Must be set before calling
+	//ApplyScheduledChanges().
+	ApplyScheduledChange func(cluster *limes.Cluster, change db.ScheduledQuotaChange) (failureReason string, err error)
 }
 
 //NewCollector creates a Collector instance.
@@ -0,0 +1,337 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sapcc/limes/pkg/datamodel"
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+var quotaExpiryInterval = 5 * time.Minute
+
+//query that finds domain resources with expired temporary quota raises (in
+//locked domains, the quota is only reverted once the lock is removed)
+var expiredDomainQuotasQuery = `
+	SELECT d.id, d.name, d.uuid, ds.type, dr.service_id, dr.name
+	  FROM domain_resources dr
+	  JOIN domain_services ds ON ds.id = dr.service_id
+	  JOIN domains d ON d.id = ds.domain_id
+	 WHERE d.cluster_id = $1 AND dr.quota_expires_at <= $2 AND NOT d.quota_locked
+`
+
+//query that finds project resources with expired temporary quota raises (in
+//locked projects, the quota is only reverted once the lock is removed)
+var expiredProjectQuotasQuery = `
+	SELECT d.id, d.name, p.id, p.name, p.uuid, d.uuid, ps.type, pr.service_id, pr.name
+	  FROM project_resources pr
+	  JOIN project_services ps ON ps.id = pr.service_id
+	  JOIN projects p ON p.id = ps.project_id
+	  JOIN domains d ON d.id = p.domain_id
+	 WHERE d.cluster_id = $1 AND pr.quota_expires_at <= $2 AND NOT p.quota_locked
+`
+
+type expiredDomainQuota struct {
+	DomainID    int64
+	DomainName  string
+	DomainUUID  string
+	ServiceType string
+	ServiceID   int64
+	Name        string
+}
+
+type expiredProjectQuota struct {
+	DomainID    int64
+	DomainName  string
+	ProjectID   int64
+	ProjectName string
+	ProjectUUID string
+	DomainUUID  string
+	ServiceType string
+	ServiceID   int64
+	Name        string
+}
+
+//ExpireQuotas checks the database periodically for temporary quota raises in
+//domains and projects of this cluster whose expiry date has passed, and
+//reverts the quota to the value from before the raise (or, if that value is
+//not acceptable anymore, to the closest value allowed by usage and
+//constraints).
+//
+//Errors are logged instead of returned.
+func (c *Collector) ExpireQuotas() {
+	for {
+		//projects first, since reverting project quotas may allow for domain
+		//quotas to be reverted further
+		now := c.TimeNow()
+		c.expireProjectQuotas(now)
+		c.expireDomainQuotas(now)
+
+		if c.Once {
+			return
+		}
+		time.Sleep(quotaExpiryInterval)
+	}
+}
+
+func (c *Collector) expireDomainQuotas(now time.Time) {
+	var expired []expiredDomainQuota
+	err := db.ForeachRow(db.DB, expiredDomainQuotasQuery, []interface{}{c.Cluster.ID, now}, func(rows *sql.Rows) error {
+		var e expiredDomainQuota
+		err := rows.Scan(&e.DomainID, &e.DomainName, &e.DomainUUID, &e.ServiceType, &e.ServiceID, &e.Name)
+		expired = append(expired, e)
+		return err
+	})
+	if err != nil {
+		c.LogError("cannot find expired domain quotas: %s", err.Error())
+		return
+	}
+
+	for _, e := range expired {
+		err := c.expireDomainQuota(e, now)
+		if err != nil {
+			c.LogError("cannot revert temporary %s/%s quota for domain %s: %s",
+				e.ServiceType, e.Name, e.DomainName, err.Error())
+		}
+	}
+}
+
+func (c *Collector) expireDomainQuota(e expiredDomainQuota, now time.Time) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	//re-read the resource within the transaction to avoid racing with concurrent quota updates
+	var res db.DomainResource
+	err = tx.SelectOne(&res, `SELECT * FROM domain_resources WHERE service_id = $1 AND name = $2`, e.ServiceID, e.Name)
+	if err != nil {
+		return err
+	}
+	if res.QuotaExpiresAt == nil || res.QuotaExpiresAt.After(now) {
+		return nil
+	}
+
+	//the domain quota may not be lower than the sum of project quotas
+	projectsQuotas, err := datamodel.GetProjectsQuotas(tx, e.DomainID, e.ServiceType)
+	if err != nil {
+		return err
+	}
+	newQuota := res.Quota
+	if res.PreviousQuota != nil {
+		newQuota = *res.PreviousQuota
+	}
+	if newQuota < projectsQuotas[res.Name] {
+		newQuota = projectsQuotas[res.Name]
+	}
+
+	var constraint limes.QuotaConstraint
+	if c.Cluster.QuotaConstraints != nil {
+		constraint = c.Cluster.QuotaConstraints.Domains[e.DomainName][e.ServiceType][res.Name]
+	}
+	if constraint.IsRelative() {
+		usages, err := datamodel.GetDomainUsages(tx, e.DomainID, e.ServiceType)
+		if err != nil {
+			return err
+		}
+		constraint = constraint.Evaluate(usages[res.Name], 0)
+	}
+	newQuota = constraint.ApplyTo(newQuota)
+
+	var auditTrail util.AuditTrail
+	auditTrail.Add("set quota %s.%s = %d -> %d for domain %s after expiry of temporary quota raise",
+		e.ServiceType, res.Name, res.Quota, newQuota, e.DomainUUID,
+	)
+	var history datamodel.QuotaChangeHistory
+	history.Add(e.ServiceType, res.Name, res.Quota, newQuota)
+	res.Quota = newQuota
+	res.QuotaExpiresAt = nil
+	res.PreviousQuota = nil
+	_, err = tx.Update(&res)
+	if err != nil {
+		return err
+	}
+	err = history.Save(tx, db.QuotaChangeEvent{
+		ClusterID: c.Cluster.ID,
+		DomainID:  e.DomainID,
+		ProjectID: nil,
+		Via:       "after expiry of temporary quota raise",
+		CreatedAt: now.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	auditTrail.Commit()
+	return nil
+}
+
+func (c *Collector) expireProjectQuotas(now time.Time) {
+	var expired []expiredProjectQuota
+	err := db.ForeachRow(db.DB, expiredProjectQuotasQuery, []interface{}{c.Cluster.ID, now}, func(rows *sql.Rows) error {
+		var e expiredProjectQuota
+		err := rows.Scan(&e.DomainID, &e.DomainName, &e.ProjectID, &e.ProjectName, &e.ProjectUUID, &e.DomainUUID, &e.ServiceType, &e.ServiceID, &e.Name)
+		expired = append(expired, e)
+		return err
+	})
+	if err != nil {
+		c.LogError("cannot find expired project quotas: %s", err.Error())
+		return
+	}
+
+	servicesToUpdate := make(map[int64]expiredProjectQuota)
+	for _, e := range expired {
+		err := c.expireProjectQuota(e, now)
+		if err != nil {
+			c.LogError("cannot revert temporary %s/%s quota for project %s/%s: %s",
+				e.ServiceType, e.Name, e.DomainName, e.ProjectName, err.Error())
+			continue
+		}
+		servicesToUpdate[e.ServiceID] = e
+	}
+
+	//write the reverted quotas into the backend (if this fails, the scraping job
+	//will try again later when it detects the frontend/backend quota mismatch)
+	for serviceID, e := range servicesToUpdate {
+		err := c.writeBackendQuotas(serviceID, e.ServiceType, e.DomainUUID, e.ProjectUUID)
+		if err != nil {
+			c.LogError("could not write %s quotas for project %s/%s into backend: %s",
+				e.ServiceType, e.DomainName, e.ProjectName, err.Error())
+		}
+	}
+}
+
+func (c *Collector) expireProjectQuota(e expiredProjectQuota, now time.Time) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer db.RollbackUnlessCommitted(tx)
+
+	//re-read the resource within the transaction to avoid racing with concurrent quota updates
+	var res db.ProjectResource
+	err = tx.SelectOne(&res, `SELECT * FROM project_resources WHERE service_id = $1 AND name = $2`, e.ServiceID, e.Name)
+	if err != nil {
+		return err
+	}
+	if res.QuotaExpiresAt == nil || res.QuotaExpiresAt.After(now) {
+		return nil
+	}
+
+	//the project quota may not be lower than the usage or the active commitments
+	newQuota := res.Quota
+	if res.PreviousQuota != nil {
+		newQuota = *res.PreviousQuota
+	}
+	if newQuota < res.Usage {
+		newQuota = res.Usage
+	}
+	commitments, err := datamodel.GetProjectCommitments(tx, e.ServiceID, now)
+	if err != nil {
+		return err
+	}
+	if newQuota < commitments[res.Name] {
+		newQuota = commitments[res.Name]
+	}
+
+	var constraint limes.QuotaConstraint
+	if c.Cluster.QuotaConstraints != nil {
+		constraint = c.Cluster.QuotaConstraints.Projects[e.DomainName][e.ProjectName][e.ServiceType][res.Name]
+	}
+	if constraint.IsRelative() {
+		domainQuotas, err := datamodel.GetDomainQuotas(tx, e.DomainID, e.ServiceType)
+		if err != nil {
+			return err
+		}
+		constraint = constraint.Evaluate(res.Usage, domainQuotas[res.Name])
+	}
+	newQuota = constraint.ApplyTo(newQuota)
+
+	var auditTrail util.AuditTrail
+	auditTrail.Add("set quota %s.%s = %d -> %d for project %s after expiry of temporary quota raise",
+		e.ServiceType, res.Name, res.Quota, newQuota, e.ProjectUUID,
+	)
+	var history datamodel.QuotaChangeHistory
+	history.Add(e.ServiceType, res.Name, res.Quota, newQuota)
+	res.Quota = newQuota
+	res.QuotaExpiresAt = nil
+	res.PreviousQuota = nil
+	_, err = tx.Update(&res)
+	if err != nil {
+		return err
+	}
+	err = history.Save(tx, db.QuotaChangeEvent{
+		ClusterID: c.Cluster.ID,
+		DomainID:  e.DomainID,
+		ProjectID: &e.ProjectID,
+		Via:       "after expiry of temporary quota raise",
+		CreatedAt: now.UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	auditTrail.Commit()
+	return nil
+}
+
+//writeBackendQuotas writes the quotas of the given project service into the
+//backend.
+func (c *Collector) writeBackendQuotas(serviceID int64, serviceType, domainUUID, projectUUID string) error {
+	plugin := c.Cluster.QuotaPlugins[serviceType]
+	if plugin == nil {
+		return nil
+	}
+
+	//the QuotaPlugin.SetQuota method requires as input *all* quotas for that
+	//plugin's service
+	quotaValues := make(map[string]uint64)
+	var resources []db.ProjectResource
+	_, err := db.DB.Select(&resources, `SELECT * FROM project_resources WHERE service_id = $1`, serviceID)
+	if err != nil {
+		return err
+	}
+	for _, res := range resources {
+		quotaValues[res.Name] = res.Quota
+	}
+
+	err = plugin.SetQuota(
+		c.Cluster.ProviderClientForService(serviceType),
+		c.Cluster.ID, domainUUID, projectUUID, quotaValues,
+	)
+	if err != nil {
+		return err
+	}
+
+	//on success, we now know that the backend has all the correct quotas
+	_, err = db.DB.Exec(`UPDATE project_resources SET backend_quota = quota WHERE service_id = $1`, serviceID)
+	return err
+}
@@ -0,0 +1,82 @@
+/*******************************************************************************
+*
+* Copyright 2017 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+func Test_ExpireQuotas(t *testing.T) {
+	plugin := test.NewPlugin("unittest")
+	cluster := prepareScrapeTest(t, plugin)
+	c := Collector{
+		Cluster:  cluster,
+		Plugin:   plugin,
+		LogError: t.Errorf,
+		TimeNow:  test.TimeNow,
+		Once:     true,
+	}
+
+	//create the project_resources entries
+	c.Scrape()
+
+	//setup some temporary quota raises: the project's capacity quota has expired
+	//(and shall revert to the minimum allowed by the constraint rather than to
+	//its previous value), the project's things quota has not expired yet, and the
+	//domain's capacity quota has expired (and shall revert to the sum of project
+	//quotas rather than to its previous value)
+	expired := time.Unix(1, 0)
+	notExpired := time.Unix(3600, 0)
+	mustExec(t, `UPDATE project_resources SET quota = 30, quota_expires_at = $1, previous_quota = 5 WHERE name = 'capacity'`, expired)
+	mustExec(t, `UPDATE project_resources SET quota = 50, quota_expires_at = $1, previous_quota = 0 WHERE name = 'things'`, notExpired)
+	mustExec(t, `INSERT INTO domain_resources (service_id, name, quota, quota_expires_at, previous_quota) VALUES (1, 'capacity', 100, $1, 0)`, expired)
+
+	c.ExpireQuotas()
+	test.AssertDBContent(t, "fixtures/expirequotas1.sql")
+
+	//nothing happens until the next expiry date is reached
+	c.ExpireQuotas()
+	test.AssertDBContent(t, "fixtures/expirequotas1.sql")
+
+	//when it is reached, the things quota is not reverted while the project is
+	//locked...
+	mustExec(t, `UPDATE project_resources SET quota_expires_at = $1 WHERE name = 'things'`, expired)
+	mustExec(t, `UPDATE projects SET quota_locked = TRUE, quota_lock_reason = 'legal hold'`)
+	c.ExpireQuotas()
+	test.AssertDBContent(t, "fixtures/expirequotas-locked.sql")
+
+	//...and once the lock is removed, the things quota cannot revert to its
+	//previous value because the usage is higher than that
+	mustExec(t, `UPDATE projects SET quota_locked = FALSE, quota_lock_reason = ''`)
+	c.ExpireQuotas()
+	test.AssertDBContent(t, "fixtures/expirequotas2.sql")
+}
+
+func mustExec(t *testing.T, query string, args ...interface{}) {
+	t.Helper()
+	_, err := db.DB.Exec(query, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
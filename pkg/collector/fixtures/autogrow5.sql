@@ -0,0 +1,22 @@
+INSERT INTO domains (id, cluster_id, name, uuid, quota_locked, quota_lock_reason) VALUES (1, 'west', 'germany', 'uuid-for-germany', FALSE, '');
+
+INSERT INTO domain_services (id, domain_id, type) VALUES (1, 1, 'unittest');
+
+INSERT INTO domain_resources (service_id, name, quota, quota_expires_at, previous_quota, quota_comment) VALUES (1, 'things', 20, NULL, NULL, '');
+
+INSERT INTO projects (id, domain_id, name, uuid, parent_uuid, quota_class, quota_locked, quota_lock_reason) VALUES (1, 1, 'berlin', 'uuid-for-berlin', 'uuid-for-germany', '', FALSE, '');
+
+INSERT INTO project_services (id, project_id, type, scraped_at, stale) VALUES (1, 1, 'unittest', 19, FALSE);
+
+INSERT INTO project_resources (service_id, name, quota, usage, backend_quota, subresources, quota_expires_at, previous_quota, low_usage_since, quota_comment, reserved) VALUES (1, 'capacity', 10, 0, 100, '', NULL, NULL, NULL, '', 0);
+INSERT INTO project_resources (service_id, name, quota, usage, backend_quota, subresources, quota_expires_at, previous_quota, low_usage_since, quota_comment, reserved) VALUES (1, 'things', 7, 4, 42, '[{"index":0},{"index":1},{"index":2},{"index":3}]', NULL, NULL, NULL, '', 0);
+
+INSERT INTO quota_change_events (id, cluster_id, domain_id, project_id, user_uuid, user_name, via, created_at) VALUES (1, 'west', 1, 1, '', '', 'through autogrow', 1);
+INSERT INTO quota_change_events (id, cluster_id, domain_id, project_id, user_uuid, user_name, via, created_at) VALUES (2, 'west', 1, 1, '', '', 'through autogrow', 3);
+INSERT INTO quota_change_events (id, cluster_id, domain_id, project_id, user_uuid, user_name, via, created_at) VALUES (3, 'west', 1, 1, '', '', 'through autogrow', 5);
+INSERT INTO quota_change_events (id, cluster_id, domain_id, project_id, user_uuid, user_name, via, created_at) VALUES (4, 'west', 1, 1, '', '', 'through autogrow', 19);
+
+INSERT INTO quota_changes (event_id, service_type, resource_name, old_quota, new_quota) VALUES (1, 'unittest', 'things', 0, 5);
+INSERT INTO quota_changes (event_id, service_type, resource_name, old_quota, new_quota) VALUES (2, 'unittest', 'things', 5, 15);
+INSERT INTO quota_changes (event_id, service_type, resource_name, old_quota, new_quota) VALUES (3, 'unittest', 'things', 15, 20);
+INSERT INTO quota_changes (event_id, service_type, resource_name, old_quota, new_quota) VALUES (4, 'unittest', 'things', 20, 7);
@@ -0,0 +1,62 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+var pendingChangePurgeInterval = 1 * time.Hour
+
+//how long expired pending changes are kept around (so that users can see in
+//the pending change listing why their raise was not applied)
+var pendingChangeRetention = 7 * 24 * time.Hour
+
+//PurgeExpiredPendingChanges periodically deletes pending changes of this
+//cluster that have expired more than a week ago, since those cannot be
+//confirmed anymore.
+//
+//Errors are logged instead of returned.
+func (c *Collector) PurgeExpiredPendingChanges() {
+	for {
+		result, err := db.DB.Exec(
+			`DELETE FROM pending_quota_changes WHERE cluster_id = $1 AND expires_at <= $2`,
+			c.Cluster.ID, c.TimeNow().Add(-pendingChangeRetention).UTC(),
+		)
+		if err == nil {
+			var count int64
+			count, err = result.RowsAffected()
+			if count > 0 {
+				util.LogInfo("purged %d expired pending changes", count)
+			}
+		}
+		if err != nil {
+			c.LogError("cannot purge expired pending changes: %s", err.Error())
+		}
+
+		if c.Once {
+			return
+		}
+		time.Sleep(pendingChangePurgeInterval)
+	}
+}
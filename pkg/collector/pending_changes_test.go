@@ -0,0 +1,79 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+func Test_PurgeExpiredPendingChanges(t *testing.T) {
+	test.InitDatabase(t, "../test/migrations")
+	day := 24 * time.Hour
+	now := time.Unix(0, 0).Add(30 * day)
+	c := Collector{
+		Cluster:  &limes.Cluster{ID: "west"},
+		LogError: t.Errorf,
+		TimeNow:  func() time.Time { return now },
+		Once:     true,
+	}
+
+	//changes 1 and 4 have expired long ago, change 2 has expired recently, and
+	//change 3 has not expired yet; change 4 belongs to a different cluster
+	changes := []struct {
+		ClusterID string
+		ExpiresAt time.Time
+	}{
+		{"west", now.Add(-10 * day)},
+		{"west", now.Add(-1 * day)},
+		{"west", now.Add(day)},
+		{"east", now.Add(-10 * day)},
+	}
+	for _, change := range changes {
+		err := db.DB.Insert(&db.PendingQuotaChange{
+			ClusterID:    change.ClusterID,
+			ServiceType:  "unittest",
+			ResourceName: "things",
+			OldValue:     10,
+			NewValue:     100,
+			CreatedAt:    change.ExpiresAt.Add(-3 * day).UTC(),
+			ExpiresAt:    change.ExpiresAt.UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.PurgeExpiredPendingChanges()
+
+	var ids []int64
+	_, err := db.DB.Select(&ids, `SELECT id FROM pending_quota_changes ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{2, 3, 4}) {
+		t.Errorf("expected pending changes [2 3 4] to remain, but got %v", ids)
+	}
+}
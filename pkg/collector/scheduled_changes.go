@@ -0,0 +1,76 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+)
+
+var scheduledChangesInterval = 1 * time.Minute
+
+//ApplyScheduledChanges checks the database periodically for scheduled quota
+//and capacity changes in this cluster that are due, and applies them using
+//c.ApplyScheduledChange.
+//
+//Errors are logged instead of returned. The function will not return unless
+//c.Once is set.
+func (c *Collector) ApplyScheduledChanges() {
+	for {
+		c.applyScheduledChanges(c.TimeNow())
+
+		if c.Once {
+			return
+		}
+		time.Sleep(scheduledChangesInterval)
+	}
+}
+
+func (c *Collector) applyScheduledChanges(now time.Time) {
+	var changes []db.ScheduledQuotaChange
+	_, err := db.DB.Select(&changes,
+		`SELECT * FROM scheduled_quota_changes WHERE cluster_id = $1 AND processed_at IS NULL AND due_at <= $2 ORDER BY due_at, id`,
+		c.Cluster.ID, now)
+	if err != nil {
+		c.LogError("cannot load due scheduled changes: %s", err.Error())
+		return
+	}
+
+	for _, change := range changes {
+		failureReason, err := c.ApplyScheduledChange(c.Cluster, change)
+		if err != nil {
+			//leave the change pending, so that it will be retried on the next run
+			c.LogError("cannot apply scheduled change %d: %s", change.ID, err.Error())
+			continue
+		}
+		if failureReason != "" {
+			c.LogError("scheduled change %d failed: %s", change.ID, failureReason)
+		}
+
+		processedAt := now.UTC()
+		change.ProcessedAt = &processedAt
+		change.FailureReason = failureReason
+		_, err = db.DB.Update(&change)
+		if err != nil {
+			c.LogError("cannot mark scheduled change %d as processed: %s", change.ID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,110 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package collector
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+func Test_ApplyScheduledChanges(t *testing.T) {
+	test.InitDatabase(t, "../test/migrations")
+	now := time.Unix(3600, 0)
+
+	//change 1 will be applied, change 2 will fail validation, change 3 runs
+	//into an internal error, change 4 is not due yet, and change 5 belongs to
+	//a different cluster
+	changes := []struct {
+		ClusterID string
+		DueAt     time.Time
+	}{
+		{"west", now.Add(-2 * time.Minute)},
+		{"west", now.Add(-1 * time.Minute)},
+		{"west", now},
+		{"west", now.Add(time.Minute)},
+		{"east", now.Add(-2 * time.Minute)},
+	}
+	for _, change := range changes {
+		value := uint64(42)
+		err := db.DB.Insert(&db.ScheduledQuotaChange{
+			ClusterID:    change.ClusterID,
+			ServiceType:  "unittest",
+			ResourceName: "things",
+			NewValue:     &value,
+			DueAt:        change.DueAt.UTC(),
+			CreatedAt:    time.Unix(0, 0).UTC(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var (
+		appliedIDs []int64
+		loggedMsgs int
+	)
+	c := Collector{
+		Cluster:  &limes.Cluster{ID: "west"},
+		LogError: func(msg string, args ...interface{}) { loggedMsgs++ },
+		TimeNow:  func() time.Time { return now },
+		Once:     true,
+		ApplyScheduledChange: func(cluster *limes.Cluster, change db.ScheduledQuotaChange) (string, error) {
+			appliedIDs = append(appliedIDs, change.ID)
+			switch change.ID {
+			case 2:
+				return "not allowed", nil
+			case 3:
+				return "", errors.New("database is on fire")
+			default:
+				return "", nil
+			}
+		},
+	}
+	c.ApplyScheduledChanges()
+
+	if !reflect.DeepEqual(appliedIDs, []int64{1, 2, 3}) {
+		t.Errorf("expected scheduled changes [1 2 3] to be applied, but got %v", appliedIDs)
+	}
+	if loggedMsgs != 2 {
+		t.Errorf("expected 2 logged errors, but got %d", loggedMsgs)
+	}
+
+	var processed []db.ScheduledQuotaChange
+	_, err := db.DB.Select(&processed,
+		`SELECT * FROM scheduled_quota_changes WHERE processed_at IS NOT NULL ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processed) != 2 || processed[0].ID != 1 || processed[1].ID != 2 {
+		t.Fatalf("expected scheduled changes 1 and 2 to be processed, but got %#v", processed)
+	}
+	if !processed[0].ProcessedAt.Equal(now) || processed[0].FailureReason != "" {
+		t.Errorf("unexpected state of applied change: %#v", processed[0])
+	}
+	if processed[1].FailureReason != "not allowed" {
+		t.Errorf("expected failure reason %q, but got %q", "not allowed", processed[1].FailureReason)
+	}
+}
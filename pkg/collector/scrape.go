@@ -27,6 +27,7 @@ import (
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sapcc/limes/pkg/datamodel"
 	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
 	"github.com/sapcc/limes/pkg/util"
@@ -43,7 +44,7 @@ var scrapeInterval = 30 * time.Minute
 
 //query that finds the next project that needs to be scraped
 var findProjectQuery = `
-	SELECT ps.id, p.name, p.uuid, d.name, d.uuid
+	SELECT ps.id, p.id, p.name, p.uuid, p.quota_locked, d.id, d.name, d.uuid
 	FROM project_services ps
 	JOIN projects p ON p.id = ps.project_id
 	JOIN domains d ON d.id = p.domain_id
@@ -79,13 +80,16 @@ func (c *Collector) Scrape() {
 	for {
 		var (
 			serviceID   int64
+			projectID   int64
 			projectName string
 			projectUUID string
+			quotaLocked bool
+			domainID    int64
 			domainName  string
 			domainUUID  string
 		)
 		err := db.DB.QueryRow(findProjectQuery, c.Cluster.ID, serviceType, c.TimeNow().Add(-scrapeInterval)).
-			Scan(&serviceID, &projectName, &projectUUID, &domainName, &domainUUID)
+			Scan(&serviceID, &projectID, &projectName, &projectUUID, &quotaLocked, &domainID, &domainName, &domainUUID)
 		if err != nil {
 			//ErrNoRows is okay; it just means that nothing needs scraping right now
 			if err != sql.ErrNoRows {
@@ -126,7 +130,7 @@ func (c *Collector) Scrape() {
 			continue
 		}
 
-		err = c.writeScrapeResult(domainName, domainUUID, projectName, projectUUID, serviceType, serviceID, resourceData, c.TimeNow())
+		err = c.writeScrapeResult(domainID, domainName, domainUUID, projectID, projectName, projectUUID, quotaLocked, serviceType, serviceID, resourceData, c.TimeNow())
 		if err != nil {
 			c.LogError("write %s backend data for %s/%s failed: %s", serviceType, domainName, projectName, err.Error())
 			scrapeFailedCounter.With(labels).Inc()
@@ -147,7 +151,7 @@ func (c *Collector) Scrape() {
 	}
 }
 
-func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, projectUUID, serviceType string, serviceID int64, resourceData map[string]limes.ResourceData, scrapedAt time.Time) error {
+func (c *Collector) writeScrapeResult(domainID int64, domainName, domainUUID string, projectID int64, projectName, projectUUID string, quotaLocked bool, serviceType string, serviceID int64, resourceData map[string]limes.ResourceData, scrapedAt time.Time) error {
 	tx, err := db.DB.Begin()
 	if err != nil {
 		return err
@@ -159,6 +163,15 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 		serviceConstraints = c.Cluster.QuotaConstraints.Projects[domainName][projectName][serviceType]
 	}
 
+	//relative constraints may refer to the domain quota
+	var domainQuotas map[string]uint64
+	if datamodel.HasRelativeConstraints(serviceConstraints) {
+		domainQuotas, err = datamodel.GetDomainQuotas(tx, domainID, serviceType)
+		if err != nil {
+			return err
+		}
+	}
+
 	//update existing project_resources entries
 	quotaValues := make(map[string]uint64)
 	needToSetQuota := false
@@ -167,6 +180,40 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 	if err != nil {
 		return err
 	}
+
+	//autogrow policies can only give out the part of the domain quota that is
+	//not yet given out to other projects
+	autogrowPolicies := c.Cluster.AutogrowPolicies[serviceType]
+	if quotaLocked {
+		autogrowPolicies = nil //locked quotas are not changed automatically
+	}
+	var otherProjectsQuotas, commitments map[string]uint64
+	if len(autogrowPolicies) > 0 {
+		if domainQuotas == nil {
+			domainQuotas, err = datamodel.GetDomainQuotas(tx, domainID, serviceType)
+			if err != nil {
+				return err
+			}
+		}
+		otherProjectsQuotas, err = datamodel.GetProjectsQuotas(tx, domainID, serviceType)
+		if err != nil {
+			return err
+		}
+		for _, res := range resources {
+			otherProjectsQuotas[res.Name] -= res.Quota
+		}
+		//autogrow may not shrink the quota below the active commitments
+		commitments, err = datamodel.GetProjectCommitments(tx, serviceID, scrapedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	var (
+		auditTrail        util.AuditTrail
+		constraintHistory datamodel.QuotaChangeHistory
+		autogrowHistory   datamodel.QuotaChangeHistory
+	)
 	for _, res := range resources {
 		quotaValues[res.Name] = res.Quota
 
@@ -179,24 +226,49 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 			continue
 		}
 
-		//check if we need to enforce a constraint
+		//check if we need to enforce a constraint (except for locked quotas)
 		constraint := serviceConstraints[res.Name]
-		if !constraint.Allows(res.Quota) {
+		effectiveConstraint := constraint.Evaluate(data.Usage, domainQuotas[res.Name])
+		if !quotaLocked && !effectiveConstraint.Allows(res.Quota) {
 			resInfo := c.Cluster.InfoForResource(serviceType, res.Name)
-			newQuota := constraint.ApplyTo(res.Quota)
+			newQuota := effectiveConstraint.ApplyTo(res.Quota)
 			util.LogInfo("changing %s/%s quota for project %s/%s from %s to %s to satisfy constraint %q",
 				serviceType, res.Name, domainName, projectName,
 				limes.ValueWithUnit{Value: res.Quota, Unit: resInfo.Unit},
 				limes.ValueWithUnit{Value: newQuota, Unit: resInfo.Unit},
 				constraint.ToString(resInfo.Unit),
 			)
+			constraintHistory.Add(serviceType, res.Name, res.Quota, newQuota)
 			res.Quota = newQuota
 			quotaValues[res.Name] = newQuota
 		}
 
+		//apply the autogrow policy, if any (but not while a temporary quota raise
+		//is pending)
+		if policy, exists := autogrowPolicies[res.Name]; exists && res.QuotaExpiresAt == nil {
+			maxQuota := autogrowMaxQuota(domainQuotas[res.Name], otherProjectsQuotas[res.Name])
+			newQuota, lowUsageSince := applyAutogrow(policy, constraint.Evaluate(data.Usage, domainQuotas[res.Name]), res.Quota, data.Usage, maxQuota, res.LowUsageSince, scrapedAt)
+			if newQuota < res.Quota && newQuota < commitments[res.Name] {
+				newQuota = commitments[res.Name]
+				if newQuota > res.Quota {
+					newQuota = res.Quota
+				}
+			}
+			if newQuota != res.Quota {
+				auditTrail.Add("set quota %s.%s = %d -> %d for project %s through autogrow",
+					serviceType, res.Name, res.Quota, newQuota, projectUUID,
+				)
+				autogrowHistory.Add(serviceType, res.Name, res.Quota, newQuota)
+				res.Quota = newQuota
+				quotaValues[res.Name] = newQuota
+			}
+			res.LowUsageSince = lowUsageSince
+		}
+
 		//update existing resource record
 		res.BackendQuota = data.Quota
 		res.Usage = data.Usage
+		res.Reserved = data.Reserved
 		if len(data.Subresources) == 0 {
 			res.SubresourcesJSON = ""
 		} else {
@@ -225,7 +297,6 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 	}
 
 	//insert missing project_resources entries
-	var auditTrail util.AuditTrail
 	for _, resMetadata := range c.Plugin.Resources() {
 		if _, exists := quotaValues[resMetadata.Name]; exists {
 			continue
@@ -234,8 +305,9 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 		res := &db.ProjectResource{
 			ServiceID:        serviceID,
 			Name:             resMetadata.Name,
-			Quota:            serviceConstraints[resMetadata.Name].InitialQuotaValue(),
+			Quota:            serviceConstraints[resMetadata.Name].Evaluate(data.Usage, domainQuotas[resMetadata.Name]).InitialQuotaValue(),
 			Usage:            data.Usage,
+			Reserved:         data.Reserved,
 			BackendQuota:     data.Quota,
 			SubresourcesJSON: "", //but see below
 		}
@@ -247,6 +319,20 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 			)
 		}
 
+		if policy, exists := autogrowPolicies[res.Name]; exists {
+			constraint := serviceConstraints[res.Name].Evaluate(data.Usage, domainQuotas[res.Name])
+			maxQuota := autogrowMaxQuota(domainQuotas[res.Name], otherProjectsQuotas[res.Name])
+			newQuota, lowUsageSince := applyAutogrow(policy, constraint, res.Quota, data.Usage, maxQuota, nil, scrapedAt)
+			if newQuota != res.Quota {
+				auditTrail.Add("set quota %s.%s = %d -> %d for project %s through autogrow",
+					serviceType, res.Name, res.Quota, newQuota, projectUUID,
+				)
+				autogrowHistory.Add(serviceType, res.Name, res.Quota, newQuota)
+				res.Quota = newQuota
+			}
+			res.LowUsageSince = lowUsageSince
+		}
+
 		if len(data.Subresources) != 0 {
 			//warn when the backend is inconsistent with itself
 			if uint64(len(data.Subresources)) != data.Usage {
@@ -272,6 +358,24 @@ func (c *Collector) writeScrapeResult(domainName, domainUUID, projectName, proje
 		}
 	}
 
+	//record automatic quota changes in the quota change history
+	event := db.QuotaChangeEvent{
+		ClusterID: c.Cluster.ID,
+		DomainID:  domainID,
+		ProjectID: &projectID,
+		CreatedAt: scrapedAt.UTC(),
+	}
+	event.Via = "to satisfy quota constraints"
+	err = constraintHistory.Save(tx, event)
+	if err != nil {
+		return err
+	}
+	event.Via = "through autogrow"
+	err = autogrowHistory.Save(tx, event)
+	if err != nil {
+		return err
+	}
+
 	//update scraped_at timestamp and reset the stale flag on this service so
 	//that we don't scrape it again immediately afterwards
 	_, err = tx.Exec(
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/prometheus/client_golang/prometheus"
@@ -121,6 +122,7 @@ func Test_Scrape(t *testing.T) {
 
 	//change the data that is reported by the plugin
 	plugin.StaticResourceData["capacity"].Quota = 110
+	plugin.StaticResourceData["capacity"].Reserved = 2
 	plugin.StaticResourceData["things"].Usage = 5
 	setProjectServicesStale(t)
 	//Scrape should pick up the changed resource data
@@ -250,3 +252,107 @@ func Test_AutoApproveInitialQuota(t *testing.T) {
 	c.Scrape()
 	test.AssertDBContent(t, "fixtures/scrape-autoapprove2.sql")
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// test for relative constraints
+
+func Test_ScrapeRelativeConstraints(t *testing.T) {
+	plugin := test.NewPlugin("unittest")
+	cluster := prepareScrapeTest(t, plugin)
+	cluster.QuotaConstraints.Projects["germany"]["berlin"]["unittest"]["things"] = limes.QuotaConstraint{
+		Maximum:         p2u64(12),
+		RelativeMinimum: &limes.RelativeQuotaBound{Base: limes.RelativeToUsage, Percent: 150},
+	}
+	c := Collector{
+		Cluster:  cluster,
+		Plugin:   plugin,
+		LogError: t.Errorf,
+		TimeNow:  test.TimeNow,
+		Once:     true,
+	}
+
+	//first Scrape should create the "things" resource with the quota required
+	//by the relative constraint (usage 2 x 1.5 = 3)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/relative-constraints1.sql")
+
+	//when usage grows, the constraint raises the quota (6 x 1.5 = 9)
+	plugin.StaticResourceData["things"].Usage = 6
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/relative-constraints2.sql")
+
+	//the absolute upper bound takes precedence over the relative lower bound
+	//(10 x 1.5 = 15 > 12)
+	plugin.StaticResourceData["things"].Usage = 10
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/relative-constraints3.sql")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// test for autogrow
+
+func Test_ScrapeAutogrow(t *testing.T) {
+	plugin := test.NewPlugin("unittest")
+	cluster := prepareScrapeTest(t, plugin)
+	cluster.AutogrowPolicies = map[string]map[string]limes.AutogrowPolicy{
+		"unittest": {
+			"things": {UsagePermille: 1500, MinHeadroom: 3, ShrinkAfter: 10 * time.Second},
+		},
+	}
+	c := Collector{
+		Cluster:  cluster,
+		Plugin:   plugin,
+		LogError: t.Errorf,
+		TimeNow:  test.TimeNow,
+		Once:     true,
+	}
+
+	//autogrow can only give out quota that the domain has
+	_, err := db.DB.Exec(`INSERT INTO domain_resources (service_id, name, quota) VALUES (1, 'things', 20)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//first Scrape should create the "things" resource with the quota required
+	//by the autogrow policy (usage 2 + headroom 3, since 2 x 1.5 is less), and
+	//record this in the quota change history
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow1.sql")
+
+	//when usage grows, quota grows along with it (10 x 1.5 = 15)...
+	plugin.StaticResourceData["things"].Usage = 10
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow2.sql")
+
+	//...but not beyond the domain quota (18 x 1.5 = 27 > 20)
+	plugin.StaticResourceData["things"].Usage = 18
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow3.sql")
+
+	//when usage drops, quota is not lowered immediately (usage 4 would require
+	//quota 7), but low_usage_since is recorded
+	plugin.StaticResourceData["things"].Usage = 4
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow4.sql")
+
+	//when usage stays low for long enough, quota is lowered
+	for idx := 0; idx < 10; idx++ {
+		test.TimeNow() //let some time pass
+	}
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow5.sql")
+
+	//while the project is locked, autogrow does not change the quota (usage 10
+	//would require quota 15)
+	mustExec(t, `UPDATE projects SET quota_locked = TRUE, quota_lock_reason = 'decommissioning'`)
+	plugin.StaticResourceData["things"].Usage = 10
+	setProjectServicesStale(t)
+	c.Scrape()
+	test.AssertDBContent(t, "fixtures/autogrow6.sql")
+}
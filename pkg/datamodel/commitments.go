@@ -0,0 +1,52 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package datamodel
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/sapcc/limes/pkg/db"
+)
+
+var projectCommitmentsQuery = `
+	SELECT pc.resource_name, SUM(pc.amount)
+	  FROM project_services ps
+	  JOIN project_commitments pc ON pc.project_id = ps.project_id AND pc.service_type = ps.type
+	 WHERE ps.id = $1 AND pc.expires_at > $2
+	 GROUP BY pc.resource_name
+`
+
+//GetProjectCommitments returns the sum of all active commitments for all
+//resources of the given project service, indexed by resource name. The
+//project quota for a resource may never be lower than this value.
+func GetProjectCommitments(dbi db.Interface, serviceID int64, now time.Time) (map[string]uint64, error) {
+	result := make(map[string]uint64)
+	err := db.ForeachRow(dbi, projectCommitmentsQuery, []interface{}{serviceID, now}, func(rows *sql.Rows) error {
+		var (
+			resourceName string
+			amount       uint64
+		)
+		err := rows.Scan(&resourceName, &amount)
+		result[resourceName] = amount
+		return err
+	})
+	return result, err
+}
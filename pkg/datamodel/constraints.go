@@ -0,0 +1,96 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package datamodel
+
+import (
+	"database/sql"
+
+	"github.com/sapcc/limes/pkg/db"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+var domainQuotasQuery = `
+	SELECT dr.name, dr.quota
+	  FROM domain_services ds
+	  JOIN domain_resources dr ON dr.service_id = ds.id
+	 WHERE ds.domain_id = $1 AND ds.type = $2
+`
+
+var domainUsagesQuery = `
+	SELECT pr.name, SUM(pr.usage)
+	  FROM projects p
+	  JOIN project_services ps ON ps.project_id = p.id
+	  JOIN project_resources pr ON pr.service_id = ps.id
+	 WHERE p.domain_id = $1 AND ps.type = $2
+	 GROUP BY pr.name
+`
+
+var projectsQuotasQuery = `
+	SELECT pr.name, SUM(pr.quota)
+	  FROM projects p
+	  JOIN project_services ps ON ps.project_id = p.id
+	  JOIN project_resources pr ON pr.service_id = ps.id
+	 WHERE p.domain_id = $1 AND ps.type = $2
+	 GROUP BY pr.name
+`
+
+//HasRelativeConstraints returns true if any of the given constraints contains
+//relative bounds, i.e. if live usage or domain quota values are required to
+//evaluate them.
+func HasRelativeConstraints(serviceConstraints map[string]limes.QuotaConstraint) bool {
+	for _, constraint := range serviceConstraints {
+		if constraint.IsRelative() {
+			return true
+		}
+	}
+	return false
+}
+
+//GetDomainQuotas returns the domain quotas for all resources of the given
+//service in the given domain, indexed by resource name.
+func GetDomainQuotas(dbi db.Interface, domainID int64, serviceType string) (map[string]uint64, error) {
+	return collectResourceValues(dbi, domainQuotasQuery, domainID, serviceType)
+}
+
+//GetDomainUsages returns the sum of project usages for all resources of the
+//given service in the given domain, indexed by resource name.
+func GetDomainUsages(dbi db.Interface, domainID int64, serviceType string) (map[string]uint64, error) {
+	return collectResourceValues(dbi, domainUsagesQuery, domainID, serviceType)
+}
+
+//GetProjectsQuotas returns the sum of project quotas for all resources of the
+//given service in the given domain, indexed by resource name.
+func GetProjectsQuotas(dbi db.Interface, domainID int64, serviceType string) (map[string]uint64, error) {
+	return collectResourceValues(dbi, projectsQuotasQuery, domainID, serviceType)
+}
+
+func collectResourceValues(dbi db.Interface, query string, domainID int64, serviceType string) (map[string]uint64, error) {
+	result := make(map[string]uint64)
+	err := db.ForeachRow(dbi, query, []interface{}{domainID, serviceType}, func(rows *sql.Rows) error {
+		var (
+			resourceName string
+			value        uint64
+		)
+		err := rows.Scan(&resourceName, &value)
+		result[resourceName] = value
+		return err
+	})
+	return result, err
+}
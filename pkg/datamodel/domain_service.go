@@ -80,7 +80,7 @@ func ValidateDomainServices(tx *gorp.Transaction, cluster *limes.Cluster, domain
 		}
 		services = append(services, srv)
 
-		err = createMissingDomainResources(tx, cluster, domain, srv, constraints[serviceType], nil)
+		err = createMissingDomainResources(tx, cluster, domain, srv, constraints[serviceType], nil, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -102,6 +102,16 @@ func checkDomainServiceConstraints(tx *gorp.Transaction, cluster *limes.Cluster,
 		return err
 	}
 
+	//relative constraints for domains are evaluated against the sum of the
+	//usage of all projects in the domain
+	var usages map[string]uint64
+	if HasRelativeConstraints(serviceConstraints) {
+		usages, err = GetDomainUsages(tx, domain.ID, srv.Type)
+		if err != nil {
+			return err
+		}
+	}
+
 	//check existing domain_resources for any quota values that violate constraints
 	seen := make(map[string]bool)
 	var resourcesToUpdate []interface{}
@@ -109,7 +119,7 @@ func checkDomainServiceConstraints(tx *gorp.Transaction, cluster *limes.Cluster,
 		seen[res.Name] = true
 
 		constraint := serviceConstraints[res.Name]
-		if newQuota := constraint.ApplyTo(res.Quota); newQuota != res.Quota {
+		if newQuota := constraint.Evaluate(usages[res.Name], 0).ApplyTo(res.Quota); newQuota != res.Quota {
 			resInfo := cluster.InfoForResource(srv.Type, res.Name)
 			util.LogInfo("changing %s/%s quota for domain %s from %s to %s to satisfy constraint %q",
 				srv.Type, res.Name, domain.Name,
@@ -147,10 +157,10 @@ func checkDomainServiceConstraints(tx *gorp.Transaction, cluster *limes.Cluster,
 	}
 
 	//create any missing domain resources where there are "at least/exactly/should be" constraints
-	return createMissingDomainResources(tx, cluster, domain, srv, serviceConstraints, seen)
+	return createMissingDomainResources(tx, cluster, domain, srv, serviceConstraints, seen, usages)
 }
 
-func createMissingDomainResources(tx *gorp.Transaction, cluster *limes.Cluster, domain db.Domain, srv db.DomainService, serviceConstraints map[string]limes.QuotaConstraint, resourceExists map[string]bool) error {
+func createMissingDomainResources(tx *gorp.Transaction, cluster *limes.Cluster, domain db.Domain, srv db.DomainService, serviceConstraints map[string]limes.QuotaConstraint, resourceExists map[string]bool, usages map[string]uint64) error {
 	//do not hit the database if there are no constraints to check
 	if len(serviceConstraints) == 0 {
 		return nil
@@ -160,7 +170,7 @@ func createMissingDomainResources(tx *gorp.Transaction, cluster *limes.Cluster,
 	resourceNames := make([]string, 0, len(serviceConstraints))
 	for resourceName, constraint := range serviceConstraints {
 		//initialize domain quotas where constraints require a non-zero quota value
-		if constraint.InitialQuotaValue() != 0 && !resourceExists[resourceName] {
+		if constraint.Evaluate(usages[resourceName], 0).InitialQuotaValue() != 0 && !resourceExists[resourceName] {
 			resourceNames = append(resourceNames, resourceName)
 		}
 	}
@@ -169,7 +179,7 @@ func createMissingDomainResources(tx *gorp.Transaction, cluster *limes.Cluster,
 	for _, resourceName := range resourceNames {
 		resInfo := cluster.InfoForResource(srv.Type, resourceName)
 		constraint := serviceConstraints[resourceName]
-		newQuota := constraint.InitialQuotaValue()
+		newQuota := constraint.Evaluate(usages[resourceName], 0).InitialQuotaValue()
 		util.LogInfo("initializing %s/%s quota for domain %s to %s to satisfy constraint %q",
 			srv.Type, resourceName, domain.Name,
 			limes.ValueWithUnit{Value: newQuota, Unit: resInfo.Unit},
@@ -0,0 +1,64 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package datamodel
+
+import (
+	"github.com/sapcc/limes/pkg/db"
+	gorp "gopkg.in/gorp.v2"
+)
+
+//QuotaChangeHistory collects quota changes that belong to the same event in
+//the quota change history, e.g. all changes made by a single API request, or
+//all changes made by autogrow during a single scrape.
+type QuotaChangeHistory []db.QuotaChange
+
+//Add records a quota change. Changes that do not alter the quota value (e.g.
+//when only the expiry date is updated) are not recorded.
+func (h *QuotaChangeHistory) Add(serviceType, resourceName string, oldQuota, newQuota uint64) {
+	if oldQuota == newQuota {
+		return
+	}
+	*h = append(*h, db.QuotaChange{
+		ServiceType:  serviceType,
+		ResourceName: resourceName,
+		OldQuota:     oldQuota,
+		NewQuota:     newQuota,
+	})
+}
+
+//Save writes the recorded changes into the quota change history as a single
+//event. Nothing is written if no changes were recorded.
+func (h QuotaChangeHistory) Save(tx *gorp.Transaction, event db.QuotaChangeEvent) error {
+	if len(h) == 0 {
+		return nil
+	}
+	err := tx.Insert(&event)
+	if err != nil {
+		return err
+	}
+	for _, change := range h {
+		change.EventID = event.ID
+		err := tx.Insert(&change)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
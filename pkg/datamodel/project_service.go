@@ -139,10 +139,18 @@ func checkProjectResourcesAgainstConstraint(tx *gorp.Transaction, cluster *limes
 		return false, err
 	}
 
+	var domainQuotas map[string]uint64
+	if HasRelativeConstraints(serviceConstraints) {
+		domainQuotas, err = GetDomainQuotas(tx, domain.ID, srv.Type)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	ok = true
 	for _, res := range resources {
 		constraint := serviceConstraints[res.Name]
-		if !constraint.Allows(res.Quota) {
+		if !constraint.Evaluate(res.Usage, domainQuotas[res.Name]).Allows(res.Quota) {
 			ok = false
 		}
 
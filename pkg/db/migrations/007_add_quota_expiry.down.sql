@@ -0,0 +1,4 @@
+ALTER TABLE domain_resources DROP COLUMN quota_expires_at;
+ALTER TABLE domain_resources DROP COLUMN previous_quota;
+ALTER TABLE project_resources DROP COLUMN quota_expires_at;
+ALTER TABLE project_resources DROP COLUMN previous_quota;
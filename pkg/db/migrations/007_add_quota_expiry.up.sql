@@ -0,0 +1,4 @@
+ALTER TABLE domain_resources ADD COLUMN quota_expires_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE domain_resources ADD COLUMN previous_quota BIGINT DEFAULT NULL;
+ALTER TABLE project_resources ADD COLUMN quota_expires_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE project_resources ADD COLUMN previous_quota BIGINT DEFAULT NULL;
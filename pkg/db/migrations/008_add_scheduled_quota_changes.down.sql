@@ -0,0 +1 @@
+DROP TABLE scheduled_quota_changes;
@@ -0,0 +1,20 @@
+CREATE TABLE scheduled_quota_changes (
+  id             BIGSERIAL NOT NULL PRIMARY KEY,
+  cluster_id     TEXT      NOT NULL,
+  domain_id      BIGINT    DEFAULT NULL REFERENCES domains ON DELETE CASCADE,  -- NULL for changes to cluster capacity
+  project_id     BIGINT    DEFAULT NULL REFERENCES projects ON DELETE CASCADE, -- NULL for changes to cluster capacity or domain quota
+  service_type   TEXT      NOT NULL,
+  resource_name  TEXT      NOT NULL,
+  new_value      BIGINT    DEFAULT NULL, -- exactly one of new_value and delta is not NULL
+  delta          BIGINT    DEFAULT NULL,
+  comment        TEXT      NOT NULL DEFAULT '',
+  due_at         TIMESTAMP NOT NULL,
+  created_at     TIMESTAMP NOT NULL,
+  creator_uuid   TEXT      NOT NULL,
+  creator_name   TEXT      NOT NULL,
+  can_raise      BOOLEAN   NOT NULL,
+  can_lower      BOOLEAN   NOT NULL,
+  processed_at   TIMESTAMP DEFAULT NULL, -- NULL while the change is pending
+  failure_reason TEXT      NOT NULL DEFAULT ''
+);
+CREATE INDEX scheduled_quota_changes_due_idx ON scheduled_quota_changes (cluster_id, processed_at, due_at);
@@ -0,0 +1 @@
+ALTER TABLE projects ADD COLUMN quota_class TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE project_resources DROP COLUMN low_usage_since;
@@ -0,0 +1 @@
+ALTER TABLE project_resources ADD COLUMN low_usage_since TIMESTAMP DEFAULT NULL;
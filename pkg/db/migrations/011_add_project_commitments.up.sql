@@ -0,0 +1,13 @@
+CREATE TABLE project_commitments (
+  id            BIGSERIAL NOT NULL PRIMARY KEY,
+  project_id    BIGINT    NOT NULL REFERENCES projects ON DELETE CASCADE,
+  service_type  TEXT      NOT NULL,
+  resource_name TEXT      NOT NULL,
+  amount        BIGINT    NOT NULL,
+  duration      TEXT      NOT NULL,
+  created_at    TIMESTAMP NOT NULL,
+  creator_uuid  TEXT      NOT NULL,
+  creator_name  TEXT      NOT NULL,
+  expires_at    TIMESTAMP NOT NULL
+);
+CREATE INDEX project_commitments_resource_idx ON project_commitments (service_type, resource_name, expires_at);
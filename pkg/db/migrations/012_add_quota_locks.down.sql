@@ -0,0 +1,4 @@
+ALTER TABLE domains DROP COLUMN quota_locked;
+ALTER TABLE domains DROP COLUMN quota_lock_reason;
+ALTER TABLE projects DROP COLUMN quota_locked;
+ALTER TABLE projects DROP COLUMN quota_lock_reason;
@@ -0,0 +1,4 @@
+ALTER TABLE domains ADD COLUMN quota_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE domains ADD COLUMN quota_lock_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE projects ADD COLUMN quota_locked BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE projects ADD COLUMN quota_lock_reason TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE domain_resources DROP COLUMN quota_comment;
+ALTER TABLE project_resources DROP COLUMN quota_comment;
@@ -0,0 +1,2 @@
+ALTER TABLE domain_resources ADD COLUMN quota_comment TEXT NOT NULL DEFAULT '';
+ALTER TABLE project_resources ADD COLUMN quota_comment TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+CREATE TABLE pending_quota_changes (
+  id            BIGSERIAL NOT NULL PRIMARY KEY,
+  cluster_id    TEXT      NOT NULL,
+  domain_id     BIGINT    DEFAULT NULL REFERENCES domains ON DELETE CASCADE, -- NULL for changes to cluster capacity
+  service_type  TEXT      NOT NULL,
+  resource_name TEXT      NOT NULL,
+  old_value     BIGINT    NOT NULL,
+  new_value     BIGINT    NOT NULL,
+  comment       TEXT      NOT NULL DEFAULT '',
+  created_at    TIMESTAMP NOT NULL,
+  creator_uuid  TEXT      NOT NULL,
+  creator_name  TEXT      NOT NULL,
+  expires_at    TIMESTAMP NOT NULL
+);
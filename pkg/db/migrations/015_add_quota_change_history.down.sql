@@ -0,0 +1,2 @@
+DROP TABLE quota_changes;
+DROP TABLE quota_change_events;
@@ -0,0 +1,19 @@
+CREATE TABLE quota_change_events (
+  id         BIGSERIAL NOT NULL PRIMARY KEY,
+  cluster_id TEXT      NOT NULL,
+  domain_id  BIGINT    NOT NULL REFERENCES domains ON DELETE CASCADE,
+  project_id BIGINT    DEFAULT NULL REFERENCES projects ON DELETE CASCADE, -- NULL for changes to domain quotas
+  user_uuid  TEXT      NOT NULL,
+  user_name  TEXT      NOT NULL,
+  via        TEXT      NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX quota_change_events_user_idx ON quota_change_events (domain_id, user_uuid, created_at);
+CREATE TABLE quota_changes (
+  event_id      BIGINT NOT NULL REFERENCES quota_change_events ON DELETE CASCADE,
+  service_type  TEXT   NOT NULL,
+  resource_name TEXT   NOT NULL,
+  old_quota     BIGINT NOT NULL,
+  new_quota     BIGINT NOT NULL,
+  PRIMARY KEY (event_id, service_type, resource_name)
+);
@@ -0,0 +1 @@
+ALTER TABLE project_resources ADD COLUMN reserved BIGINT NOT NULL DEFAULT 0;
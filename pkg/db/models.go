@@ -40,10 +40,12 @@ type ClusterResource struct {
 
 //Domain contains a record from the `domains` table.
 type Domain struct {
-	ID        int64  `db:"id"`
-	ClusterID string `db:"cluster_id"`
-	Name      string `db:"name"`
-	UUID      string `db:"uuid"`
+	ID              int64  `db:"id"`
+	ClusterID       string `db:"cluster_id"`
+	Name            string `db:"name"`
+	UUID            string `db:"uuid"`
+	QuotaLocked     bool   `db:"quota_locked"`
+	QuotaLockReason string `db:"quota_lock_reason"` //empty if not locked
 }
 
 //DomainService contains a record from the `domain_services` table.
@@ -55,18 +57,24 @@ type DomainService struct {
 
 //DomainResource contains a record from the `domain_resources` table.
 type DomainResource struct {
-	ServiceID int64  `db:"service_id"`
-	Name      string `db:"name"`
-	Quota     uint64 `db:"quota"`
+	ServiceID      int64      `db:"service_id"`
+	Name           string     `db:"name"`
+	Quota          uint64     `db:"quota"`
+	QuotaExpiresAt *time.Time `db:"quota_expires_at"` //only set for temporary quota raises
+	PreviousQuota  *uint64    `db:"previous_quota"`   //only set for temporary quota raises
+	QuotaComment   string     `db:"quota_comment"`    //justification given for the last quota change
 }
 
 //Project contains a record from the `projects` table.
 type Project struct {
-	ID         int64  `db:"id"`
-	DomainID   int64  `db:"domain_id"`
-	Name       string `db:"name"`
-	UUID       string `db:"uuid"`
-	ParentUUID string `db:"parent_uuid"`
+	ID              int64  `db:"id"`
+	DomainID        int64  `db:"domain_id"`
+	Name            string `db:"name"`
+	UUID            string `db:"uuid"`
+	ParentUUID      string `db:"parent_uuid"`
+	QuotaClass      string `db:"quota_class"` //empty if no quota class was applied
+	QuotaLocked     bool   `db:"quota_locked"`
+	QuotaLockReason string `db:"quota_lock_reason"` //empty if not locked
 }
 
 //ProjectService contains a record from the `project_services` table.
@@ -80,12 +88,89 @@ type ProjectService struct {
 
 //ProjectResource contains a record from the `project_resources` table.
 type ProjectResource struct {
-	ServiceID        int64  `db:"service_id"`
-	Name             string `db:"name"`
-	Quota            uint64 `db:"quota"`
-	Usage            uint64 `db:"usage"`
-	BackendQuota     int64  `db:"backend_quota"`
-	SubresourcesJSON string `db:"subresources"`
+	ServiceID        int64      `db:"service_id"`
+	Name             string     `db:"name"`
+	Quota            uint64     `db:"quota"`
+	Usage            uint64     `db:"usage"`
+	Reserved         uint64     `db:"reserved"` //only reported by some backends
+	BackendQuota     int64      `db:"backend_quota"`
+	SubresourcesJSON string     `db:"subresources"`
+	QuotaExpiresAt   *time.Time `db:"quota_expires_at"` //only set for temporary quota raises
+	PreviousQuota    *uint64    `db:"previous_quota"`   //only set for temporary quota raises
+	LowUsageSince    *time.Time `db:"low_usage_since"`  //only used by autogrow policies
+	QuotaComment     string     `db:"quota_comment"`    //justification given for the last quota change
+}
+
+//ScheduledQuotaChange contains a record from the `scheduled_quota_changes` table.
+type ScheduledQuotaChange struct {
+	ID            int64      `db:"id"`
+	ClusterID     string     `db:"cluster_id"`
+	DomainID      *int64     `db:"domain_id"`  //only set for changes to domain or project quotas
+	ProjectID     *int64     `db:"project_id"` //only set for changes to project quotas
+	ServiceType   string     `db:"service_type"`
+	ResourceName  string     `db:"resource_name"`
+	NewValue      *uint64    `db:"new_value"` //exactly one of NewValue and Delta is set
+	Delta         *int64     `db:"delta"`
+	Comment       string     `db:"comment"`
+	DueAt         time.Time  `db:"due_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+	CreatorUUID   string     `db:"creator_uuid"`
+	CreatorName   string     `db:"creator_name"`
+	CanRaise      bool       `db:"can_raise"`
+	CanLower      bool       `db:"can_lower"`
+	ProcessedAt   *time.Time `db:"processed_at"` //only set once the change was applied or has failed
+	FailureReason string     `db:"failure_reason"`
+}
+
+//PendingQuotaChange contains a record from the `pending_quota_changes` table.
+type PendingQuotaChange struct {
+	ID           int64     `db:"id"`
+	ClusterID    string    `db:"cluster_id"`
+	DomainID     *int64    `db:"domain_id"` //only set for changes to domain quotas
+	ServiceType  string    `db:"service_type"`
+	ResourceName string    `db:"resource_name"`
+	OldValue     uint64    `db:"old_value"`
+	NewValue     uint64    `db:"new_value"`
+	Comment      string    `db:"comment"`
+	CreatedAt    time.Time `db:"created_at"`
+	CreatorUUID  string    `db:"creator_uuid"`
+	CreatorName  string    `db:"creator_name"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+//QuotaChangeEvent contains a record from the `quota_change_events` table.
+type QuotaChangeEvent struct {
+	ID        int64     `db:"id"`
+	ClusterID string    `db:"cluster_id"`
+	DomainID  int64     `db:"domain_id"`
+	ProjectID *int64    `db:"project_id"` //only set for changes to project quotas
+	UserUUID  string    `db:"user_uuid"`
+	UserName  string    `db:"user_name"`
+	Via       string    `db:"via"` //empty if the user requested the change directly
+	CreatedAt time.Time `db:"created_at"`
+}
+
+//QuotaChange contains a record from the `quota_changes` table.
+type QuotaChange struct {
+	EventID      int64  `db:"event_id"`
+	ServiceType  string `db:"service_type"`
+	ResourceName string `db:"resource_name"`
+	OldQuota     uint64 `db:"old_quota"`
+	NewQuota     uint64 `db:"new_quota"`
+}
+
+//ProjectCommitment contains a record from the `project_commitments` table.
+type ProjectCommitment struct {
+	ID           int64     `db:"id"`
+	ProjectID    int64     `db:"project_id"`
+	ServiceType  string    `db:"service_type"`
+	ResourceName string    `db:"resource_name"`
+	Amount       uint64    `db:"amount"`
+	Duration     string    `db:"duration"`
+	CreatedAt    time.Time `db:"created_at"`
+	CreatorUUID  string    `db:"creator_uuid"`
+	CreatorName  string    `db:"creator_name"`
+	ExpiresAt    time.Time `db:"expires_at"`
 }
 
 //InitGorp is used by Init() to setup the ORM part of the database connection.
@@ -100,4 +185,9 @@ func InitGorp() {
 	DB.AddTableWithName(Project{}, "projects").SetKeys(true, "id")
 	DB.AddTableWithName(ProjectService{}, "project_services").SetKeys(true, "id")
 	DB.AddTableWithName(ProjectResource{}, "project_resources").SetKeys(false, "service_id", "name")
+	DB.AddTableWithName(ScheduledQuotaChange{}, "scheduled_quota_changes").SetKeys(true, "id")
+	DB.AddTableWithName(ProjectCommitment{}, "project_commitments").SetKeys(true, "id")
+	DB.AddTableWithName(PendingQuotaChange{}, "pending_quota_changes").SetKeys(true, "id")
+	DB.AddTableWithName(QuotaChangeEvent{}, "quota_change_events").SetKeys(true, "id")
+	DB.AddTableWithName(QuotaChange{}, "quota_changes").SetKeys(false, "event_id", "service_type", "resource_name")
 }
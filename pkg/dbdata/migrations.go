@@ -12,6 +12,26 @@
 // pkg/db/migrations/005_add_project_resource_subresources.up.sql
 // pkg/db/migrations/006_add_cluster_resources_subcapacities.down.sql
 // pkg/db/migrations/006_add_cluster_resources_subcapacities.up.sql
+// pkg/db/migrations/007_add_quota_expiry.down.sql
+// pkg/db/migrations/007_add_quota_expiry.up.sql
+// pkg/db/migrations/008_add_scheduled_quota_changes.down.sql
+// pkg/db/migrations/008_add_scheduled_quota_changes.up.sql
+// pkg/db/migrations/009_add_project_quota_class.down.sql
+// pkg/db/migrations/009_add_project_quota_class.up.sql
+// pkg/db/migrations/010_add_project_resources_low_usage_since.down.sql
+// pkg/db/migrations/010_add_project_resources_low_usage_since.up.sql
+// pkg/db/migrations/011_add_project_commitments.down.sql
+// pkg/db/migrations/011_add_project_commitments.up.sql
+// pkg/db/migrations/012_add_quota_locks.down.sql
+// pkg/db/migrations/012_add_quota_locks.up.sql
+// pkg/db/migrations/013_add_quota_comments.down.sql
+// pkg/db/migrations/013_add_quota_comments.up.sql
+// pkg/db/migrations/014_add_pending_quota_changes.down.sql
+// pkg/db/migrations/014_add_pending_quota_changes.up.sql
+// pkg/db/migrations/015_add_quota_change_history.down.sql
+// pkg/db/migrations/015_add_quota_change_history.up.sql
+// pkg/db/migrations/016_add_project_resources_reserved.down.sql
+// pkg/db/migrations/016_add_project_resources_reserved.up.sql
 // DO NOT EDIT!
 
 package dbdata
@@ -319,6 +339,406 @@ func _006_add_cluster_resources_subcapacitiesUpSql() (*asset, error) {
 	return a, nil
 }
 
+var __007_add_quota_expiryDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xc9\xcf\x4d\xcc\xcc\x8b\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\x4f\xad\x28\xc8\x04\xca\xc7\x27\x96\x58\x73\x39\x12\xab\xb5\xa0\x28\xb5\x2c\x33\xbf\xb4\x38\x1e\x6c\x06\xaa\xc6\x82\xa2\xfc\xac\xd4\xe4\x12\xb2\x2c\xc5\xaf\x17\xdd\x56\x00\xaf\xf9\x1e\x5a\xea\x00\x00\x00")
+
+func _007_add_quota_expiryDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__007_add_quota_expiryDownSql,
+		"007_add_quota_expiry.down.sql",
+	)
+}
+
+func _007_add_quota_expiryDownSql() (*asset, error) {
+	bytes, err := _007_add_quota_expiryDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "007_add_quota_expiry.down.sql", size: 234, mode: os.FileMode(420), modTime: time.Unix(1792393290, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __007_add_quota_expiryUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\xce\x3d\x0a\x02\x31\x10\x40\xe1\xde\x53\xcc\x3d\xac\x66\x4d\x94\xc0\x24\x8a\x4e\xea\x10\xd6\x14\x11\x74\x62\x7e\xc4\xe3\x2b\x1e\x60\xd9\xc2\xfa\xc1\xc7\x43\x62\x7d\x06\xc6\x89\x34\x5c\xe5\x1e\xf3\x23\xd4\xd4\x64\xd4\x39\x35\x40\xa5\x60\x77\x24\x6f\x1d\x3c\x87\xf4\x18\xd2\xbb\xe4\x6f\x0e\xb1\x03\x1b\xab\x2f\x8c\xf6\x04\x4a\xef\xd1\x13\x83\xf3\x44\xdb\x0d\xae\x04\x4b\x4d\xaf\x2c\xa3\x85\x9f\x0c\x93\x39\x18\xc7\x0b\x56\xa9\x72\x4b\x73\xff\xe3\xdd\xa2\xb8\x66\xef\x03\x68\xf6\x6b\x4e\x3c\x01\x00\x00")
+
+func _007_add_quota_expiryUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__007_add_quota_expiryUpSql,
+		"007_add_quota_expiry.up.sql",
+	)
+}
+
+func _007_add_quota_expiryUpSql() (*asset, error) {
+	bytes, err := _007_add_quota_expiryUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "007_add_quota_expiry.up.sql", size: 316, mode: os.FileMode(420), modTime: time.Unix(1792393290, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __008_add_scheduled_quota_changesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x4e\xce\x48\x4d\x29\xcd\x49\x4d\x89\x2f\x2c\xcd\x2f\x49\x8c\x4f\xce\x48\xcc\x4b\x4f\x2d\xb6\xe6\x02\x00\x33\x64\x62\x41\x24\x00\x00\x00")
+
+func _008_add_scheduled_quota_changesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__008_add_scheduled_quota_changesDownSql,
+		"008_add_scheduled_quota_changes.down.sql",
+	)
+}
+
+func _008_add_scheduled_quota_changesDownSql() (*asset, error) {
+	bytes, err := _008_add_scheduled_quota_changesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "008_add_scheduled_quota_changes.down.sql", size: 36, mode: os.FileMode(420), modTime: time.Unix(1792393719, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __008_add_scheduled_quota_changesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x93\xcd\x4e\x02\x31\x14\x85\xf7\x3c\xc5\xdd\xa9\x09\x3e\x81\xab\x32\x54\x33\x71\x18\xc8\x30\x26\xb0\x6a\x9a\xce\x45\x6a\x86\x16\xfb\x03\xf8\xf6\x76\x7e\xaa\x18\x19\x94\xe5\xf0\x9d\xde\xd3\x73\x6e\x93\x82\x92\x92\x42\x49\x26\x19\x05\x2b\xb6\x58\xf9\x1a\x2b\xf6\xee\xb5\xe3\x4c\x6c\xb9\x7a\x45\x0b\xb7\x23\x00\x59\xc1\xf9\x6f\x92\x3e\x2d\x69\x91\x92\x0c\xf2\x79\x09\xf9\x4b\x96\xc1\xa2\x48\x67\xa4\x58\xc3\x33\x5d\x8f\x83\x40\xd4\xde\x3a\x34\xac\x17\x96\x74\x55\x76\xd2\x28\x68\xa0\x4a\xef\xb8\x54\x91\x69\x4e\x4d\xf3\x16\x9b\xd2\x47\xf2\x92\xf5\x27\x17\xf4\x91\x16\x34\x4f\xe8\xb2\x17\x58\x98\xe7\x01\xc9\x68\xb0\x9e\x90\x65\x42\xa6\x74\x0c\x70\x7f\xdf\xe1\x1b\x6d\x20\x5a\x77\x3a\x1a\x01\xc1\xf7\x5c\x48\xf7\x11\xc6\xee\x8d\x7e\x43\xe1\xe2\xdc\xbf\xc7\xf6\x82\x8b\x73\xff\x39\x16\xc2\xdf\x9d\x7b\x68\xd3\x0d\x36\x2c\x9a\x83\x14\xc8\xdc\xc7\x1e\x07\x23\x32\x68\xb5\x37\x81\x52\x7c\x87\x43\x90\xc2\x23\x3b\xf0\xda\xe3\xb5\x1c\x5b\xab\x78\xe2\xc2\xd5\xc1\x8d\x42\xd0\x9b\x33\x21\x57\x15\x54\x58\x3b\x0e\xd2\x82\xd2\xae\x95\x34\x15\xb5\xdf\xce\x8b\xbf\x74\x74\x53\xb8\xde\xed\x50\xb9\x08\xfe\x36\xfa\xa5\xb8\xb9\x69\xbb\xf7\xc8\xf8\x17\x0e\x65\x3a\xa3\xcb\x92\xcc\x16\x3f\x2e\x26\x0c\x72\x17\x16\xb2\x07\xaf\x40\xda\x30\xef\xdb\x42\x2f\x47\x14\xa1\x2e\xc6\x21\x88\x2b\x66\xb8\xb4\x31\xc7\xf9\x3c\xa3\x24\xbf\x00\xd5\xfa\x18\xba\xbd\x02\x85\x8d\x11\x68\x6d\xb4\xfe\x6d\xfc\x57\x23\x6d\x34\xc7\xad\xac\x11\xdc\x16\xfb\x15\x6a\x4a\xd8\xa3\xaa\xa4\x7a\x0d\x87\x6d\xb8\xac\xbd\x41\x16\xae\x60\xb5\xba\x1e\xed\xe8\xee\x61\x94\x74\xaf\x3a\xcd\xa7\x74\x35\xf4\xaa\x59\x93\xbf\xac\x4e\xcd\x4a\x0f\x3e\xfc\xef\x47\x3c\xfe\x71\xa3\x71\xdf\x5e\x98\xf5\x09\x8c\x4f\x0b\xd6\x42\x04\x00\x00")
+
+func _008_add_scheduled_quota_changesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__008_add_scheduled_quota_changesUpSql,
+		"008_add_scheduled_quota_changes.up.sql",
+	)
+}
+
+func _008_add_scheduled_quota_changesUpSql() (*asset, error) {
+	bytes, err := _008_add_scheduled_quota_changesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "008_add_scheduled_quota_changes.up.sql", size: 1090, mode: os.FileMode(420), modTime: time.Unix(1792393719, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __009_add_project_quota_classDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x29\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\x4f\xce\x49\x2c\x2e\xb6\xe6\x02\x00\xf3\xf5\x11\xf1\x2e\x00\x00\x00")
+
+func _009_add_project_quota_classDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__009_add_project_quota_classDownSql,
+		"009_add_project_quota_class.down.sql",
+	)
+}
+
+func _009_add_project_quota_classDownSql() (*asset, error) {
+	bytes, err := _009_add_project_quota_classDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "009_add_project_quota_class.down.sql", size: 46, mode: os.FileMode(420), modTime: time.Unix(1792394308, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __009_add_project_quota_classUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x29\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\x4f\xce\x49\x2c\x2e\x56\x08\x71\x8d\x08\x51\xf0\xf3\x07\xe2\x50\x1f\x1f\x05\x17\x57\x37\xc7\x50\x9f\x10\x05\x75\x75\x6b\x2e\x00\xd1\x98\xca\xbb\x46\x00\x00\x00")
+
+func _009_add_project_quota_classUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__009_add_project_quota_classUpSql,
+		"009_add_project_quota_class.up.sql",
+	)
+}
+
+func _009_add_project_quota_classUpSql() (*asset, error) {
+	bytes, err := _009_add_project_quota_classUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "009_add_project_quota_class.up.sql", size: 70, mode: os.FileMode(420), modTime: time.Unix(1792394308, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __010_add_project_resources_low_usage_sinceDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x89\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\xc8\xc9\x2f\x8f\x2f\x2d\x4e\x4c\x4f\x8d\x2f\xce\xcc\x4b\x4e\xb5\xe6\x02\x00\xa6\x97\x98\xb3\x3b\x00\x00\x00")
+
+func _010_add_project_resources_low_usage_sinceDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__010_add_project_resources_low_usage_sinceDownSql,
+		"010_add_project_resources_low_usage_since.down.sql",
+	)
+}
+
+func _010_add_project_resources_low_usage_sinceDownSql() (*asset, error) {
+	bytes, err := _010_add_project_resources_low_usage_sinceDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "010_add_project_resources_low_usage_since.down.sql", size: 59, mode: os.FileMode(420), modTime: time.Unix(1792394591, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __010_add_project_resources_low_usage_sinceUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x05\xc1\xc1\x0a\x80\x20\x0c\x00\xd0\x7b\x5f\xb1\xff\xe8\xb4\x72\x41\x30\x2d\x6a\x9e\x25\x64\x44\x11\x19\x9a\xf4\xfb\xbd\x87\x2c\xb4\x80\x60\xc7\x04\x4f\x4e\xa7\xc6\x37\x64\x2d\xa9\xe6\xa8\x05\xd0\x18\xe8\x27\xf6\xd6\xc1\x95\xbe\x50\xcb\xb6\x6b\x28\xc7\x1d\x15\x64\xb4\xb4\x0a\xda\x19\x0c\x0d\xe8\x59\xc0\x79\xe6\xb6\xf9\x01\x3d\xb4\x36\xc1\x51\x00\x00\x00")
+
+func _010_add_project_resources_low_usage_sinceUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__010_add_project_resources_low_usage_sinceUpSql,
+		"010_add_project_resources_low_usage_since.up.sql",
+	)
+}
+
+func _010_add_project_resources_low_usage_sinceUpSql() (*asset, error) {
+	bytes, err := _010_add_project_resources_low_usage_sinceUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "010_add_project_resources_low_usage_since.up.sql", size: 81, mode: os.FileMode(420), modTime: time.Unix(1792394591, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __011_add_project_commitmentsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x89\x4f\xce\xcf\xcd\xcd\x2c\xc9\x4d\xcd\x2b\x29\xb6\xe6\x02\x00\x2c\x7e\xe2\x42\x20\x00\x00\x00")
+
+func _011_add_project_commitmentsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__011_add_project_commitmentsDownSql,
+		"011_add_project_commitments.down.sql",
+	)
+}
+
+func _011_add_project_commitmentsDownSql() (*asset, error) {
+	bytes, err := _011_add_project_commitmentsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "011_add_project_commitments.down.sql", size: 32, mode: os.FileMode(420), modTime: time.Unix(1792395129, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __011_add_project_commitmentsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7d\x91\xc1\x6e\x83\x30\x10\x44\xef\x7c\xc5\x1e\x5b\x89\x3f\xe8\xc9\x81\x6d\x65\xd5\x38\x91\x71\xa5\xe4\x84\x2c\xf0\xc1\x91\xc0\xc8\xd8\x55\xfa\xf7\xb5\x83\x88\x68\x0b\xf5\xc9\x87\xb7\xb3\x33\xb3\x85\x40\x22\x11\x24\x39\x30\x84\xd1\xd9\xab\x6e\x7d\xd3\xda\xbe\x37\xbe\xd7\x83\x9f\xe0\x29\x03\x30\x1d\xac\xde\x81\xbe\xd5\x28\x28\x61\xc0\x8f\x12\xf8\x07\x63\x70\x12\xb4\x22\xe2\x02\xef\x78\xc9\x23\xbf\xe8\xcc\x73\x91\xa7\x5c\xa6\xdf\x83\x17\xf8\x8a\x02\x79\x81\xf5\xc2\x4e\x70\xe4\x50\x22\xc3\x68\xa6\x20\x75\x41\x4a\x4c\x4a\x93\x76\x9f\xa6\xd5\x8d\xff\x1a\x35\x80\xc4\xb3\x9c\x3d\x2c\x4a\x89\x71\x7a\xb2\xc1\x45\x68\x50\xbd\xde\x61\x54\x6f\xc3\xe0\x57\x09\x7e\x39\x4a\x4c\x17\x9c\xf2\xc6\x0e\x33\xb3\xad\xd3\x3a\xad\xbc\xee\x1a\x75\xd7\x92\xb4\xc2\x5a\x92\xea\xf4\x97\xb1\xae\x09\x21\xe5\xff\x47\x27\x32\x77\xcb\x3b\x8c\xbe\x8d\x26\x66\xdb\xdd\x95\x3d\xbf\x64\xc5\x7c\x3e\xca\x4b\x3c\x6f\x9d\xaf\x79\x94\x63\xba\x5b\xaa\x78\xf3\xc4\xeb\x96\xf3\x9f\x7d\xe6\x2b\x1b\x71\xdf\x37\x05\xe0\x2a\x0c\x2f\x02\x00\x00")
+
+func _011_add_project_commitmentsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__011_add_project_commitmentsUpSql,
+		"011_add_project_commitments.up.sql",
+	)
+}
+
+func _011_add_project_commitmentsUpSql() (*asset, error) {
+	bytes, err := _011_add_project_commitmentsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "011_add_project_commitments.up.sql", size: 559, mode: os.FileMode(420), modTime: time.Unix(1792395129, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __012_add_quota_locksDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xc9\xcf\x4d\xcc\xcc\x2b\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\xcf\xc9\x4f\xce\x4e\x4d\xb1\xe6\x72\x24\x5a\x75\x7c\x51\x6a\x62\x71\x7e\x1e\xaa\x96\x82\xa2\xfc\xac\xd4\xe4\x12\x62\x6d\x20\xa0\x1c\x6e\x05\x00\x3b\x97\x9d\x02\xc4\x00\x00\x00")
+
+func _012_add_quota_locksDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__012_add_quota_locksDownSql,
+		"012_add_quota_locks.down.sql",
+	)
+}
+
+func _012_add_quota_locksDownSql() (*asset, error) {
+	bytes, err := _012_add_quota_locksDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "012_add_quota_locks.down.sql", size: 196, mode: os.FileMode(420), modTime: time.Unix(1792395467, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __012_add_quota_locksUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xad\xce\x3b\x0e\x82\x40\x10\x06\xe0\xde\x53\xfc\x1d\x87\xb0\x1a\xdc\xa1\x1a\x77\x13\x99\x4d\xe8\xc8\x06\xb6\xc0\x07\xa3\x3c\xee\x6f\x4d\x48\xd4\xc2\x0b\x7c\xf9\x48\x94\x2f\x50\x2a\x85\xd1\xdb\x23\x0d\xe3\x0c\x72\x0e\xa7\x20\xf1\xec\xf1\x5a\x6d\x49\xed\xdd\xba\x5b\xee\x51\x86\x20\x4c\x1e\x3e\x28\x7c\x14\x81\xe3\x8a\xa2\x28\x2a\x92\x9a\x8f\x07\xfa\xd5\x6a\xa7\x9c\x66\x1b\xa1\xdc\xe8\x5e\x2b\x8a\x2d\xf5\x9c\xec\x9a\xbb\xe5\x3f\xaf\xcf\xd8\xf7\xd8\x1b\x20\x93\x92\x7c\x30\x01\x00\x00")
+
+func _012_add_quota_locksUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__012_add_quota_locksUpSql,
+		"012_add_quota_locks.up.sql",
+	)
+}
+
+func _012_add_quota_locksUpSql() (*asset, error) {
+	bytes, err := _012_add_quota_locksUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "012_add_quota_locks.up.sql", size: 304, mode: os.FileMode(420), modTime: time.Unix(1792395467, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __013_add_quota_commentsDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xc9\xcf\x4d\xcc\xcc\x8b\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\x4f\xce\xcf\xcd\x4d\xcd\x2b\xb1\xe6\x72\x44\xd2\x57\x50\x94\x9f\x95\x9a\x5c\x42\x9c\x46\x00\x36\x3b\x65\xe1\x71\x00\x00\x00")
+
+func _013_add_quota_commentsDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__013_add_quota_commentsDownSql,
+		"013_add_quota_comments.down.sql",
+	)
+}
+
+func _013_add_quota_commentsDownSql() (*asset, error) {
+	bytes, err := _013_add_quota_commentsDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "013_add_quota_comments.down.sql", size: 113, mode: os.FileMode(420), modTime: time.Unix(1792395807, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __013_add_quota_commentsUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x48\xc9\xcf\x4d\xcc\xcc\x8b\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x28\x2c\xcd\x2f\x49\x8c\x4f\xce\xcf\xcd\x4d\xcd\x2b\x51\x08\x71\x8d\x08\x51\xf0\xf3\x07\xe2\x50\x1f\x1f\x05\x17\x57\x37\xc7\x50\x9f\x10\x05\x75\x75\x6b\x2e\x47\x24\xf3\x0a\x8a\xf2\xb3\x52\x93\x4b\x28\x33\x10\x00\x7d\x72\xce\x89\xa1\x00\x00\x00")
+
+func _013_add_quota_commentsUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__013_add_quota_commentsUpSql,
+		"013_add_quota_comments.up.sql",
+	)
+}
+
+func _013_add_quota_commentsUpSql() (*asset, error) {
+	bytes, err := _013_add_quota_commentsUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "013_add_quota_comments.up.sql", size: 161, mode: os.FileMode(420), modTime: time.Unix(1792395807, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __014_add_pending_quota_changesDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x48\xcd\x4b\xc9\xcc\x4b\x8f\x2f\x2c\xcd\x2f\x49\x8c\x4f\xce\x48\xcc\x4b\x4f\x2d\xb6\xe6\x02\x00\x15\xf6\x35\x48\x22\x00\x00\x00")
+
+func _014_add_pending_quota_changesDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__014_add_pending_quota_changesDownSql,
+		"014_add_pending_quota_changes.down.sql",
+	)
+}
+
+func _014_add_pending_quota_changesDownSql() (*asset, error) {
+	bytes, err := _014_add_pending_quota_changesDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "014_add_pending_quota_changes.down.sql", size: 34, mode: os.FileMode(420), modTime: time.Unix(1792396125, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __014_add_pending_quota_changesUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x85\xd0\x4d\x6e\x83\x30\x10\x05\xe0\x3d\xa7\x98\x5d\x5a\x29\x39\x41\x57\x0e\x99\x54\xa8\x40\x22\xe3\x48\xcd\xca\xb2\xcc\x34\x45\x02\x9b\x1a\x93\x36\xb7\x2f\x10\xa8\xd2\x1f\x54\xaf\xbf\x99\x79\x7e\x21\x47\x26\x10\x04\x5b\xc7\x08\x35\x99\xbc\x30\x27\xf9\xd6\x5a\xaf\xa4\x7e\x55\xe6\x44\x0d\xdc\x05\x00\x45\x0e\x37\x6f\x1d\x3d\x66\xc8\x23\x16\x43\xba\x13\x90\x1e\xe2\x18\xf6\x3c\x4a\x18\x3f\xc2\x13\x1e\x97\x9d\xd7\x65\xdb\x78\x72\xf2\x3a\x27\xf0\x59\x5c\x27\x27\xdf\x9b\xdc\x56\xaa\x30\x23\xe9\x77\x46\xe9\xa0\x36\xb8\x65\x87\x78\xdc\xcb\x71\x8b\x1c\xd3\x10\xb3\xd1\x37\xb0\x4b\x3b\x12\x63\x97\x3a\x64\x59\xc8\x36\xb8\x84\xd5\xea\xaa\x5f\xac\x83\x29\xb6\xb7\x53\x0a\xd0\xaa\x56\xba\xf0\x97\xee\x68\x43\xee\x5c\x68\x92\xfe\x52\xd3\x5c\x30\x47\x8d\x6d\x5d\x87\x8c\xaa\x68\xc6\xd8\x32\x97\x67\x55\xb6\xf4\x23\xfc\xad\x31\xf4\xfe\xaf\xd1\xb6\xaa\xc8\xf8\xb1\xd8\xdf\xb7\xbe\xda\x58\x2c\x06\xee\x48\x79\xca\xa5\x1a\x26\x44\x94\x60\x26\x58\xb2\xff\xbe\xb2\x37\xd6\xc9\xb6\xed\xab\xfd\x3b\xfe\x64\x86\x1f\xce\x18\xfa\xa8\x8b\xae\x8a\xd9\x5b\xc1\xfd\x43\xf0\x09\x1a\xf8\x74\x46\x3f\x02\x00\x00")
+
+func _014_add_pending_quota_changesUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__014_add_pending_quota_changesUpSql,
+		"014_add_pending_quota_changes.up.sql",
+	)
+}
+
+func _014_add_pending_quota_changesUpSql() (*asset, error) {
+	bytes, err := _014_add_pending_quota_changesUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "014_add_pending_quota_changes.up.sql", size: 575, mode: os.FileMode(420), modTime: time.Unix(1792396125, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __015_add_quota_change_historyDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\x09\xf2\x0f\x50\x08\x71\x74\xf2\x71\x55\x28\x2c\xcd\x2f\x49\x8c\x4f\xce\x48\xcc\x4b\x4f\x2d\xb6\xe6\x72\xc1\x2e\x13\x9f\x5a\x96\x9a\x57\x02\x94\x07\x00\x9a\x5e\xa4\xe6\x3a\x00\x00\x00")
+
+func _015_add_quota_change_historyDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__015_add_quota_change_historyDownSql,
+		"015_add_quota_change_history.down.sql",
+	)
+}
+
+func _015_add_quota_change_historyDownSql() (*asset, error) {
+	bytes, err := _015_add_quota_change_historyDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "015_add_quota_change_history.down.sql", size: 58, mode: os.FileMode(420), modTime: time.Unix(1792396419, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __015_add_quota_change_historyUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7d\x92\xcd\x6e\x83\x30\x10\x84\xef\x79\x8a\xbd\x25\x91\xc8\x13\xf4\xe4\xc0\xa6\x42\x25\x24\x02\x47\x4a\x4e\x96\x05\x6e\x4b\x95\xe0\x14\x9b\xb4\x7d\xfb\xda\xfc\x04\x22\xdc\x72\xe5\xf3\xec\xcc\xce\xfa\x09\x12\x8a\x40\xc9\x3a\x42\xf8\xac\xa5\xe6\x2c\x7b\xe7\xe5\x9b\x60\xe2\x26\x4a\xad\x60\x31\x03\x28\x72\xe8\xbf\x75\xf8\x9c\x62\x12\x92\x08\xe2\x1d\x85\xf8\x10\x45\xb0\x4f\xc2\x2d\x49\x4e\xf0\x82\x27\xcf\xc0\xd9\xb9\x56\x5a\x54\xcc\x3c\xa2\x78\xa4\xed\xb3\x1e\xb6\x40\x2e\x2f\xbc\x28\xed\x7f\xab\x16\xc6\x74\x0c\x40\x82\x1b\x4c\x30\xf6\x31\xed\x40\x05\xbb\x18\x02\x8c\xd0\xd8\xf4\x49\xea\x93\x00\xad\xca\xb5\x92\x1f\x22\xd3\x56\x66\x50\x09\x70\x43\x0e\xd1\x54\xa9\x83\x5d\x52\xb0\x5a\xb5\xf8\xab\xac\xa0\x8d\xae\x40\xcb\x6e\x78\xbb\x12\x65\xe6\xd5\xca\x64\xaa\x6b\xeb\xda\x1d\xab\x01\x4a\x7e\x11\x7f\x01\xb7\x82\xf7\x5b\x9c\x02\x77\xeb\xf3\x79\xb3\xc4\x4a\x70\x2d\x72\xc6\x35\xd0\x70\x8b\x29\x25\xdb\xfd\x9d\x9d\x2d\x9f\x66\x7e\xdb\x5b\x18\x07\x78\x74\xf5\xc6\x1a\x3b\x45\xfe\x6d\x23\x3b\x7b\xbd\xd7\xe0\x0d\xd9\xbc\xd1\xe0\x61\xc8\xf4\x38\xda\xb3\x68\x94\x58\x7f\x1c\x5d\x0b\xae\x22\x5d\xf3\x9d\xa5\x1a\x1f\xb7\x22\x13\x4c\xff\x5c\x87\x35\x8e\x77\x58\x09\x25\xeb\xca\x10\xcd\xa2\x1d\x80\x3c\xe7\xac\x19\xe7\xf2\x64\x81\x52\x7c\xfd\x0f\x8c\xce\x19\x16\x7d\x44\xef\xc1\x9a\xf7\xe8\x63\x69\x0b\xf9\x05\xe0\x02\x4c\x2a\x49\x03\x00\x00")
+
+func _015_add_quota_change_historyUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__015_add_quota_change_historyUpSql,
+		"015_add_quota_change_history.up.sql",
+	)
+}
+
+func _015_add_quota_change_historyUpSql() (*asset, error) {
+	bytes, err := _015_add_quota_change_historyUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "015_add_quota_change_history.up.sql", size: 841, mode: os.FileMode(420), modTime: time.Unix(1792396419, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __016_add_project_resources_reservedDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x89\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x09\xf2\x0f\x50\x70\xf6\xf7\x09\xf5\xf5\x53\x00\x8a\xa6\x16\x95\xa5\xa6\x58\x73\x01\x00\x3a\xbf\x35\x1b\x34\x00\x00\x00")
+
+func _016_add_project_resources_reservedDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__016_add_project_resources_reservedDownSql,
+		"016_add_project_resources_reserved.down.sql",
+	)
+}
+
+func _016_add_project_resources_reservedDownSql() (*asset, error) {
+	bytes, err := _016_add_project_resources_reservedDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "016_add_project_resources_reserved.down.sql", size: 52, mode: os.FileMode(420), modTime: time.Unix(1792397342, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __016_add_project_resources_reservedUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x73\xf4\x09\x71\x0d\x52\x08\x71\x74\xf2\x71\x55\x28\x28\xca\xcf\x4a\x4d\x2e\x89\x2f\x4a\x2d\xce\x2f\x2d\x4a\x4e\x2d\x56\x70\x74\x71\x51\x70\xf6\xf7\x09\xf5\xf5\x53\x00\x0a\xa6\x16\x95\xa5\xa6\x28\x38\x79\xba\x7b\xfa\x85\x28\xf8\xf9\x03\x71\xa8\x8f\x8f\x82\x8b\xab\x9b\x63\xa8\x4f\x88\x82\x81\x35\x17\x00\x8f\x47\xe2\x1c\x4d\x00\x00\x00")
+
+func _016_add_project_resources_reservedUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__016_add_project_resources_reservedUpSql,
+		"016_add_project_resources_reserved.up.sql",
+	)
+}
+
+func _016_add_project_resources_reservedUpSql() (*asset, error) {
+	bytes, err := _016_add_project_resources_reservedUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "016_add_project_resources_reserved.up.sql", size: 77, mode: os.FileMode(420), modTime: time.Unix(1792397342, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -371,29 +791,51 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_initial.down.sql":                             _001_initialDownSql,
-	"001_initial.up.sql":                               _001_initialUpSql,
-	"002_add_cluster_resource_comment.down.sql":        _002_add_cluster_resource_commentDownSql,
-	"002_add_cluster_resource_comment.up.sql":          _002_add_cluster_resource_commentUpSql,
-	"003_add_project_parent_id.down.sql":               _003_add_project_parent_idDownSql,
-	"003_add_project_parent_id.up.sql":                 _003_add_project_parent_idUpSql,
-	"004_fix_domain_uuid_uniqueness.down.sql":          _004_fix_domain_uuid_uniquenessDownSql,
-	"004_fix_domain_uuid_uniqueness.up.sql":            _004_fix_domain_uuid_uniquenessUpSql,
-	"005_add_project_resource_subresources.down.sql":   _005_add_project_resource_subresourcesDownSql,
-	"005_add_project_resource_subresources.up.sql":     _005_add_project_resource_subresourcesUpSql,
-	"006_add_cluster_resources_subcapacities.down.sql": _006_add_cluster_resources_subcapacitiesDownSql,
-	"006_add_cluster_resources_subcapacities.up.sql":   _006_add_cluster_resources_subcapacitiesUpSql,
+	"001_initial.down.sql":                               _001_initialDownSql,
+	"001_initial.up.sql":                                 _001_initialUpSql,
+	"002_add_cluster_resource_comment.down.sql":          _002_add_cluster_resource_commentDownSql,
+	"002_add_cluster_resource_comment.up.sql":            _002_add_cluster_resource_commentUpSql,
+	"003_add_project_parent_id.down.sql":                 _003_add_project_parent_idDownSql,
+	"003_add_project_parent_id.up.sql":                   _003_add_project_parent_idUpSql,
+	"004_fix_domain_uuid_uniqueness.down.sql":            _004_fix_domain_uuid_uniquenessDownSql,
+	"004_fix_domain_uuid_uniqueness.up.sql":              _004_fix_domain_uuid_uniquenessUpSql,
+	"005_add_project_resource_subresources.down.sql":     _005_add_project_resource_subresourcesDownSql,
+	"005_add_project_resource_subresources.up.sql":       _005_add_project_resource_subresourcesUpSql,
+	"006_add_cluster_resources_subcapacities.down.sql":   _006_add_cluster_resources_subcapacitiesDownSql,
+	"006_add_cluster_resources_subcapacities.up.sql":     _006_add_cluster_resources_subcapacitiesUpSql,
+	"007_add_quota_expiry.down.sql":                      _007_add_quota_expiryDownSql,
+	"007_add_quota_expiry.up.sql":                        _007_add_quota_expiryUpSql,
+	"008_add_scheduled_quota_changes.down.sql":           _008_add_scheduled_quota_changesDownSql,
+	"008_add_scheduled_quota_changes.up.sql":             _008_add_scheduled_quota_changesUpSql,
+	"009_add_project_quota_class.down.sql":               _009_add_project_quota_classDownSql,
+	"009_add_project_quota_class.up.sql":                 _009_add_project_quota_classUpSql,
+	"010_add_project_resources_low_usage_since.down.sql": _010_add_project_resources_low_usage_sinceDownSql,
+	"010_add_project_resources_low_usage_since.up.sql":   _010_add_project_resources_low_usage_sinceUpSql,
+	"011_add_project_commitments.down.sql":               _011_add_project_commitmentsDownSql,
+	"011_add_project_commitments.up.sql":                 _011_add_project_commitmentsUpSql,
+	"012_add_quota_locks.down.sql":                       _012_add_quota_locksDownSql,
+	"012_add_quota_locks.up.sql":                         _012_add_quota_locksUpSql,
+	"013_add_quota_comments.down.sql":                    _013_add_quota_commentsDownSql,
+	"013_add_quota_comments.up.sql":                      _013_add_quota_commentsUpSql,
+	"014_add_pending_quota_changes.down.sql":             _014_add_pending_quota_changesDownSql,
+	"014_add_pending_quota_changes.up.sql":               _014_add_pending_quota_changesUpSql,
+	"015_add_quota_change_history.down.sql":              _015_add_quota_change_historyDownSql,
+	"015_add_quota_change_history.up.sql":                _015_add_quota_change_historyUpSql,
+	"016_add_project_resources_reserved.down.sql":        _016_add_project_resources_reservedDownSql,
+	"016_add_project_resources_reserved.up.sql":          _016_add_project_resources_reservedUpSql,
 }
 
 // AssetDir returns the file names below a certain
 // directory embedded in the file by go-bindata.
 // For example if you run go-bindata on data/... and data contains the
 // following hierarchy:
-//     data/
-//       foo.txt
-//       img/
-//         a.png
-//         b.png
+//
+//	data/
+//	  foo.txt
+//	  img/
+//	    a.png
+//	    b.png
+//
 // then AssetDir("data") would return []string{"foo.txt", "img"}
 // AssetDir("data/img") would return []string{"a.png", "b.png"}
 // AssetDir("foo.txt") and AssetDir("notexist") would return an error
@@ -426,18 +868,38 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_initial.down.sql":                             {_001_initialDownSql, map[string]*bintree{}},
-	"001_initial.up.sql":                               {_001_initialUpSql, map[string]*bintree{}},
-	"002_add_cluster_resource_comment.down.sql":        {_002_add_cluster_resource_commentDownSql, map[string]*bintree{}},
-	"002_add_cluster_resource_comment.up.sql":          {_002_add_cluster_resource_commentUpSql, map[string]*bintree{}},
-	"003_add_project_parent_id.down.sql":               {_003_add_project_parent_idDownSql, map[string]*bintree{}},
-	"003_add_project_parent_id.up.sql":                 {_003_add_project_parent_idUpSql, map[string]*bintree{}},
-	"004_fix_domain_uuid_uniqueness.down.sql":          {_004_fix_domain_uuid_uniquenessDownSql, map[string]*bintree{}},
-	"004_fix_domain_uuid_uniqueness.up.sql":            {_004_fix_domain_uuid_uniquenessUpSql, map[string]*bintree{}},
-	"005_add_project_resource_subresources.down.sql":   {_005_add_project_resource_subresourcesDownSql, map[string]*bintree{}},
-	"005_add_project_resource_subresources.up.sql":     {_005_add_project_resource_subresourcesUpSql, map[string]*bintree{}},
-	"006_add_cluster_resources_subcapacities.down.sql": {_006_add_cluster_resources_subcapacitiesDownSql, map[string]*bintree{}},
-	"006_add_cluster_resources_subcapacities.up.sql":   {_006_add_cluster_resources_subcapacitiesUpSql, map[string]*bintree{}},
+	"001_initial.down.sql":                               {_001_initialDownSql, map[string]*bintree{}},
+	"001_initial.up.sql":                                 {_001_initialUpSql, map[string]*bintree{}},
+	"002_add_cluster_resource_comment.down.sql":          {_002_add_cluster_resource_commentDownSql, map[string]*bintree{}},
+	"002_add_cluster_resource_comment.up.sql":            {_002_add_cluster_resource_commentUpSql, map[string]*bintree{}},
+	"003_add_project_parent_id.down.sql":                 {_003_add_project_parent_idDownSql, map[string]*bintree{}},
+	"003_add_project_parent_id.up.sql":                   {_003_add_project_parent_idUpSql, map[string]*bintree{}},
+	"004_fix_domain_uuid_uniqueness.down.sql":            {_004_fix_domain_uuid_uniquenessDownSql, map[string]*bintree{}},
+	"004_fix_domain_uuid_uniqueness.up.sql":              {_004_fix_domain_uuid_uniquenessUpSql, map[string]*bintree{}},
+	"005_add_project_resource_subresources.down.sql":     {_005_add_project_resource_subresourcesDownSql, map[string]*bintree{}},
+	"005_add_project_resource_subresources.up.sql":       {_005_add_project_resource_subresourcesUpSql, map[string]*bintree{}},
+	"006_add_cluster_resources_subcapacities.down.sql":   {_006_add_cluster_resources_subcapacitiesDownSql, map[string]*bintree{}},
+	"006_add_cluster_resources_subcapacities.up.sql":     {_006_add_cluster_resources_subcapacitiesUpSql, map[string]*bintree{}},
+	"007_add_quota_expiry.down.sql":                      {_007_add_quota_expiryDownSql, map[string]*bintree{}},
+	"007_add_quota_expiry.up.sql":                        {_007_add_quota_expiryUpSql, map[string]*bintree{}},
+	"008_add_scheduled_quota_changes.down.sql":           {_008_add_scheduled_quota_changesDownSql, map[string]*bintree{}},
+	"008_add_scheduled_quota_changes.up.sql":             {_008_add_scheduled_quota_changesUpSql, map[string]*bintree{}},
+	"009_add_project_quota_class.down.sql":               {_009_add_project_quota_classDownSql, map[string]*bintree{}},
+	"009_add_project_quota_class.up.sql":                 {_009_add_project_quota_classUpSql, map[string]*bintree{}},
+	"010_add_project_resources_low_usage_since.down.sql": {_010_add_project_resources_low_usage_sinceDownSql, map[string]*bintree{}},
+	"010_add_project_resources_low_usage_since.up.sql":   {_010_add_project_resources_low_usage_sinceUpSql, map[string]*bintree{}},
+	"011_add_project_commitments.down.sql":               {_011_add_project_commitmentsDownSql, map[string]*bintree{}},
+	"011_add_project_commitments.up.sql":                 {_011_add_project_commitmentsUpSql, map[string]*bintree{}},
+	"012_add_quota_locks.down.sql":                       {_012_add_quota_locksDownSql, map[string]*bintree{}},
+	"012_add_quota_locks.up.sql":                         {_012_add_quota_locksUpSql, map[string]*bintree{}},
+	"013_add_quota_comments.down.sql":                    {_013_add_quota_commentsDownSql, map[string]*bintree{}},
+	"013_add_quota_comments.up.sql":                      {_013_add_quota_commentsUpSql, map[string]*bintree{}},
+	"014_add_pending_quota_changes.down.sql":             {_014_add_pending_quota_changesDownSql, map[string]*bintree{}},
+	"014_add_pending_quota_changes.up.sql":               {_014_add_pending_quota_changesUpSql, map[string]*bintree{}},
+	"015_add_quota_change_history.down.sql":              {_015_add_quota_change_historyDownSql, map[string]*bintree{}},
+	"015_add_quota_change_history.up.sql":                {_015_add_quota_change_historyUpSql, map[string]*bintree{}},
+	"016_add_project_resources_reserved.down.sql":        {_016_add_project_resources_reservedDownSql, map[string]*bintree{}},
+	"016_add_project_resources_reserved.up.sql":          {_016_add_project_resources_reservedUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
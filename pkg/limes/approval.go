@@ -0,0 +1,195 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//DefaultApprovalExpiry is used as ApprovalPolicy.ExpireAfter when the
+//configuration does not specify a value.
+const DefaultApprovalExpiry = 72 * time.Hour
+
+//ApprovalPolicy describes which raises of domain quotas and cluster capacities
+//are too large to be made by a single user. Such changes are held as pending
+//changes until a second user confirms them.
+type ApprovalPolicy struct {
+	//          srvType    resName
+	Thresholds map[string]map[string]ApprovalThreshold
+	//Pending changes that are not confirmed within this time expire.
+	ExpireAfter time.Duration
+}
+
+//ApprovalThreshold describes how large a raise of a single quota or capacity
+//value may be before it needs to be confirmed by a second user.
+type ApprovalThreshold struct {
+	//Raises by more than this amount need approval. This value is in the
+	//resource's native unit. Zero means that this limit is not used.
+	Absolute uint64
+	//Raises by more than this fraction of the current value need approval, in
+	//permille. For example, 500 means "current value x 0.5". Zero means that
+	//this limit is not used.
+	RelativePermille uint64
+}
+
+//IsExceededBy returns whether changing a value from oldValue to newValue
+//exceeds this threshold. Changes that lower the value never exceed it.
+func (t ApprovalThreshold) IsExceededBy(oldValue, newValue uint64) bool {
+	if newValue <= oldValue {
+		return false
+	}
+	delta := newValue - oldValue
+	if t.Absolute > 0 && delta > t.Absolute {
+		return true
+	}
+	if t.RelativePermille > 0 && delta*1000 > oldValue*t.RelativePermille {
+		return true
+	}
+	return false
+}
+
+//NeedsApproval returns whether changing the given resource from oldValue to
+//newValue needs to be confirmed by a second user. It is safe to call this on
+//a nil policy.
+func (p *ApprovalPolicy) NeedsApproval(serviceType, resourceName string, oldValue, newValue uint64) bool {
+	if p == nil {
+		return false
+	}
+	threshold, exists := p.Thresholds[serviceType][resourceName]
+	return exists && threshold.IsExceededBy(oldValue, newValue)
+}
+
+//NewApprovalPolicy parses the approval policy from the given section of the
+//cluster configuration.
+func NewApprovalPolicy(cluster *Cluster, cfg ApprovalConfiguration) (*ApprovalPolicy, []error) {
+	result := &ApprovalPolicy{
+		ExpireAfter: DefaultApprovalExpiry,
+	}
+	var errors []error
+
+	if cfg.ExpireAfter != "" {
+		var err error
+		result.ExpireAfter, err = time.ParseDuration(cfg.ExpireAfter)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("invalid value %q for approval.expire_after: %s", cfg.ExpireAfter, err.Error()))
+		} else if result.ExpireAfter <= 0 {
+			errors = append(errors, fmt.Errorf("approval.expire_after must be positive, but is %q", cfg.ExpireAfter))
+		}
+	}
+
+	var thresholdErrors []error
+	result.Thresholds, thresholdErrors = parseApprovalThresholds(cluster, "approval threshold", cfg.Thresholds)
+	return result, append(errors, thresholdErrors...)
+}
+
+//CommentPolicy describes which raises of project and domain quotas need to be
+//justified with a comment.
+type CommentPolicy struct {
+	//          srvType    resName
+	Thresholds map[string]map[string]ApprovalThreshold
+}
+
+//NeedsComment returns whether changing the given resource from oldValue to
+//newValue needs to be justified with a comment. It is safe to call this on a
+//nil policy.
+func (p *CommentPolicy) NeedsComment(serviceType, resourceName string, oldValue, newValue uint64) bool {
+	if p == nil {
+		return false
+	}
+	threshold, exists := p.Thresholds[serviceType][resourceName]
+	return exists && threshold.IsExceededBy(oldValue, newValue)
+}
+
+//HasThreshold returns whether any raise of the given resource may need to be
+//justified with a comment. It is safe to call this on a nil policy.
+func (p *CommentPolicy) HasThreshold(serviceType, resourceName string) bool {
+	if p == nil {
+		return false
+	}
+	_, exists := p.Thresholds[serviceType][resourceName]
+	return exists
+}
+
+//NewCommentPolicy parses the comment policy from the given section of the
+//cluster configuration.
+func NewCommentPolicy(cluster *Cluster, cfg map[string]map[string]ApprovalThresholdConfiguration) (*CommentPolicy, []error) {
+	thresholds, errors := parseApprovalThresholds(cluster, "comment threshold", cfg)
+	return &CommentPolicy{Thresholds: thresholds}, errors
+}
+
+func parseApprovalThresholds(cluster *Cluster, description string, cfg map[string]map[string]ApprovalThresholdConfiguration) (map[string]map[string]ApprovalThreshold, []error) {
+	result := make(map[string]map[string]ApprovalThreshold, len(cfg))
+	var errors []error
+
+	//iterate in a stable order to get reproducible error messages
+	serviceTypes := make([]string, 0, len(cfg))
+	for serviceType := range cfg {
+		serviceTypes = append(serviceTypes, serviceType)
+	}
+	sort.Strings(serviceTypes)
+
+	for _, serviceType := range serviceTypes {
+		if !cluster.HasService(serviceType) {
+			errors = append(errors, fmt.Errorf("invalid %s: no such service: %s", description, serviceType))
+			continue
+		}
+		result[serviceType] = make(map[string]ApprovalThreshold, len(cfg[serviceType]))
+
+		for resourceName, thresholdCfg := range cfg[serviceType] {
+			if !cluster.HasResource(serviceType, resourceName) {
+				errors = append(errors, fmt.Errorf("invalid %s: no such resource: %s/%s", description, serviceType, resourceName))
+				continue
+			}
+			threshold, err := parseApprovalThreshold(cluster.InfoForResource(serviceType, resourceName), thresholdCfg)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("invalid %s for %s/%s: %s", description, serviceType, resourceName, err.Error()))
+				continue
+			}
+			result[serviceType][resourceName] = threshold
+		}
+	}
+
+	return result, errors
+}
+
+func parseApprovalThreshold(resource ResourceInfo, cfg ApprovalThresholdConfiguration) (ApprovalThreshold, error) {
+	var threshold ApprovalThreshold
+
+	if cfg.Absolute != "" {
+		var err error
+		threshold.Absolute, err = resource.Unit.Parse(cfg.Absolute)
+		if err != nil {
+			return ApprovalThreshold{}, fmt.Errorf("invalid value %q for absolute: %s", cfg.Absolute, err.Error())
+		}
+	}
+
+	if cfg.Percent < 0 {
+		return ApprovalThreshold{}, fmt.Errorf("percent may not be negative, but is %g", cfg.Percent)
+	}
+	threshold.RelativePermille = uint64(math.Round(cfg.Percent * 10))
+
+	if threshold.Absolute == 0 && threshold.RelativePermille == 0 {
+		return ApprovalThreshold{}, fmt.Errorf("at least one of absolute and percent must be given")
+	}
+	return threshold, nil
+}
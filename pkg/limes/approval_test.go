@@ -0,0 +1,161 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApprovalPolicyParsing(t *testing.T) {
+	policy, errs := NewApprovalPolicy(clusterForQuotaConstraintTest(), ApprovalConfiguration{
+		ExpireAfter: "24h",
+		Thresholds: map[string]map[string]ApprovalThresholdConfiguration{
+			"service-one": {
+				"capacity_MiB": {Absolute: "10 GiB", Percent: 50},
+				"things":       {Percent: 12.5},
+			},
+		},
+	})
+	if len(errs) > 0 {
+		t.Errorf("expected no parsing errors, got %d errors:\n", len(errs))
+		for idx, err := range errs {
+			t.Logf("[%d] %s\n", idx+1, err.Error())
+		}
+	}
+
+	expected := &ApprovalPolicy{
+		Thresholds: map[string]map[string]ApprovalThreshold{
+			"service-one": {
+				"capacity_MiB": {Absolute: 10240, RelativePermille: 500},
+				"things":       {RelativePermille: 125},
+			},
+		},
+		ExpireAfter: 24 * time.Hour,
+	}
+	if !reflect.DeepEqual(policy, expected) {
+		t.Errorf("expected %#v, got %#v", expected, policy)
+	}
+
+	_, errs = NewApprovalPolicy(clusterForQuotaConstraintTest(), ApprovalConfiguration{
+		ExpireAfter: "soon",
+		Thresholds: map[string]map[string]ApprovalThresholdConfiguration{
+			"service-one": {
+				"capacity_MiB": {Absolute: "10 ounces"},
+				"things":       {Percent: -5},
+				"unknown":      {Percent: 50},
+			},
+			"service-two": {
+				"things": {},
+			},
+			"service-three": {
+				"things": {Percent: 50},
+			},
+		},
+	})
+	expectedErrors := map[string]bool{
+		`invalid value "soon" for approval.expire_after: time: invalid duration "soon"`:                                                                                           true,
+		`invalid approval threshold for service-one/capacity_MiB: invalid value "10 ounces" for absolute: cannot convert value from ounces to MiB because units are incompatible`: true,
+		`invalid approval threshold for service-one/things: percent may not be negative, but is -5`:                                                                               true,
+		`invalid approval threshold: no such resource: service-one/unknown`:                                                                                                       true,
+		`invalid approval threshold for service-two/things: at least one of absolute and percent must be given`:                                                                   true,
+		`invalid approval threshold: no such service: service-three`:                                                                                                              true,
+	}
+	for _, err := range errs {
+		if !expectedErrors[err.Error()] {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+		delete(expectedErrors, err.Error())
+	}
+	for msg := range expectedErrors {
+		t.Errorf("missing expected error: %s", msg)
+	}
+}
+
+func TestApprovalThreshold(t *testing.T) {
+	policy := &ApprovalPolicy{
+		Thresholds: map[string]map[string]ApprovalThreshold{
+			"service-one": {
+				"things": {Absolute: 100, RelativePermille: 500},
+			},
+		},
+	}
+	testCases := []struct {
+		OldValue      uint64
+		NewValue      uint64
+		NeedsApproval bool
+	}{
+		{1000, 1100, false}, //exactly at the absolute threshold
+		{1000, 1101, true},  //above the absolute threshold
+		{100, 150, false},   //exactly at the relative threshold
+		{100, 151, true},    //above the relative threshold
+		{0, 1, true},        //any raise from zero exceeds the relative threshold
+		{1000, 0, false},    //lowering never needs approval
+	}
+	for _, tc := range testCases {
+		actual := policy.NeedsApproval("service-one", "things", tc.OldValue, tc.NewValue)
+		if actual != tc.NeedsApproval {
+			t.Errorf("expected NeedsApproval(%d -> %d) = %t, but got %t", tc.OldValue, tc.NewValue, tc.NeedsApproval, actual)
+		}
+	}
+
+	//resources without a threshold, and clusters without a policy, never need approval
+	if policy.NeedsApproval("service-one", "capacity_MiB", 0, 100000) {
+		t.Error("expected no approval to be needed for resource without threshold")
+	}
+	var nilPolicy *ApprovalPolicy
+	if nilPolicy.NeedsApproval("service-one", "things", 0, 100000) {
+		t.Error("expected no approval to be needed without approval policy")
+	}
+}
+
+func TestCommentPolicy(t *testing.T) {
+	policy, errs := NewCommentPolicy(clusterForQuotaConstraintTest(), map[string]map[string]ApprovalThresholdConfiguration{
+		"service-one": {
+			"things": {Absolute: "10"},
+		},
+	})
+	if len(errs) > 0 {
+		t.Fatalf("expected no parsing errors, got: %v", errs)
+	}
+	if policy.NeedsComment("service-one", "things", 100, 110) {
+		t.Error("expected no comment to be needed for raise within threshold")
+	}
+	if !policy.NeedsComment("service-one", "things", 100, 111) {
+		t.Error("expected comment to be needed for raise above threshold")
+	}
+	if policy.NeedsComment("service-one", "capacity_MiB", 0, 100000) {
+		t.Error("expected no comment to be needed for resource without threshold")
+	}
+
+	//without a policy, comments are never required
+	var nilPolicy *CommentPolicy
+	if nilPolicy.NeedsComment("service-one", "things", 0, 100000) || nilPolicy.HasThreshold("service-one", "things") {
+		t.Error("expected no comment to be needed without comment policy")
+	}
+
+	_, errs = NewCommentPolicy(clusterForQuotaConstraintTest(), map[string]map[string]ApprovalThresholdConfiguration{
+		"service-three": {"things": {Percent: 50}},
+	})
+	if len(errs) != 1 || errs[0].Error() != "invalid comment threshold: no such service: service-three" {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
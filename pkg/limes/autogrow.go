@@ -0,0 +1,131 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+//AutogrowPolicy describes how the quota of a project resource is adjusted
+//automatically based on its usage, without requiring a human to approve each
+//quota change.
+type AutogrowPolicy struct {
+	//The quota is kept at (at least) this multiple of the usage, in permille.
+	//For example, 1200 means "usage x 1.2".
+	UsagePermille uint64
+	//The quota is kept at (at least) the usage plus this amount. This value is
+	//in the resource's native unit.
+	MinHeadroom uint64
+	//If the quota stays above the target value for this long, the quota is
+	//lowered to the target value. Zero means that quotas are never lowered.
+	ShrinkAfter time.Duration
+}
+
+//TargetQuota returns the quota value that this policy wants for the given
+//usage value.
+func (p AutogrowPolicy) TargetQuota(usage uint64) uint64 {
+	//round up to ensure that the requested headroom is always available
+	target := (usage*p.UsagePermille + 999) / 1000
+	if target < usage+p.MinHeadroom {
+		target = usage + p.MinHeadroom
+	}
+	return target
+}
+
+//NewAutogrowPolicies parses the autogrow policies from the given section of
+//the cluster configuration.
+func NewAutogrowPolicies(cluster *Cluster, data map[string]map[string]AutogrowConfiguration) (map[string]map[string]AutogrowPolicy, []error) {
+	result := make(map[string]map[string]AutogrowPolicy, len(data))
+	var errors []error
+
+	//iterate in a stable order to get reproducible error messages
+	serviceTypes := make([]string, 0, len(data))
+	for serviceType := range data {
+		serviceTypes = append(serviceTypes, serviceType)
+	}
+	sort.Strings(serviceTypes)
+
+	for _, serviceType := range serviceTypes {
+		if !cluster.HasService(serviceType) {
+			errors = append(errors, fmt.Errorf("invalid autogrow policy: no such service: %s", serviceType))
+			continue
+		}
+		result[serviceType] = make(map[string]AutogrowPolicy, len(data[serviceType]))
+
+		for resourceName, cfg := range data[serviceType] {
+			if !cluster.HasResource(serviceType, resourceName) {
+				errors = append(errors, fmt.Errorf("invalid autogrow policy: no such resource: %s/%s", serviceType, resourceName))
+				continue
+			}
+			if cluster.InfoForResource(serviceType, resourceName).NoUsage {
+				errors = append(errors, fmt.Errorf("invalid autogrow policy for %s/%s: usage is not reported for this resource", serviceType, resourceName))
+				continue
+			}
+			policy, err := parseAutogrowPolicy(cluster.InfoForResource(serviceType, resourceName), cfg)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("invalid autogrow policy for %s/%s: %s", serviceType, resourceName, err.Error()))
+				continue
+			}
+			result[serviceType][resourceName] = policy
+		}
+	}
+
+	return result, errors
+}
+
+func parseAutogrowPolicy(resource ResourceInfo, cfg AutogrowConfiguration) (AutogrowPolicy, error) {
+	var policy AutogrowPolicy
+
+	switch {
+	case cfg.UsageMultiplier == 0:
+		policy.UsagePermille = 1000
+	case cfg.UsageMultiplier < 1:
+		return AutogrowPolicy{}, fmt.Errorf("usage_multiplier must be at least 1, but is %g", cfg.UsageMultiplier)
+	default:
+		policy.UsagePermille = uint64(math.Round(cfg.UsageMultiplier * 1000))
+	}
+
+	if cfg.MinHeadroom != "" {
+		var err error
+		policy.MinHeadroom, err = resource.Unit.Parse(cfg.MinHeadroom)
+		if err != nil {
+			return AutogrowPolicy{}, fmt.Errorf("invalid value %q for min_headroom: %s", cfg.MinHeadroom, err.Error())
+		}
+	}
+
+	if cfg.ShrinkAfter != "" {
+		var err error
+		policy.ShrinkAfter, err = time.ParseDuration(cfg.ShrinkAfter)
+		if err != nil {
+			return AutogrowPolicy{}, fmt.Errorf("invalid value %q for shrink_after: %s", cfg.ShrinkAfter, err.Error())
+		}
+		if policy.ShrinkAfter <= 0 {
+			return AutogrowPolicy{}, fmt.Errorf("shrink_after must be positive, but is %q", cfg.ShrinkAfter)
+		}
+	}
+
+	if policy.UsagePermille == 1000 && policy.MinHeadroom == 0 {
+		return AutogrowPolicy{}, fmt.Errorf("at least one of usage_multiplier and min_headroom must be given")
+	}
+	return policy, nil
+}
@@ -0,0 +1,95 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutogrowPolicyParsing(t *testing.T) {
+	policies, errs := NewAutogrowPolicies(clusterForQuotaConstraintTest(), map[string]map[string]AutogrowConfiguration{
+		"service-one": {
+			"capacity_MiB": {UsageMultiplier: 1.2, MinHeadroom: "10 GiB", ShrinkAfter: "72h"},
+			"things":       {MinHeadroom: "5"},
+		},
+	})
+	if len(errs) > 0 {
+		t.Errorf("expected no parsing errors, got %d errors:\n", len(errs))
+		for idx, err := range errs {
+			t.Logf("[%d] %s\n", idx+1, err.Error())
+		}
+	}
+
+	expected := map[string]map[string]AutogrowPolicy{
+		"service-one": {
+			"capacity_MiB": {UsagePermille: 1200, MinHeadroom: 10240, ShrinkAfter: 72 * time.Hour},
+			"things":       {UsagePermille: 1000, MinHeadroom: 5},
+		},
+	}
+	if !reflect.DeepEqual(policies, expected) {
+		t.Errorf("expected %#v, got %#v", expected, policies)
+	}
+
+	_, errs = NewAutogrowPolicies(clusterForQuotaConstraintTest(), map[string]map[string]AutogrowConfiguration{
+		"service-one": {
+			"capacity_MiB": {UsageMultiplier: 0.5},
+			"things":       {UsageMultiplier: 1.5, ShrinkAfter: "soon"},
+			"unknown":      {UsageMultiplier: 1.5},
+		},
+		"service-two": {
+			"things": {ShrinkAfter: "1h"},
+		},
+	})
+	expectedErrors := map[string]bool{
+		`invalid autogrow policy for service-one/capacity_MiB: usage_multiplier must be at least 1, but is 0.5`:                true,
+		`invalid autogrow policy for service-one/things: invalid value "soon" for shrink_after: time: invalid duration "soon"`: true,
+		`invalid autogrow policy: no such resource: service-one/unknown`:                                                       true,
+		`invalid autogrow policy for service-two/things: at least one of usage_multiplier and min_headroom must be given`:      true,
+	}
+	for _, err := range errs {
+		if !expectedErrors[err.Error()] {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+		delete(expectedErrors, err.Error())
+	}
+	for msg := range expectedErrors {
+		t.Errorf("missing expected error: %s", msg)
+	}
+}
+
+func TestAutogrowTargetQuota(t *testing.T) {
+	policy := AutogrowPolicy{UsagePermille: 1200, MinHeadroom: 10}
+	testCases := map[uint64]uint64{
+		0:    10,
+		40:   50,
+		50:   60,
+		51:   62, //rounded up from 61.2
+		100:  120,
+		1000: 1200,
+	}
+	for usage, expected := range testCases {
+		actual := policy.TargetQuota(usage)
+		if actual != expected {
+			t.Errorf("expected target quota %d for usage %d, but got %d", expected, usage, actual)
+		}
+	}
+}
@@ -40,6 +40,10 @@ type Cluster struct {
 	CapacityPlugins  map[string]CapacityPlugin
 	Authoritative    bool
 	QuotaConstraints *QuotaConstraintSet
+	QuotaClasses     map[string]QuotaClass
+	AutogrowPolicies map[string]map[string]AutogrowPolicy
+	ApprovalPolicy   *ApprovalPolicy //nil if no approval thresholds are configured
+	CommentPolicy    *CommentPolicy  //nil if no comment thresholds are configured
 }
 
 //NewCluster creates a new Cluster instance with the given ID and
@@ -63,6 +67,9 @@ func NewCluster(id string, config *ClusterConfiguration) *Cluster {
 
 	for _, srv := range config.Services {
 		factory, exists := quotaPluginFactories[srv.Type]
+		if srv.Generic != nil {
+			factory, exists = genericQuotaPluginFactory, genericQuotaPluginFactory != nil
+		}
 		if !exists {
 			util.LogError("skipping service %s: no suitable collector plugin found", srv.Type)
 			continue
@@ -79,6 +86,10 @@ func NewCluster(id string, config *ClusterConfiguration) *Cluster {
 			continue
 		}
 
+		if srv.Generic != nil {
+			registerServiceTypeForArea(plugin.ServiceInfo().Area, srv.Type)
+		}
+
 		c.ServiceTypes = append(c.ServiceTypes, srv.Type)
 		c.QuotaPlugins[srv.Type] = plugin
 		c.IsServiceShared[srv.Type] = srv.Shared
@@ -116,7 +127,8 @@ func NewCluster(id string, config *ClusterConfiguration) *Cluster {
 //that all ProviderClient instances are available. It also calls Init() on all
 //quota plugins.
 //
-//It also loads the QuotaConstraints for thie cluster, if configured.
+//It also loads the QuotaConstraints, QuotaClasses, AutogrowPolicies,
+//ApprovalPolicy and CommentPolicy for thie cluster, if configured.
 func (c *Cluster) Connect() error {
 	if c.Config.ConstraintConfigPath != "" && c.QuotaConstraints == nil {
 		var errs []error
@@ -128,6 +140,46 @@ func (c *Cluster) Connect() error {
 			return fmt.Errorf("cannot load quota constraints for cluster %s (see errors above)", c.ID)
 		}
 	}
+	if len(c.Config.QuotaClasses) > 0 && c.QuotaClasses == nil {
+		var errs []error
+		c.QuotaClasses, errs = NewQuotaClasses(c, c.Config.QuotaClasses)
+		if len(errs) > 0 {
+			for _, err := range errs {
+				util.LogError(err.Error())
+			}
+			return fmt.Errorf("cannot load quota classes for cluster %s (see errors above)", c.ID)
+		}
+	}
+	if len(c.Config.Autogrow) > 0 && c.AutogrowPolicies == nil {
+		var errs []error
+		c.AutogrowPolicies, errs = NewAutogrowPolicies(c, c.Config.Autogrow)
+		if len(errs) > 0 {
+			for _, err := range errs {
+				util.LogError(err.Error())
+			}
+			return fmt.Errorf("cannot load autogrow policies for cluster %s (see errors above)", c.ID)
+		}
+	}
+	if len(c.Config.Approval.Thresholds) > 0 && c.ApprovalPolicy == nil {
+		var errs []error
+		c.ApprovalPolicy, errs = NewApprovalPolicy(c, c.Config.Approval)
+		if len(errs) > 0 {
+			for _, err := range errs {
+				util.LogError(err.Error())
+			}
+			return fmt.Errorf("cannot load approval policy for cluster %s (see errors above)", c.ID)
+		}
+	}
+	if len(c.Config.CommentThresholds) > 0 && c.CommentPolicy == nil {
+		var errs []error
+		c.CommentPolicy, errs = NewCommentPolicy(c, c.Config.CommentThresholds)
+		if len(errs) > 0 {
+			for _, err := range errs {
+				util.LogError(err.Error())
+			}
+			return fmt.Errorf("cannot load comment thresholds for cluster %s (see errors above)", c.ID)
+		}
+	}
 
 	err := c.Config.Auth.Connect()
 	if err != nil {
@@ -63,6 +63,13 @@ type ClusterConfiguration struct {
 	Subcapacities        map[string][]string `yaml:"subcapacities"`
 	Authoritative        bool                `yaml:"authoritative"`
 	ConstraintConfigPath string              `yaml:"constraints"`
+	//              class      srvType    resName
+	QuotaClasses map[string]map[string]map[string]string `yaml:"quota_classes"`
+	//          srvType    resName
+	Autogrow map[string]map[string]AutogrowConfiguration `yaml:"autogrow"`
+	Approval ApprovalConfiguration                       `yaml:"approval"`
+	//                   srvType    resName
+	CommentThresholds map[string]map[string]ApprovalThresholdConfiguration `yaml:"comment_thresholds"`
 	//The following is only read to warn that users need to upgrade from seeds to constraints.
 	OldSeedConfigPath string `yaml:"seeds"`
 }
@@ -98,6 +105,63 @@ type ServiceConfiguration struct {
 			Type    string `yaml:"type"`
 		} `yaml:"hypervisor_type_rules"`
 	} `yaml:"compute"`
+	Network struct {
+		DisableLBaaS bool `yaml:"disable_lbaas"`
+	} `yaml:"network"`
+	ObjectStore struct {
+		ResellerPrefix  string   `yaml:"reseller_prefix"`
+		StoragePolicies []string `yaml:"storage_policies"`
+	} `yaml:"object-store"`
+	//services without a specialized quota plugin can use the generic quota
+	//plugin instead by configuring it here
+	Generic *GenericServiceConfiguration `yaml:"generic"`
+}
+
+//GenericServiceConfiguration contains the configuration for the generic quota
+//plugin, which can be used for services with a "quota-sets" style API. The
+//paths and the update body are templates for the text/template package.
+type GenericServiceConfiguration struct {
+	ProductName  string                         `yaml:"product"`
+	Area         string                         `yaml:"area"`
+	QuotaPath    string                         `yaml:"quota_path"`
+	UpdatePath   string                         `yaml:"update_path"`
+	UpdateMethod string                         `yaml:"update_method"`
+	UpdateBody   string                         `yaml:"update_body"`
+	Resources    []GenericResourceConfiguration `yaml:"resources"`
+}
+
+//GenericResourceConfiguration describes a single resource of a service that
+//uses the generic quota plugin. The fields are given as dot-separated paths
+//into the JSON document returned by GET on the quota path.
+type GenericResourceConfiguration struct {
+	Name       string `yaml:"name"`
+	Unit       Unit   `yaml:"unit"`
+	Category   string `yaml:"category"`
+	QuotaField string `yaml:"quota_field"`
+	UsageField string `yaml:"usage_field"`
+}
+
+//AutogrowConfiguration describes the autogrow policy for a single resource.
+//See type AutogrowPolicy for details.
+type AutogrowConfiguration struct {
+	UsageMultiplier float64 `yaml:"usage_multiplier"`
+	MinHeadroom     string  `yaml:"min_headroom"`
+	ShrinkAfter     string  `yaml:"shrink_after"`
+}
+
+//ApprovalConfiguration describes which quota and capacity changes need to be
+//confirmed by a second user. See type ApprovalPolicy for details.
+type ApprovalConfiguration struct {
+	//          srvType    resName
+	Thresholds  map[string]map[string]ApprovalThresholdConfiguration `yaml:"thresholds"`
+	ExpireAfter string                                               `yaml:"expire_after"`
+}
+
+//ApprovalThresholdConfiguration describes the approval threshold for a single
+//resource. See type ApprovalThreshold for details.
+type ApprovalThresholdConfiguration struct {
+	Absolute string  `yaml:"absolute"`
+	Percent  float64 `yaml:"percent"`
 }
 
 //CapacitorConfiguration describes a capacity plugin that is enabled for a
@@ -0,0 +1,266 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+//constraintFileData is the structure of a constraint file, and also of the
+//merged contents of multiple constraint files.
+type constraintFileData struct {
+	//          dom/proj   srvType    resName
+	Domains  map[string]map[string]map[string]string `yaml:"domains"`
+	Projects map[string]map[string]map[string]string `yaml:"projects"`
+	//                srv/res
+	Ratios map[string]string `yaml:"ratios"`
+}
+
+//constraintKey identifies a key in a constraint file by its path from the root
+//of the document, e.g. {"domains", "Default", "compute", "cores"}. Unused
+//trailing elements are empty.
+type constraintKey [4]string
+
+func makeConstraintKey(keys ...string) (result constraintKey) {
+	copy(result[:], keys)
+	return
+}
+
+//constraintLocation identifies a line in a constraint file.
+type constraintLocation struct {
+	Path string
+	Line int
+}
+
+//String returns the location in the common "path:line" format.
+func (l constraintLocation) String() string {
+	if l.Line == 0 {
+		return l.Path
+	}
+	return fmt.Sprintf("%s:%d", l.Path, l.Line)
+}
+
+//constraintLocations records where each key of the merged constraint set was
+//defined.
+type constraintLocations map[constraintKey]constraintLocation
+
+//Prefix returns a prefix for error messages that names the file and line where
+//the given key was defined, or an empty string if the location is not known.
+func (l constraintLocations) Prefix(keys ...string) string {
+	loc, exists := l[makeConstraintKey(keys...)]
+	if !exists {
+		return ""
+	}
+	return loc.String() + ": "
+}
+
+//findConstraintFiles resolves the value of ClusterConfiguration.ConstraintConfigPath
+//into a list of files. The path may point to a single file, to a directory
+//(in which case all *.yaml and *.yml files therein are used), or it may be a
+//glob pattern.
+func findConstraintFiles(path string) ([]string, error) {
+	var paths []string
+	fi, err := os.Stat(path)
+	switch {
+	case err == nil && fi.IsDir():
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(path, pattern))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, matches...)
+		}
+	case err == nil:
+		return []string{path}, nil
+	case os.IsNotExist(err) && strings.ContainsAny(path, `*?[`):
+		paths, err = filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no constraint files found at %s", path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+//readConstraintFiles reads and merges all the given constraint files. When
+//multiple files define a constraint for the same resource in the same domain
+//or project (or the same ratio constraint), that is reported as a conflict.
+func readConstraintFiles(paths []string) (constraintFileData, constraintLocations, []error) {
+	merged := constraintFileData{
+		Domains:  make(map[string]map[string]map[string]string),
+		Projects: make(map[string]map[string]map[string]string),
+		Ratios:   make(map[string]string),
+	}
+	locations := make(constraintLocations)
+	var errors []error
+
+	for _, path := range paths {
+		buf, err := ioutil.ReadFile(path)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		var data constraintFileData
+		err = yaml.Unmarshal(buf, &data)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("%s: %s", path, err.Error()))
+			continue
+		}
+		fileLocations := scanConstraintFile(path, buf)
+
+		//record locations for everything except the leaf keys (those are
+		//recorded below when they have been checked for conflicts); if multiple
+		//files define the same domain/project/service, the first one wins
+		for key, loc := range fileLocations {
+			isLeaf := key[3] != "" || (key[0] == "ratios" && key[1] != "")
+			if _, exists := locations[key]; !exists && !isLeaf {
+				locations[key] = loc
+			}
+		}
+
+		mergeLeaf := func(newLoc constraintLocation, keys ...string) bool {
+			key := makeConstraintKey(keys...)
+			if oldLoc, exists := locations[key]; exists {
+				var what string
+				switch keys[0] {
+				case "domains":
+					what = fmt.Sprintf("%s/%s in domain %s", keys[2], keys[3], keys[1])
+				case "projects":
+					what = fmt.Sprintf("%s/%s in project %s", keys[2], keys[3], keys[1])
+				default:
+					what = "ratio " + keys[1]
+				}
+				errors = append(errors, fmt.Errorf(
+					"%s: conflicting constraint for %s (already defined at %s)",
+					newLoc.String(), what, oldLoc.String(),
+				))
+				return false
+			}
+			locations[key] = newLoc
+			return true
+		}
+
+		for domainName, domainData := range data.Domains {
+			if _, exists := merged.Domains[domainName]; !exists {
+				merged.Domains[domainName] = make(map[string]map[string]string)
+			}
+			for serviceType, serviceData := range domainData {
+				for resourceName, value := range serviceData {
+					loc := fileLocations.find(path, "domains", domainName, serviceType, resourceName)
+					if mergeLeaf(loc, "domains", domainName, serviceType, resourceName) {
+						setConstraintValue(merged.Domains[domainName], serviceType, resourceName, value)
+					}
+				}
+			}
+		}
+		for projectName, projectData := range data.Projects {
+			if _, exists := merged.Projects[projectName]; !exists {
+				merged.Projects[projectName] = make(map[string]map[string]string)
+			}
+			for serviceType, serviceData := range projectData {
+				for resourceName, value := range serviceData {
+					loc := fileLocations.find(path, "projects", projectName, serviceType, resourceName)
+					if mergeLeaf(loc, "projects", projectName, serviceType, resourceName) {
+						setConstraintValue(merged.Projects[projectName], serviceType, resourceName, value)
+					}
+				}
+			}
+		}
+		for key, value := range data.Ratios {
+			loc := fileLocations.find(path, "ratios", key)
+			if mergeLeaf(loc, "ratios", key) {
+				merged.Ratios[key] = value
+			}
+		}
+	}
+
+	return merged, locations, errors
+}
+
+func setConstraintValue(target map[string]map[string]string, serviceType, resourceName, value string) {
+	if target[serviceType] == nil {
+		target[serviceType] = make(map[string]string)
+	}
+	target[serviceType][resourceName] = value
+}
+
+//find returns the location of the given key in this file, or a location
+//without line number if the key could not be found.
+func (l constraintLocations) find(path string, keys ...string) constraintLocation {
+	if loc, exists := l[makeConstraintKey(keys...)]; exists {
+		return loc
+	}
+	return constraintLocation{Path: path}
+}
+
+//scanConstraintFile finds the line numbers of all keys in a constraint file.
+//The YAML library does not report line numbers for decoded values, so this
+//does a simple scan of the indentation structure, which is sufficient for the
+//nested mappings that make up a constraint file.
+func scanConstraintFile(path string, buf []byte) constraintLocations {
+	type stackEntry struct {
+		Indent int
+		Key    string
+	}
+	var stack []stackEntry
+	result := make(constraintLocations)
+
+	for idx, line := range strings.Split(string(buf), "\n") {
+		content := strings.TrimSpace(line)
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+		colonIdx := strings.Index(content, ":")
+		if colonIdx < 0 {
+			continue
+		}
+		name := strings.Trim(strings.TrimSpace(content[:colonIdx]), `"'`)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		for len(stack) > 0 && stack[len(stack)-1].Indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		stack = append(stack, stackEntry{indent, name})
+		if len(stack) > len(constraintKey{}) {
+			continue
+		}
+
+		var key constraintKey
+		for i, entry := range stack {
+			key[i] = entry.Key
+		}
+		result[key] = constraintLocation{Path: path, Line: idx + 1}
+	}
+
+	return result
+}
@@ -22,11 +22,11 @@ package limes
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"math"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
-
-	yaml "gopkg.in/yaml.v2"
 )
 
 //QuotaConstraintSet contains the contents of the constraint configuration file
@@ -36,6 +36,9 @@ type QuotaConstraintSet struct {
 	Domains map[string]QuotaConstraints
 	//Indexed by domain name, then by project name.
 	Projects map[string]map[string]QuotaConstraints
+	//Applies to all domains and projects. Sorted by service type and resource
+	//name of the constrained resource.
+	Ratios []QuotaRatioConstraint
 }
 
 //QuotaConstraints contains the quota constraints for a single domain or project.
@@ -48,6 +51,71 @@ type QuotaConstraint struct {
 	Minimum  *uint64
 	Maximum  *uint64
 	Expected *uint64 //TODO: remove (undocumented and used only during transition from quota seeds to quota constraints)
+	//Relative bounds refer to live values (usage, domain quota) and must be
+	//resolved into absolute bounds with Evaluate() before use.
+	RelativeMinimum *RelativeQuotaBound
+	RelativeMaximum *RelativeQuotaBound
+}
+
+//RelativeQuotaBase enumerates the live values that a RelativeQuotaBound can
+//refer to.
+type RelativeQuotaBase string
+
+const (
+	//RelativeToUsage refers to the usage of the same resource. For domains,
+	//this is the sum of the usage of all projects in that domain.
+	RelativeToUsage RelativeQuotaBase = "usage"
+	//RelativeToDomainQuota refers to the domain quota for the same resource.
+	//This is only valid in project constraints.
+	RelativeToDomainQuota RelativeQuotaBase = "domain quota"
+)
+
+//RelativeQuotaBound is a bound in a QuotaConstraint whose value is computed
+//from a live value, as `Percent` percent of the base value plus `Offset`.
+//For example, "usage + 10%" has Percent = 110 and Offset = 0, and "2x usage"
+//has Percent = 200 and Offset = 0.
+type RelativeQuotaBound struct {
+	Base    RelativeQuotaBase
+	Percent uint64
+	Offset  uint64
+}
+
+//Evaluate computes the value of this bound from the given live values. When
+//the computation does not yield an integer, lower bounds are rounded up and
+//upper bounds are rounded down.
+func (b RelativeQuotaBound) Evaluate(usage, domainQuota uint64, isLowerBound bool) uint64 {
+	base := usage
+	if b.Base == RelativeToDomainQuota {
+		base = domainQuota
+	}
+	product := float64(base) * float64(b.Percent) / 100
+	if isLowerBound {
+		product = math.Ceil(product)
+	} else {
+		product = math.Floor(product)
+	}
+	return uint64(product) + b.Offset
+}
+
+//ToString returns a compact string representation of this bound. The result
+//is valid input syntax for parseQuotaConstraint(). The argument is the unit
+//for the resource in question.
+func (b RelativeQuotaBound) ToString(unit Unit) string {
+	var str string
+	switch {
+	case b.Percent == 100:
+		str = string(b.Base)
+	case b.Percent%100 == 0:
+		str = fmt.Sprintf("%dx %s", b.Percent/100, b.Base)
+	case b.Percent > 100 && b.Offset == 0:
+		return fmt.Sprintf("%s + %d%%", b.Base, b.Percent-100)
+	default:
+		str = fmt.Sprintf("%d%% of %s", b.Percent, b.Base)
+	}
+	if b.Offset > 0 {
+		str += " + " + ValueWithUnit{b.Offset, unit}.String()
+	}
+	return str
 }
 
 //InitialQuotaValue shall be replaced by direct access to Minimum when Expected is removed. (TODO)
@@ -61,6 +129,58 @@ func (c QuotaConstraint) InitialQuotaValue() uint64 {
 	return 0
 }
 
+//IsRelative returns true if this constraint contains relative bounds.
+func (c QuotaConstraint) IsRelative() bool {
+	return c.RelativeMinimum != nil || c.RelativeMaximum != nil
+}
+
+//Evaluate returns a copy of this constraint where all relative bounds have
+//been resolved into absolute bounds, using the given usage and domain quota
+//of the resource in question. Absolute bounds are authoritative: a relative
+//bound is clamped so that it never contradicts an absolute bound. If two
+//relative bounds contradict each other, the lower bound takes precedence.
+//
+//Allows() and ApplyTo() only look at absolute bounds, so constraints must be
+//passed through Evaluate() before checking quota values against them.
+func (c QuotaConstraint) Evaluate(usage, domainQuota uint64) QuotaConstraint {
+	if !c.IsRelative() {
+		return c
+	}
+	pointerTo := func(x uint64) *uint64 { return &x }
+
+	result := QuotaConstraint{Expected: c.Expected}
+	if c.Minimum != nil {
+		result.Minimum = pointerTo(*c.Minimum)
+	}
+	if c.Maximum != nil {
+		result.Maximum = pointerTo(*c.Maximum)
+	}
+
+	if c.RelativeMinimum != nil {
+		val := c.RelativeMinimum.Evaluate(usage, domainQuota, true)
+		if c.Maximum != nil && val > *c.Maximum {
+			val = *c.Maximum
+		}
+		if result.Minimum == nil || *result.Minimum < val {
+			result.Minimum = pointerTo(val)
+		}
+	}
+	if c.RelativeMaximum != nil {
+		val := c.RelativeMaximum.Evaluate(usage, domainQuota, false)
+		if c.Minimum != nil && val < *c.Minimum {
+			val = *c.Minimum
+		}
+		if result.Minimum != nil && val < *result.Minimum {
+			val = *result.Minimum
+		}
+		if result.Maximum == nil || *result.Maximum > val {
+			result.Maximum = pointerTo(val)
+		}
+	}
+
+	return result
+}
+
 //Allows checks whether the given quota value satisfies this constraint.
 func (c QuotaConstraint) Allows(value uint64) bool {
 	return (c.Minimum == nil || *c.Minimum <= value) && (c.Maximum == nil || *c.Maximum >= value)
@@ -93,34 +213,153 @@ func (c QuotaConstraint) ToString(unit Unit) string {
 			parts = append(parts, "at least "+ValueWithUnit{*c.Minimum, unit}.String())
 		}
 	}
+	if c.RelativeMinimum != nil {
+		parts = append(parts, "at least "+c.RelativeMinimum.ToString(unit))
+	}
 	if c.Maximum != nil && !hasExactly {
 		parts = append(parts, "at most "+ValueWithUnit{*c.Maximum, unit}.String())
 	}
+	if c.RelativeMaximum != nil {
+		parts = append(parts, "at most "+c.RelativeMaximum.ToString(unit))
+	}
 	if c.Expected != nil {
 		parts = append(parts, "should be "+ValueWithUnit{*c.Maximum, unit}.String())
 	}
 	return strings.Join(parts, ", ")
 }
 
+//QuotaRatioConstraint is a constraint that bounds the quota of one resource
+//by a linear function of the quota of another resource in the same domain or
+//project, e.g. "compute/ram: at most 8 GiB per compute/cores".
+type QuotaRatioConstraint struct {
+	ServiceType     string
+	ResourceName    string
+	RefServiceType  string
+	RefResourceName string
+	IsUpperBound    bool
+	//The bound is computed as `Factor * refQuota + Offset`, in the base units
+	//of the respective resources.
+	Factor float64
+	Offset uint64
+}
+
+//Bound computes the bound for the constrained resource's quota, given the
+//quota of the reference resource.
+func (r QuotaRatioConstraint) Bound(refQuota uint64) uint64 {
+	product := r.Factor * float64(refQuota)
+	if r.IsUpperBound {
+		product = math.Floor(product)
+	} else {
+		product = math.Ceil(product)
+	}
+	//saturate instead of overflowing
+	if product >= math.MaxUint64 || uint64(product) > math.MaxUint64-r.Offset {
+		return math.MaxUint64
+	}
+	return uint64(product) + r.Offset
+}
+
+//Allows checks whether the given quota values for the constrained resource
+//and the reference resource satisfy this constraint.
+func (r QuotaRatioConstraint) Allows(quota, refQuota uint64) bool {
+	if r.IsUpperBound {
+		return quota <= r.Bound(refQuota)
+	}
+	return quota >= r.Bound(refQuota)
+}
+
+//RefBound computes the bound for the reference resource's quota that this
+//constraint implies, given the quota of the constrained resource. For upper
+//bounds, the result is a lower bound for the reference quota, and vice versa.
+//If no reference quota satisfies the constraint, ok is false. The Factor must
+//be finite and positive (as ensured by parseQuotaRatioConstraints).
+func (r QuotaRatioConstraint) RefBound(quota uint64) (bound uint64, ok bool) {
+	if r.IsUpperBound {
+		//need refQuota >= (quota - Offset) / Factor
+		if quota <= r.Offset {
+			return 0, true
+		}
+		if r.Factor <= 0 {
+			return 0, false
+		}
+		quotient := math.Ceil(float64(quota-r.Offset) / r.Factor)
+		if quotient >= math.MaxUint64 {
+			return 0, false
+		}
+		bound = uint64(quotient)
+		//correct rounding errors in the floating-point division
+		for bound > 0 && r.Allows(quota, bound-1) {
+			bound--
+		}
+		for !r.Allows(quota, bound) {
+			bound++
+		}
+		return bound, true
+	}
+
+	//need refQuota <= (quota - Offset) / Factor
+	if quota < r.Offset {
+		return 0, false
+	}
+	if r.Factor <= 0 {
+		return math.MaxUint64, true
+	}
+	quotient := math.Floor(float64(quota-r.Offset) / r.Factor)
+	if quotient >= math.MaxUint64 {
+		return math.MaxUint64, true
+	}
+	bound = uint64(quotient)
+	//correct rounding errors in the floating-point division
+	for bound < math.MaxUint64 && r.Allows(quota, bound+1) {
+		bound++
+	}
+	for !r.Allows(quota, bound) {
+		if bound == 0 {
+			return 0, false
+		}
+		bound--
+	}
+	return bound, true
+}
+
+//ToString returns a compact string representation of this constraint,
+//including the name of the constrained resource. The argument is the unit for
+//the constrained resource.
+func (r QuotaRatioConstraint) ToString(unit Unit) string {
+	operator := "at least"
+	if r.IsUpperBound {
+		operator = "at most"
+	}
+	var factor string
+	if unit != UnitNone && r.Factor == math.Trunc(r.Factor) {
+		factor = ValueWithUnit{uint64(r.Factor), unit}.String() + " per"
+	} else {
+		factor = strconv.FormatFloat(r.Factor, 'f', -1, 64) + "x"
+	}
+	str := fmt.Sprintf("%s/%s: %s %s %s/%s", r.ServiceType, r.ResourceName, operator, factor, r.RefServiceType, r.RefResourceName)
+	if r.Offset > 0 {
+		str += " + " + ValueWithUnit{r.Offset, unit}.String()
+	}
+	return str
+}
+
 //NewQuotaConstraints parses the quota constraints at `constraintConfigPath`.
+//This path may point to a single file, to a directory containing multiple
+//files, or it may be a glob pattern matching multiple files. Multiple files
+//are merged into one constraint set.
+//
 //The `cluster` argument is required because quota values need to be converted
 //into the base unit of their resource, for which we need to access the
 //QuotaPlugin.Resources(). Hence, `cluster.Init()` needs to have been called
 //before this function is called.
 func NewQuotaConstraints(cluster *Cluster, constraintConfigPath string) (*QuotaConstraintSet, []error) {
-	buf, err := ioutil.ReadFile(constraintConfigPath)
+	paths, err := findConstraintFiles(constraintConfigPath)
 	if err != nil {
 		return nil, []error{err}
 	}
-
-	var data struct {
-		//          dom/proj   srvType    resName
-		Domains  map[string]map[string]map[string]string `yaml:"domains"`
-		Projects map[string]map[string]map[string]string `yaml:"projects"`
-	}
-	err = yaml.Unmarshal(buf, &data)
-	if err != nil {
-		return nil, []error{err}
+	data, locations, errs := readConstraintFiles(paths)
+	if len(errs) > 0 {
+		return nil, errs
 	}
 
 	result := &QuotaConstraintSet{
@@ -131,10 +370,13 @@ func NewQuotaConstraints(cluster *Cluster, constraintConfigPath string) (*QuotaC
 
 	//parse quota constraints for domains
 	for domainName, domainData := range data.Domains {
-		values, errs := compileQuotaConstraints(cluster, domainData)
+		locate := func(keys ...string) string {
+			return locations.Prefix(append([]string{"domains", domainName}, keys...)...)
+		}
+		values, errs := compileQuotaConstraints(cluster, domainData, false, locate)
 		for _, err := range errs {
 			errors = append(errors,
-				fmt.Errorf("invalid constraints for domain %s: %s", domainName, err.Error()),
+				fmt.Errorf("%sinvalid constraints for domain %s: %s", err.Location, domainName, err.Error()),
 			)
 		}
 		result.Domains[domainName] = values
@@ -142,20 +384,23 @@ func NewQuotaConstraints(cluster *Cluster, constraintConfigPath string) (*QuotaC
 
 	//parse quota constraints for projects
 	for projectAndDomainName, projectData := range data.Projects {
+		locate := func(keys ...string) string {
+			return locations.Prefix(append([]string{"projects", projectAndDomainName}, keys...)...)
+		}
 		fields := strings.SplitN(projectAndDomainName, "/", 2)
 		if len(fields) < 2 {
 			errors = append(errors,
-				fmt.Errorf("missing domain name for project %s", projectAndDomainName),
+				fmt.Errorf("%smissing domain name for project %s", locate(), projectAndDomainName),
 			)
 			continue
 		}
 		domainName := fields[0]
 		projectName := fields[1]
 
-		values, errs := compileQuotaConstraints(cluster, projectData)
+		values, errs := compileQuotaConstraints(cluster, projectData, true, locate)
 		for _, err := range errs {
 			errors = append(errors,
-				fmt.Errorf("invalid constraints for project %s: %s", projectAndDomainName, err.Error()),
+				fmt.Errorf("%sinvalid constraints for project %s: %s", err.Location, projectAndDomainName, err.Error()),
 			)
 		}
 
@@ -165,6 +410,23 @@ func NewQuotaConstraints(cluster *Cluster, constraintConfigPath string) (*QuotaC
 		result.Projects[domainName][projectName] = values
 	}
 
+	//parse ratio constraints (sorted for deterministic order)
+	ratioKeys := make([]string, 0, len(data.Ratios))
+	for key := range data.Ratios {
+		ratioKeys = append(ratioKeys, key)
+	}
+	sort.Strings(ratioKeys)
+	for _, key := range ratioKeys {
+		ratios, err := parseQuotaRatioConstraints(cluster, key, data.Ratios[key])
+		if err != nil {
+			errors = append(errors,
+				fmt.Errorf("%sinvalid ratio constraint %q for %s: %s", locations.Prefix("ratios", key), data.Ratios[key], key, err.Error()),
+			)
+			continue
+		}
+		result.Ratios = append(result.Ratios, ratios...)
+	}
+
 	//do not attempt to validate if the parsing already caused errors (a
 	//consistent, but invalid constraint set might look inconsistent because
 	//values that don't parse were not initialized in `result`)
@@ -181,23 +443,69 @@ func NewQuotaConstraints(cluster *Cluster, constraintConfigPath string) (*QuotaC
 		allDomainNames[domainName] = true
 	}
 	for domainName := range allDomainNames {
-		errs := validateQuotaConstraints(cluster, result.Domains[domainName], result.Projects[domainName])
+		locate := func(serviceType, resourceName string) string {
+			if prefix := locations.Prefix("domains", domainName, serviceType, resourceName); prefix != "" {
+				return prefix
+			}
+			//if there is no domain constraint for this resource, point to one of
+			//the project constraints that make up the sum instead
+			projectNames := make([]string, 0, len(result.Projects[domainName]))
+			for projectName := range result.Projects[domainName] {
+				projectNames = append(projectNames, projectName)
+			}
+			sort.Strings(projectNames)
+			for _, projectName := range projectNames {
+				if prefix := locations.Prefix("projects", domainName+"/"+projectName, serviceType, resourceName); prefix != "" {
+					return prefix
+				}
+			}
+			return locations.Prefix("domains", domainName)
+		}
+		errs := validateQuotaConstraints(cluster, result.Domains[domainName], result.Projects[domainName], locate)
 		for _, err := range errs {
 			errors = append(errors,
-				fmt.Errorf("inconsistent constraints for domain %s: %s", domainName, err.Error()),
+				fmt.Errorf("%sinconsistent constraints for domain %s: %s", err.Location, domainName, err.Error()),
+			)
+		}
+	}
+
+	//validate that the constraints for each domain and project are compatible
+	//with the ratio constraints
+	for domainName, domainConstraints := range result.Domains {
+		for _, err := range validateQuotaRatios(cluster, result.Ratios, domainConstraints) {
+			errors = append(errors,
+				fmt.Errorf("%sinconsistent constraints for domain %s: %s", locations.Prefix("domains", domainName), domainName, err.Error()),
 			)
 		}
 	}
+	for domainName, projects := range result.Projects {
+		for projectName, projectConstraints := range projects {
+			projectAndDomainName := domainName + "/" + projectName
+			for _, err := range validateQuotaRatios(cluster, result.Ratios, projectConstraints) {
+				errors = append(errors,
+					fmt.Errorf("%sinconsistent constraints for project %s: %s", locations.Prefix("projects", projectAndDomainName), projectAndDomainName, err.Error()),
+				)
+			}
+		}
+	}
 
 	return result, errors
 }
 
-func compileQuotaConstraints(cluster *Cluster, data map[string]map[string]string) (values QuotaConstraints, errors []error) {
+//compileError is an error returned by compileQuotaConstraints and
+//validateQuotaConstraints. Location is
+//either empty or a "path:line: " prefix for the error message.
+type compileError struct {
+	error
+	Location string
+}
+
+func compileQuotaConstraints(cluster *Cluster, data map[string]map[string]string, isProject bool, locate func(keys ...string) string) (values QuotaConstraints, errors []compileError) {
 	values = make(QuotaConstraints)
 
 	for serviceType, serviceData := range data {
 		if !cluster.HasService(serviceType) {
-			errors = append(errors, fmt.Errorf("no such service: %s", serviceType))
+			errors = append(errors, compileError{fmt.Errorf("no such service: %s", serviceType), locate(serviceType)})
 			continue
 		}
 		values[serviceType] = make(map[string]QuotaConstraint)
@@ -205,8 +513,14 @@ func compileQuotaConstraints(cluster *Cluster, data map[string]map[string]string
 		for resourceName, constraintStr := range serviceData {
 			resource := cluster.InfoForResource(serviceType, resourceName)
 			constraint, err := parseQuotaConstraint(resource, constraintStr)
+			if err == nil && !isProject && constraint.refersToDomainQuota() {
+				err = fmt.Errorf("domain constraints cannot refer to the %s", RelativeToDomainQuota)
+			}
 			if err != nil {
-				errors = append(errors, fmt.Errorf("invalid constraint %q for %s/%s: %s", constraintStr, serviceType, resourceName, err.Error()))
+				errors = append(errors, compileError{
+					fmt.Errorf("invalid constraint %q for %s/%s: %s", constraintStr, serviceType, resourceName, err.Error()),
+					locate(serviceType, resourceName),
+				})
 				continue
 			}
 			values[serviceType][resourceName] = *constraint
@@ -221,26 +535,52 @@ var atMostRx = regexp.MustCompile(`^at\s+most\s+(.+)$`)
 var exactlyRx = regexp.MustCompile(`^exactly\s+(.+)$`)
 var shouldBeRx = regexp.MustCompile(`^should\s+be\s+(.+)$`)
 
+//matches relative values like "usage", "2x usage", "20% of domain quota",
+//"usage + 10%" or "usage + 10 GiB"
+var relativeValueRx = regexp.MustCompile(`^(?:([0-9.]+)\s*x\s+|([0-9]+)\s*%\s+of\s+)?(usage|domain\s+quota)(?:\s*\+\s*(.+))?$`)
+var percentRx = regexp.MustCompile(`^([0-9]+)\s*%$`)
+
 func parseQuotaConstraint(resource ResourceInfo, str string) (*QuotaConstraint, error) {
 	var lowerBounds []uint64
 	var upperBounds []uint64
 	var expected []uint64
+	var relativeLowerBounds []RelativeQuotaBound
+	var relativeUpperBounds []RelativeQuotaBound
 
 	for _, part := range strings.Split(str, ",") {
 		part = strings.TrimSpace(part)
 		if match := atLeastRx.FindStringSubmatch(part); match != nil {
+			if relMatch := relativeValueRx.FindStringSubmatch(match[1]); relMatch != nil {
+				bound, err := parseRelativeQuotaBound(resource, relMatch)
+				if err != nil {
+					return nil, err
+				}
+				relativeLowerBounds = append(relativeLowerBounds, bound)
+				continue
+			}
 			value, err := resource.Unit.Parse(match[1])
 			if err != nil {
 				return nil, err
 			}
 			lowerBounds = append(lowerBounds, value)
 		} else if match := atMostRx.FindStringSubmatch(part); match != nil {
+			if relMatch := relativeValueRx.FindStringSubmatch(match[1]); relMatch != nil {
+				bound, err := parseRelativeQuotaBound(resource, relMatch)
+				if err != nil {
+					return nil, err
+				}
+				relativeUpperBounds = append(relativeUpperBounds, bound)
+				continue
+			}
 			value, err := resource.Unit.Parse(match[1])
 			if err != nil {
 				return nil, err
 			}
 			upperBounds = append(upperBounds, value)
 		} else if match := exactlyRx.FindStringSubmatch(part); match != nil {
+			if relativeValueRx.MatchString(match[1]) {
+				return nil, fmt.Errorf(`clause %q: relative values are only allowed in "at least" and "at most" clauses`, part)
+			}
 			value, err := resource.Unit.Parse(match[1])
 			if err != nil {
 				return nil, err
@@ -290,10 +630,82 @@ func parseQuotaConstraint(resource ResourceInfo, str string) (*QuotaConstraint,
 		return nil, errors.New(`cannot have multiple "should be" clauses in one constraint`)
 	}
 
+	//relative bounds cannot be merged with each other without knowing the live
+	//values, so only one of each kind is allowed
+	switch len(relativeLowerBounds) {
+	case 0:
+		result.RelativeMinimum = nil
+	case 1:
+		result.RelativeMinimum = &relativeLowerBounds[0]
+	default:
+		return nil, errors.New(`cannot have multiple relative "at least" clauses in one constraint`)
+	}
+	switch len(relativeUpperBounds) {
+	case 0:
+		result.RelativeMaximum = nil
+	case 1:
+		result.RelativeMaximum = &relativeUpperBounds[0]
+	default:
+		return nil, errors.New(`cannot have multiple relative "at most" clauses in one constraint`)
+	}
+
 	return &result, nil
 }
 
-func validateQuotaConstraints(cluster *Cluster, domainConstraints QuotaConstraints, projectsConstraints map[string]QuotaConstraints) (errors []error) {
+//parseRelativeQuotaBound parses a match of relativeValueRx.
+func parseRelativeQuotaBound(resource ResourceInfo, match []string) (RelativeQuotaBound, error) {
+	bound := RelativeQuotaBound{
+		Base:    RelativeToUsage,
+		Percent: 100,
+	}
+	if strings.HasPrefix(match[3], "domain") {
+		bound.Base = RelativeToDomainQuota
+	}
+
+	hasFactor := match[1] != "" || match[2] != ""
+	if match[1] != "" {
+		factor, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			return bound, err
+		}
+		bound.Percent = uint64(math.Round(factor * 100))
+	}
+	if match[2] != "" {
+		percent, err := strconv.ParseUint(match[2], 10, 64)
+		if err != nil {
+			return bound, err
+		}
+		bound.Percent = percent
+	}
+
+	if match[4] != "" {
+		if percentMatch := percentRx.FindStringSubmatch(match[4]); percentMatch != nil {
+			if hasFactor {
+				return bound, errors.New("cannot combine a factor with a percentage increment")
+			}
+			percent, err := strconv.ParseUint(percentMatch[1], 10, 64)
+			if err != nil {
+				return bound, err
+			}
+			bound.Percent += percent
+		} else {
+			value, err := resource.Unit.Parse(match[4])
+			if err != nil {
+				return bound, err
+			}
+			bound.Offset = value
+		}
+	}
+
+	return bound, nil
+}
+
+func (c QuotaConstraint) refersToDomainQuota() bool {
+	return (c.RelativeMinimum != nil && c.RelativeMinimum.Base == RelativeToDomainQuota) ||
+		(c.RelativeMaximum != nil && c.RelativeMaximum.Base == RelativeToDomainQuota)
+}
+
+func validateQuotaConstraints(cluster *Cluster, domainConstraints QuotaConstraints, projectsConstraints map[string]QuotaConstraints, locate func(serviceType, resourceName string) string) (errors []compileError) {
 	//sum up the constraints of all projects into total min/max quotas
 	sumConstraints := make(QuotaConstraints)
 	for _, projectConstraints := range projectsConstraints {
@@ -336,15 +748,115 @@ func validateQuotaConstraints(cluster *Cluster, domainConstraints QuotaConstrain
 
 			if minProjectQuota > minDomainQuota {
 				unit := cluster.InfoForResource(serviceType, resourceName).Unit
-				errors = append(errors, fmt.Errorf(
+				err := fmt.Errorf(
 					`sum of "at least/exactly" project quotas (%s) for %s/%s exceeds "at least/exactly" domain quota (%s)`,
 					ValueWithUnit{minProjectQuota, unit},
 					serviceType, resourceName,
 					ValueWithUnit{minDomainQuota, unit},
-				))
+				)
+				errors = append(errors, compileError{err, locate(serviceType, resourceName)})
+			}
+		}
+	}
+
+	return
+}
+
+var ratioClauseRx = regexp.MustCompile(`^at\s+(least|most)\s+(.+?)\s*(x|per)\s+([^\s/]+)/([^\s+]+)(?:\s*\+\s*(.+))?$`)
+
+func parseQuotaRatioConstraints(cluster *Cluster, key, str string) ([]QuotaRatioConstraint, error) {
+	fields := strings.SplitN(key, "/", 2)
+	if len(fields) < 2 {
+		return nil, errors.New(`expected key in the form "service/resource"`)
+	}
+	serviceType, resourceName := fields[0], fields[1]
+	if !cluster.HasResource(serviceType, resourceName) {
+		return nil, fmt.Errorf("no such resource: %s/%s", serviceType, resourceName)
+	}
+	resource := cluster.InfoForResource(serviceType, resourceName)
+
+	var result []QuotaRatioConstraint
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		match := ratioClauseRx.FindStringSubmatch(part)
+		if match == nil {
+			return nil, fmt.Errorf(`clause %q should look like "at least/most <value> per <service>/<resource>" or "at least/most <factor>x <service>/<resource>"`, part)
+		}
+
+		ratio := QuotaRatioConstraint{
+			ServiceType:     serviceType,
+			ResourceName:    resourceName,
+			RefServiceType:  match[4],
+			RefResourceName: match[5],
+			IsUpperBound:    match[1] == "most",
+		}
+		if !cluster.HasResource(ratio.RefServiceType, ratio.RefResourceName) {
+			return nil, fmt.Errorf("no such resource: %s/%s", ratio.RefServiceType, ratio.RefResourceName)
+		}
+		if ratio.RefServiceType == serviceType && ratio.RefResourceName == resourceName {
+			return nil, errors.New("resource cannot be constrained relative to itself")
+		}
+
+		if match[3] == "x" {
+			factor, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				return nil, err
 			}
+			ratio.Factor = factor
+		} else {
+			value, err := resource.Unit.Parse(match[2])
+			if err != nil {
+				return nil, err
+			}
+			ratio.Factor = float64(value)
+		}
+		if math.IsNaN(ratio.Factor) || math.IsInf(ratio.Factor, 0) || ratio.Factor <= 0 {
+			return nil, fmt.Errorf("clause %q: factor must be a positive number", part)
 		}
+
+		if match[6] != "" {
+			value, err := resource.Unit.Parse(match[6])
+			if err != nil {
+				return nil, err
+			}
+			ratio.Offset = value
+		}
+
+		result = append(result, ratio)
 	}
 
+	return result, nil
+}
+
+func validateQuotaRatios(cluster *Cluster, ratios []QuotaRatioConstraint, constraints QuotaConstraints) (errors []error) {
+	for _, ratio := range ratios {
+		constraint := constraints[ratio.ServiceType][ratio.ResourceName]
+		refConstraint := constraints[ratio.RefServiceType][ratio.RefResourceName]
+		unit := cluster.InfoForResource(ratio.ServiceType, ratio.ResourceName).Unit
+		refUnit := cluster.InfoForResource(ratio.RefServiceType, ratio.RefResourceName).Unit
+
+		//the smallest allowed quota must fit below the largest possible upper
+		//bound, and vice versa
+		if ratio.IsUpperBound && constraint.Minimum != nil && refConstraint.Maximum != nil {
+			if !ratio.Allows(*constraint.Minimum, *refConstraint.Maximum) {
+				errors = append(errors, fmt.Errorf(
+					`"at least/exactly" quota for %s/%s (%s) and "at most/exactly" quota for %s/%s (%s) contradict ratio constraint %q`,
+					ratio.ServiceType, ratio.ResourceName, ValueWithUnit{*constraint.Minimum, unit},
+					ratio.RefServiceType, ratio.RefResourceName, ValueWithUnit{*refConstraint.Maximum, refUnit},
+					ratio.ToString(unit),
+				))
+			}
+		}
+		if !ratio.IsUpperBound && constraint.Maximum != nil && refConstraint.Minimum != nil {
+			if !ratio.Allows(*constraint.Maximum, *refConstraint.Minimum) {
+				errors = append(errors, fmt.Errorf(
+					`"at most/exactly" quota for %s/%s (%s) and "at least/exactly" quota for %s/%s (%s) contradict ratio constraint %q`,
+					ratio.ServiceType, ratio.ResourceName, ValueWithUnit{*constraint.Maximum, unit},
+					ratio.RefServiceType, ratio.RefResourceName, ValueWithUnit{*refConstraint.Minimum, refUnit},
+					ratio.ToString(unit),
+				))
+			}
+		}
+	}
 	return
 }
@@ -20,6 +20,7 @@
 package limes
 
 import (
+	"math"
 	"reflect"
 	"testing"
 
@@ -50,6 +51,9 @@ func TestQuotaConstraintParsingSuccess(t *testing.T) {
 				},
 			},
 			"poland": {
+				"service-one": {
+					"capacity_MiB": {RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 100, Offset: 1024}},
+				},
 				"service-two": {
 					"things": {Minimum: pointerTo(5)},
 				},
@@ -66,6 +70,10 @@ func TestQuotaConstraintParsingSuccess(t *testing.T) {
 				"dresden": {
 					"service-one": {
 						"things": {Minimum: pointerTo(5), Maximum: pointerTo(5)},
+						"capacity_MiB": {
+							RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 200},
+							RelativeMaximum: &RelativeQuotaBound{Base: RelativeToDomainQuota, Percent: 50},
+						},
 					},
 					"service-two": {
 						"capacity_MiB": {Minimum: pointerTo(1), Maximum: pointerTo(1)},
@@ -75,11 +83,33 @@ func TestQuotaConstraintParsingSuccess(t *testing.T) {
 			"poland": {
 				"warsaw": {
 					"service-two": {
-						"things": {Expected: pointerTo(5), Maximum: pointerTo(10)},
+						"things": {
+							Expected:        pointerTo(5),
+							Maximum:         pointerTo(10),
+							RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 110},
+						},
 					},
 				},
 			},
 		},
+		Ratios: []QuotaRatioConstraint{
+			{
+				ServiceType:     "service-one",
+				ResourceName:    "capacity_MiB",
+				RefServiceType:  "service-one",
+				RefResourceName: "things",
+				IsUpperBound:    true,
+				Factor:          8192,
+			},
+			{
+				ServiceType:     "service-one",
+				ResourceName:    "capacity_MiB",
+				RefServiceType:  "service-two",
+				RefResourceName: "capacity_MiB",
+				IsUpperBound:    false,
+				Factor:          0.5,
+			},
+		},
 	}
 	if !reflect.DeepEqual(constraints, &expected) {
 		t.Errorf("actual = %#v\n", constraints)
@@ -87,6 +117,62 @@ func TestQuotaConstraintParsingSuccess(t *testing.T) {
 	}
 }
 
+func TestQuotaConstraintParsingFromMultipleFiles(t *testing.T) {
+	pointerTo := func(x uint64) *uint64 { return &x }
+
+	expected := QuotaConstraintSet{
+		Domains: map[string]QuotaConstraints{
+			"germany": {
+				"service-one": {
+					"things": {Minimum: pointerTo(20)},
+				},
+				"service-two": {
+					"capacity_MiB": {Minimum: pointerTo(1)},
+				},
+			},
+			"poland": {
+				"service-two": {
+					"things": {Minimum: pointerTo(5)},
+				},
+			},
+		},
+		Projects: map[string]map[string]QuotaConstraints{
+			"germany": {
+				"berlin": {
+					"service-one": {
+						"things": {Minimum: pointerTo(10)},
+					},
+				},
+			},
+		},
+	}
+
+	//a directory and a glob matching the same files should yield the same result
+	for _, path := range []string{"fixtures/quota-constraint-split", "fixtures/quota-constraint-split/*.y*ml"} {
+		constraints, errs := NewQuotaConstraints(clusterForQuotaConstraintTest(), path)
+		for _, err := range errs {
+			t.Errorf("expected no parsing errors for %s, got: %s", path, err.Error())
+		}
+		if !reflect.DeepEqual(constraints, &expected) {
+			t.Errorf("actual = %#v\n", constraints)
+			t.Errorf("expected = %#v\n", expected)
+		}
+	}
+
+	expectQuotaConstraintInvalid(t, "fixtures/quota-constraint-conflict",
+		`fixtures/quota-constraint-conflict/second.yaml:6: conflicting constraint for service-one/things in domain germany (already defined at fixtures/quota-constraint-conflict/first.yaml:4)`,
+		`fixtures/quota-constraint-conflict/second.yaml:12: conflicting constraint for service-one/things in project germany/berlin (already defined at fixtures/quota-constraint-conflict/first.yaml:9)`,
+		`fixtures/quota-constraint-conflict/second.yaml:15: conflicting constraint for ratio service-one/capacity_MiB (already defined at fixtures/quota-constraint-conflict/first.yaml:12)`,
+	)
+	expectQuotaConstraintInvalid(t, "fixtures/quota-constraint-split-inconsistent",
+		`fixtures/quota-constraint-split-inconsistent/domains.yaml:4: inconsistent constraints for domain germany: sum of "at least/exactly" project quotas (25) for service-one/things exceeds "at least/exactly" domain quota (20)`,
+		`fixtures/quota-constraint-split-inconsistent/projects.yaml:10: inconsistent constraints for domain poland: sum of "at least/exactly" project quotas (5) for service-two/things exceeds "at least/exactly" domain quota (0)`,
+	)
+	expectQuotaConstraintInvalid(t, "fixtures/does-not-exist-*.yaml",
+		`no constraint files found at fixtures/does-not-exist-*.yaml`,
+	)
+}
+
 func clusterForQuotaConstraintTest() *Cluster {
 	return &Cluster{
 		QuotaPlugins: map[string]QuotaPlugin{
@@ -99,19 +185,30 @@ func clusterForQuotaConstraintTest() *Cluster {
 func TestQuotaConstraintParsingFailure(t *testing.T) {
 	expectQuotaConstraintInvalid(t, "fixtures/quota-constraint-invalid.yaml",
 		//ordered by appearance in fixture file
-		`invalid constraints for domain germany: invalid constraint "not more than 20" for service-one/things: clause "not more than 20" should start with "at least", "at most" or "exactly"`,
-		`invalid constraints for domain germany: invalid constraint "at least 10 GiB or something" for service-one/capacity_MiB: value "10 GiB or something" does not match expected format "<number> <unit>"`,
-		`invalid constraints for domain germany: invalid constraint "at most 1 ounce" for service-two/capacity_MiB: cannot convert value from ounce to MiB because units are incompatible`,
-		`missing domain name for project atlantis`,
-		`invalid constraints for project germany/berlin: no such service: unknown`,
-		`invalid constraints for project germany/dresden: invalid constraint "at least NaN" for service-one/things: strconv.ParseUint: parsing "NaN": invalid syntax`,
-		`invalid constraints for project germany/dresden: invalid constraint "at least 4, at most 2" for service-two/things: constraint clauses cannot simultaneously be satisfied`,
-		`invalid constraints for project poland/warsaw: invalid constraint "should be 4 MiB, should be 5 MiB" for service-two/capacity_MiB: cannot have multiple "should be" clauses in one constraint`,
+		`fixtures/quota-constraint-invalid.yaml:4: invalid constraints for domain germany: invalid constraint "not more than 20" for service-one/things: clause "not more than 20" should start with "at least", "at most" or "exactly"`,
+		`fixtures/quota-constraint-invalid.yaml:5: invalid constraints for domain germany: invalid constraint "at least 10 GiB or something" for service-one/capacity_MiB: value "10 GiB or something" does not match expected format "<number> <unit>"`,
+		`fixtures/quota-constraint-invalid.yaml:7: invalid constraints for domain germany: invalid constraint "at most 1 ounce" for service-two/capacity_MiB: cannot convert value from ounce to MiB because units are incompatible`,
+		`fixtures/quota-constraint-invalid.yaml:15: missing domain name for project atlantis`,
+		`fixtures/quota-constraint-invalid.yaml:25: invalid constraints for project germany/berlin: no such service: unknown`,
+		`fixtures/quota-constraint-invalid.yaml:29: invalid constraints for project germany/dresden: invalid constraint "at least NaN" for service-one/things: strconv.ParseUint: parsing "NaN": invalid syntax`,
+		`fixtures/quota-constraint-invalid.yaml:31: invalid constraints for project germany/dresden: invalid constraint "at least 4, at most 2" for service-two/things: constraint clauses cannot simultaneously be satisfied`,
+		`fixtures/quota-constraint-invalid.yaml:38: invalid constraints for project poland/warsaw: invalid constraint "should be 4 MiB, should be 5 MiB" for service-two/capacity_MiB: cannot have multiple "should be" clauses in one constraint`,
+		`fixtures/quota-constraint-invalid.yaml:10: invalid constraints for domain poland: invalid constraint "at most 20% of domain quota" for service-one/things: domain constraints cannot refer to the domain quota`,
+		`fixtures/quota-constraint-invalid.yaml:23: invalid constraints for project germany/berlin: invalid constraint "at least usage, at least 2x usage" for service-two/things: cannot have multiple relative "at least" clauses in one constraint`,
+		`fixtures/quota-constraint-invalid.yaml:24: invalid constraints for project germany/berlin: invalid constraint "exactly usage" for service-two/capacity_MiB: clause "exactly usage": relative values are only allowed in "at least" and "at most" clauses`,
+		`fixtures/quota-constraint-invalid.yaml:35: invalid constraints for project poland/warsaw: invalid constraint "at most 2x usage + 10%" for service-one/things: cannot combine a factor with a percentage increment`,
+		`fixtures/quota-constraint-invalid.yaml:41: invalid ratio constraint "at most 2x service-two/things" for service-one: expected key in the form "service/resource"`,
+		`fixtures/quota-constraint-invalid.yaml:42: invalid ratio constraint "at most 2x service-two/things" for service-one/unknown: no such resource: service-one/unknown`,
+		`fixtures/quota-constraint-invalid.yaml:43: invalid ratio constraint "at most 2x service-two/unknown" for service-one/things: no such resource: service-two/unknown`,
+		`fixtures/quota-constraint-invalid.yaml:44: invalid ratio constraint "at most 2 things" for service-two/things: clause "at most 2 things" should look like "at least/most <value> per <service>/<resource>" or "at least/most <factor>x <service>/<resource>"`,
+		`fixtures/quota-constraint-invalid.yaml:45: invalid ratio constraint "at most 1 ounce per service-one/things" for service-two/capacity_MiB: cannot convert value from ounce to MiB because units are incompatible`,
+		`fixtures/quota-constraint-invalid.yaml:46: invalid ratio constraint "at least -2x service-one/things" for service-one/capacity_MiB: clause "at least -2x service-one/things": factor must be a positive number`,
 	)
 
 	expectQuotaConstraintInvalid(t, "fixtures/quota-constraint-inconsistent.yaml",
-		`inconsistent constraints for domain germany: sum of "at least/exactly" project quotas (20480 MiB) for service-one/capacity_MiB exceeds "at least/exactly" domain quota (10240 MiB)`,
-		`inconsistent constraints for domain poland: sum of "at least/exactly" project quotas (5) for service-two/things exceeds "at least/exactly" domain quota (0)`,
+		`fixtures/quota-constraint-inconsistent.yaml:5: inconsistent constraints for domain germany: sum of "at least/exactly" project quotas (20 GiB) for service-one/capacity_MiB exceeds "at least/exactly" domain quota (10 GiB)`,
+		`fixtures/quota-constraint-inconsistent.yaml:22: inconsistent constraints for domain poland: sum of "at least/exactly" project quotas (5) for service-two/things exceeds "at least/exactly" domain quota (0)`,
+		`fixtures/quota-constraint-inconsistent.yaml:2: inconsistent constraints for domain germany: "at least/exactly" quota for service-one/capacity_MiB (10 GiB) and "at most/exactly" quota for service-one/things (30) contradict ratio constraint "service-one/capacity_MiB: at most 100 MiB per service-one/things"`,
 	)
 }
 
@@ -178,6 +275,19 @@ func TestQuotaConstraintToString(t *testing.T) {
 		}, {
 			Input:    QuotaConstraint{Minimum: pointerTo(20), Maximum: pointerTo(20)},
 			Expected: "exactly 20 MiB",
+		}, {
+			Input: QuotaConstraint{
+				Minimum:         pointerTo(10),
+				RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 110},
+				RelativeMaximum: &RelativeQuotaBound{Base: RelativeToDomainQuota, Percent: 20},
+			},
+			Expected: "at least 10 MiB, at least usage + 10%, at most 20% of domain quota",
+		}, {
+			Input: QuotaConstraint{
+				RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 100, Offset: 10},
+				RelativeMaximum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 300, Offset: 20},
+			},
+			Expected: "at least usage + 10 MiB, at most 3x usage + 20 MiB",
 		},
 	}
 
@@ -189,3 +299,126 @@ func TestQuotaConstraintToString(t *testing.T) {
 		}
 	}
 }
+
+func TestQuotaConstraintEvaluate(t *testing.T) {
+	pointerTo := func(x uint64) *uint64 { return &x }
+
+	constraint := QuotaConstraint{
+		Minimum:         pointerTo(10),
+		Maximum:         pointerTo(1000),
+		RelativeMinimum: &RelativeQuotaBound{Base: RelativeToUsage, Percent: 110},
+		RelativeMaximum: &RelativeQuotaBound{Base: RelativeToDomainQuota, Percent: 20},
+	}
+
+	type testcase struct {
+		Usage       uint64
+		DomainQuota uint64
+		Expected    QuotaConstraint
+	}
+	testcases := []testcase{
+		//absolute bounds are stricter than relative bounds
+		{Usage: 5, DomainQuota: 10000, Expected: QuotaConstraint{Minimum: pointerTo(10), Maximum: pointerTo(1000)}},
+		//relative bounds are stricter than absolute bounds (with rounding)
+		{Usage: 101, DomainQuota: 2001, Expected: QuotaConstraint{Minimum: pointerTo(112), Maximum: pointerTo(400)}},
+		//relative bounds contradict each other -> lower bound wins
+		{Usage: 500, DomainQuota: 1000, Expected: QuotaConstraint{Minimum: pointerTo(550), Maximum: pointerTo(550)}},
+		//relative lower bound exceeds absolute upper bound -> absolute bound wins
+		{Usage: 2000, DomainQuota: 100000, Expected: QuotaConstraint{Minimum: pointerTo(1000), Maximum: pointerTo(1000)}},
+		//relative upper bound is below absolute lower bound -> absolute bound wins
+		{Usage: 0, DomainQuota: 10, Expected: QuotaConstraint{Minimum: pointerTo(10), Maximum: pointerTo(10)}},
+	}
+
+	for _, tc := range testcases {
+		actual := constraint.Evaluate(tc.Usage, tc.DomainQuota)
+		if !reflect.DeepEqual(actual, tc.Expected) {
+			t.Errorf("expected Evaluate(%d, %d) = %s, but got %s", tc.Usage, tc.DomainQuota,
+				tc.Expected.ToString(UnitNone), actual.ToString(UnitNone))
+		}
+	}
+
+	//Evaluate() must not modify the original constraint
+	if *constraint.Minimum != 10 || *constraint.Maximum != 1000 {
+		t.Errorf("Evaluate() modified the original constraint: %s", constraint.ToString(UnitNone))
+	}
+}
+
+func TestQuotaRatioConstraintInvalidFactor(t *testing.T) {
+	cluster := clusterForQuotaConstraintTest()
+	for _, factor := range []string{"-2x", "0x", "NaN x", "NaNx", "Inf x", "+Infx", "-Infx", "0 MiB per"} {
+		clause := "at most " + factor + " service-one/things"
+		_, err := parseQuotaRatioConstraints(cluster, "service-one/capacity_MiB", clause)
+		expected := `clause "` + clause + `": factor must be a positive number`
+		if err == nil {
+			t.Errorf("expected error for %q, but got none", clause)
+		} else if err.Error() != expected {
+			t.Errorf("expected error %q for %q, but got %q", expected, clause, err.Error())
+		}
+	}
+}
+
+func TestQuotaRatioConstraintToString(t *testing.T) {
+	type testcase struct {
+		Input    QuotaRatioConstraint
+		Unit     Unit
+		Expected string
+	}
+	testcases := []testcase{
+		{
+			Input:    QuotaRatioConstraint{ServiceType: "compute", ResourceName: "ram", RefServiceType: "compute", RefResourceName: "cores", IsUpperBound: true, Factor: 8192},
+			Unit:     UnitMebibytes,
+			Expected: "compute/ram: at most 8 GiB per compute/cores",
+		}, {
+			Input:    QuotaRatioConstraint{ServiceType: "volumev2", ResourceName: "snapshots", RefServiceType: "volumev2", RefResourceName: "volumes", IsUpperBound: true, Factor: 2, Offset: 10},
+			Unit:     UnitNone,
+			Expected: "volumev2/snapshots: at most 2x volumev2/volumes + 10",
+		}, {
+			Input:    QuotaRatioConstraint{ServiceType: "compute", ResourceName: "ram", RefServiceType: "volumev2", RefResourceName: "capacity", Factor: 0.5},
+			Unit:     UnitMebibytes,
+			Expected: "compute/ram: at least 0.5x volumev2/capacity",
+		},
+	}
+
+	for _, testcase := range testcases {
+		actual := testcase.Input.ToString(testcase.Unit)
+		if actual != testcase.Expected {
+			t.Errorf("expected %#v to serialize into %q, but got %q",
+				testcase.Input, testcase.Expected, actual)
+		}
+	}
+}
+
+func TestQuotaRatioConstraintRefBound(t *testing.T) {
+	atMost := QuotaRatioConstraint{IsUpperBound: true, Factor: 2.5, Offset: 10}
+	atLeast := QuotaRatioConstraint{IsUpperBound: false, Factor: 2.5, Offset: 10}
+
+	type testcase struct {
+		Ratio         QuotaRatioConstraint
+		Quota         uint64
+		ExpectedBound uint64
+		ExpectedOK    bool
+	}
+	testcases := []testcase{
+		//at most 2.5x ref + 10 -> lower bound for ref
+		{atMost, 5, 0, true},
+		{atMost, 20, 4, true},
+		{atMost, 21, 5, true},
+		//at least 2.5x ref + 10 -> upper bound for ref
+		{atLeast, 5, 0, false},
+		{atLeast, 10, 0, true},
+		{atLeast, 20, 4, true},
+		{atLeast, 21, 4, true},
+		//extreme factors saturate instead of overflowing
+		{QuotaRatioConstraint{IsUpperBound: true, Factor: 1e-300}, 5, 0, false},
+		{QuotaRatioConstraint{IsUpperBound: false, Factor: 1e-300}, 5, math.MaxUint64, true},
+		{QuotaRatioConstraint{IsUpperBound: true, Factor: 1e300}, 5, 1, true},
+		{QuotaRatioConstraint{IsUpperBound: false, Factor: 1e300}, 5, 0, true},
+	}
+
+	for _, tc := range testcases {
+		bound, ok := tc.Ratio.RefBound(tc.Quota)
+		if bound != tc.ExpectedBound || ok != tc.ExpectedOK {
+			t.Errorf("expected %s.RefBound(%d) = (%d, %t), but got (%d, %t)",
+				tc.Ratio.ToString(UnitNone), tc.Quota, tc.ExpectedBound, tc.ExpectedOK, bound, ok)
+		}
+	}
+}
@@ -54,8 +54,11 @@ type DiscoveryPlugin interface {
 //quota plugin providing this ResourceData instance has been instructed to (and
 //is able to) scrape subresources for this resource.
 type ResourceData struct {
-	Quota        int64 //negative values indicate infinite quota
-	Usage        uint64
+	Quota int64 //negative values indicate infinite quota
+	Usage uint64
+	//Reserved is the part of the quota that is reserved for operations in
+	//progress, but not yet included in Usage. Only some backends report this.
+	Reserved     uint64
 	Subresources []interface{}
 }
 
@@ -143,6 +146,9 @@ type ResourceInfo struct {
 	//the first time, a backend quota equal to this value will be approved
 	//automatically (i.e. Quota will be set equal to BackendQuota).
 	AutoApproveInitialQuota uint64 `json:"-"`
+	//If NoUsage is true, the backend does not report usage for this resource,
+	//so the usage is always reported as 0.
+	NoUsage bool `json:"no_usage,omitempty"`
 }
 
 //ServiceInfo contains the metadata for a backend service.
@@ -182,6 +188,7 @@ var discoveryPluginFactories = map[string]DiscoveryPluginFactory{}
 var quotaPluginFactories = map[string]QuotaPluginFactory{}
 var capacityPluginFactories = map[string]CapacityPluginFactory{}
 var serviceTypesByArea = map[string][]string{}
+var genericQuotaPluginFactory QuotaPluginFactory
 
 //RegisterDiscoveryPlugin registers a DiscoveryPlugin with this package. It may
 //only be called once, typically in a func init() for the package that offers
@@ -227,6 +234,31 @@ func RegisterQuotaPlugin(factory QuotaPluginFactory) {
 	serviceTypesByArea[info.Area] = append(serviceTypesByArea[info.Area], info.Type)
 }
 
+//RegisterGenericQuotaPlugin registers the QuotaPlugin that is used for all
+//services that have a "generic" section in their configuration. It may only
+//be called once, typically in a func init() for the package that offers the
+//QuotaPlugin.
+func RegisterGenericQuotaPlugin(factory QuotaPluginFactory) {
+	if factory == nil {
+		panic("collector.RegisterGenericQuotaPlugin() called with nil QuotaPluginFactory instance")
+	}
+	if genericQuotaPluginFactory != nil {
+		panic("collector.RegisterGenericQuotaPlugin() called multiple times")
+	}
+	genericQuotaPluginFactory = factory
+}
+
+//registerServiceTypeForArea is used for service types that are only known at
+//runtime (when using the generic quota plugin).
+func registerServiceTypeForArea(area, serviceType string) {
+	for _, t := range serviceTypesByArea[area] {
+		if t == serviceType {
+			return
+		}
+	}
+	serviceTypesByArea[area] = append(serviceTypesByArea[area], serviceType)
+}
+
 //GetServiceTypesForArea returns a list of all service types whose QuotaPlugins
 //report the given area.
 func GetServiceTypesForArea(area string) []string {
@@ -0,0 +1,71 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"fmt"
+	"sort"
+)
+
+//QuotaClass is a named set of project quotas (e.g. "small", "medium",
+//"large") that can be applied to projects in bulk. The values are in the
+//native unit of each resource.
+type QuotaClass map[string]map[string]uint64
+
+//NewQuotaClasses parses the quota classes from the given section of the
+//cluster configuration. Quota values may carry a unit for resources that are
+//measured rather than counted, e.g. "10 GiB".
+func NewQuotaClasses(cluster *Cluster, data map[string]map[string]map[string]string) (map[string]QuotaClass, []error) {
+	result := make(map[string]QuotaClass, len(data))
+	var errors []error
+
+	//iterate in a stable order to get reproducible error messages
+	classNames := make([]string, 0, len(data))
+	for className := range data {
+		classNames = append(classNames, className)
+	}
+	sort.Strings(classNames)
+
+	for _, className := range classNames {
+		class := make(QuotaClass)
+		for serviceType, resources := range data[className] {
+			if !cluster.HasService(serviceType) {
+				errors = append(errors, fmt.Errorf("invalid quota class %s: no such service: %s", className, serviceType))
+				continue
+			}
+			class[serviceType] = make(map[string]uint64, len(resources))
+			for resourceName, str := range resources {
+				if !cluster.HasResource(serviceType, resourceName) {
+					errors = append(errors, fmt.Errorf("invalid quota class %s: no such resource: %s/%s", className, serviceType, resourceName))
+					continue
+				}
+				value, err := cluster.InfoForResource(serviceType, resourceName).Unit.Parse(str)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("invalid quota class %s: invalid value %q for %s/%s: %s", className, str, serviceType, resourceName, err.Error()))
+					continue
+				}
+				class[serviceType][resourceName] = value
+			}
+		}
+		result[className] = class
+	}
+
+	return result, errors
+}
@@ -0,0 +1,79 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package limes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuotaClassParsing(t *testing.T) {
+	classes, errs := NewQuotaClasses(clusterForQuotaConstraintTest(), map[string]map[string]map[string]string{
+		"small": {
+			"service-one": {"things": "10", "capacity_MiB": "2 GiB"},
+		},
+		"large": {
+			"service-one": {"things": "100"},
+			"service-two": {"capacity_MiB": "1.5 TiB"},
+		},
+	})
+	if len(errs) > 0 {
+		t.Errorf("expected no parsing errors, got %d errors:\n", len(errs))
+		for idx, err := range errs {
+			t.Logf("[%d] %s\n", idx+1, err.Error())
+		}
+	}
+
+	expected := map[string]QuotaClass{
+		"small": {
+			"service-one": {"things": 10, "capacity_MiB": 2048},
+		},
+		"large": {
+			"service-one": {"things": 100},
+			"service-two": {"capacity_MiB": 1572864},
+		},
+	}
+	if !reflect.DeepEqual(classes, expected) {
+		t.Errorf("expected %#v, got %#v", expected, classes)
+	}
+
+	_, errs = NewQuotaClasses(clusterForQuotaConstraintTest(), map[string]map[string]map[string]string{
+		"broken": {
+			"service-one": {"things": "10 MiB", "unknown": "5"},
+			"service-two": {"capacity_MiB": "10 KiB"},
+			"unknown":     {"things": "5"},
+		},
+	})
+	expectedErrors := map[string]bool{
+		`invalid quota class broken: invalid value "10 MiB" for service-one/things: strconv.ParseUint: parsing "10 MiB": invalid syntax`:                  true,
+		`invalid quota class broken: no such resource: service-one/unknown`:                                                                               true,
+		`invalid quota class broken: invalid value "10 KiB" for service-two/capacity_MiB: value of 10 KiB cannot be represented as integer number of MiB`: true,
+		`invalid quota class broken: no such service: unknown`:                                                                                            true,
+	}
+	for _, err := range errs {
+		if !expectedErrors[err.Error()] {
+			t.Errorf("unexpected error: %s", err.Error())
+		}
+		delete(expectedErrors, err.Error())
+	}
+	for msg := range expectedErrors {
+		t.Errorf("missing expected error: %s", msg)
+	}
+}
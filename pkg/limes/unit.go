@@ -21,6 +21,7 @@ package limes
 
 import (
 	"fmt"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
@@ -47,10 +48,51 @@ const (
 	UnitPebibytes Unit = "PiB"
 	//UnitExbibytes is exactly that.
 	UnitExbibytes Unit = "EiB"
+	//UnitKilobytes is exactly that.
+	UnitKilobytes Unit = "KB"
+	//UnitMegabytes is exactly that.
+	UnitMegabytes Unit = "MB"
+	//UnitGigabytes is exactly that.
+	UnitGigabytes Unit = "GB"
+	//UnitTerabytes is exactly that.
+	UnitTerabytes Unit = "TB"
+	//UnitPetabytes is exactly that.
+	UnitPetabytes Unit = "PB"
+	//UnitExabytes is exactly that.
+	UnitExabytes Unit = "EB"
+	//UnitSeconds is exactly that.
+	UnitSeconds Unit = "s"
+	//UnitMinutes is exactly that.
+	UnitMinutes Unit = "min"
+	//UnitHours is exactly that.
+	UnitHours Unit = "h"
+	//UnitDays is exactly that.
+	UnitDays Unit = "d"
+	//UnitBitsPerSecond is exactly that.
+	UnitBitsPerSecond Unit = "bit/s"
+	//UnitKilobitsPerSecond is exactly that.
+	UnitKilobitsPerSecond Unit = "Kbit/s"
+	//UnitMegabitsPerSecond is exactly that.
+	UnitMegabitsPerSecond Unit = "Mbit/s"
+	//UnitGigabitsPerSecond is exactly that.
+	UnitGigabitsPerSecond Unit = "Gbit/s"
+	//UnitTerabitsPerSecond is exactly that.
+	UnitTerabitsPerSecond Unit = "Tbit/s"
 	//UnitUnspecified is used as a placeholder when the unit is not known.
 	UnitUnspecified Unit = "UNSPECIFIED"
 )
 
+//unitSeries lists groups of units that are multiples of each other, ordered
+//by size. Within each series, Format() chooses the most appropriate unit.
+//The base unit may appear in multiple series (e.g. bytes appear in the binary
+//as well as the decimal series); the first series is preferred for it.
+var unitSeries = [][]Unit{
+	{UnitBytes, UnitKibibytes, UnitMebibytes, UnitGibibytes, UnitTebibytes, UnitPebibytes, UnitExbibytes},
+	{UnitBytes, UnitKilobytes, UnitMegabytes, UnitGigabytes, UnitTerabytes, UnitPetabytes, UnitExabytes},
+	{UnitSeconds, UnitMinutes, UnitHours, UnitDays},
+	{UnitBitsPerSecond, UnitKilobitsPerSecond, UnitMegabitsPerSecond, UnitGigabitsPerSecond, UnitTerabitsPerSecond},
+}
+
 //Base returns the base unit of this unit. For units defined as a multiple of
 //another unit, that unit is the base unit. Otherwise, the same unit and a
 //multiple of 1 is returned.
@@ -68,36 +110,84 @@ func (u Unit) Base() (Unit, uint64) {
 		return UnitBytes, 1 << 50
 	case UnitExbibytes:
 		return UnitBytes, 1 << 60
+	case UnitKilobytes:
+		return UnitBytes, 1e3
+	case UnitMegabytes:
+		return UnitBytes, 1e6
+	case UnitGigabytes:
+		return UnitBytes, 1e9
+	case UnitTerabytes:
+		return UnitBytes, 1e12
+	case UnitPetabytes:
+		return UnitBytes, 1e15
+	case UnitExabytes:
+		return UnitBytes, 1e18
+	case UnitMinutes:
+		return UnitSeconds, 60
+	case UnitHours:
+		return UnitSeconds, 60 * 60
+	case UnitDays:
+		return UnitSeconds, 24 * 60 * 60
+	case UnitKilobitsPerSecond:
+		return UnitBitsPerSecond, 1e3
+	case UnitMegabitsPerSecond:
+		return UnitBitsPerSecond, 1e6
+	case UnitGigabitsPerSecond:
+		return UnitBitsPerSecond, 1e9
+	case UnitTerabitsPerSecond:
+		return UnitBitsPerSecond, 1e12
 	default:
 		return u, 1
 	}
 }
 
-//Format appends the unit (if any) to the given value. This should only be used
-//for error messages; actual UIs should be more clever about formatting values
-//(e.g. UnitMebibytes.Format(1048576) returns "1048576 MiB" where "1 TiB"
-//would be more appropriate).
+//Format appends the unit (if any) to the given value. The value is shown in
+//the largest unit from the same series of units in which it is still an
+//integer, so the result can always be parsed back into the same value.
+//
+//	UnitMebibytes.Format(1048576) -> "1 TiB"
+//	UnitMebibytes.Format(1536)    -> "1536 MiB"
+//	UnitMinutes.Format(120)       -> "2 h"
+//	UnitNone.Format(42)           -> "42"
 //
 //TODO Deprecated, use ValueWithUnit.String() instead.
 func (u Unit) Format(value uint64) string {
-	str := strconv.FormatUint(value, 10)
-	if u == UnitNone {
+	unit, displayValue := u, value
+	if value != 0 {
+		_, multiple := u.Base()
+		for _, candidate := range u.series() {
+			_, candidateMultiple := candidate.Base()
+			if candidateMultiple <= multiple || candidateMultiple%multiple != 0 {
+				continue
+			}
+			factor := candidateMultiple / multiple
+			if value%factor == 0 {
+				unit, displayValue = candidate, value/factor
+			}
+		}
+	}
+
+	str := strconv.FormatUint(displayValue, 10)
+	if unit == UnitNone {
 		return str
 	}
-	return str + " " + string(u)
+	return str + " " + string(unit)
 }
 
-var measuredQuotaValueRx = regexp.MustCompile(`^\s*([0-9]+)\s*([A-Za-z]+)$`)
+var measuredQuotaValueRx = regexp.MustCompile(`^\s*([0-9]+(?:\.[0-9]+)?)\s*([A-Za-z/]+)\s*$`)
 
 //Parse parses the string representation of a value with this unit (or any unit
-//that can be converted to it).
+//that can be converted to it). Fractional values are accepted if they can be
+//represented as an integer number of this unit.
 //
-//	UnitMebibytes.Parse("10 MiB") -> 10
-//	UnitMebibytes.Parse("10 GiB") -> 10240
-//	UnitMebibytes.Parse("10 KiB") -> returns FractionalValueError
-//	UnitMebibytes.Parse("10")     -> returns syntax error (missing unit)
-//	UnitNone.Parse("42")          -> 42
-//	UnitNone.Parse("42 MiB")      -> returns syntax error (unexpected unit)
+//	UnitMebibytes.Parse("10 MiB")   -> 10
+//	UnitMebibytes.Parse("10 GiB")   -> 10240
+//	UnitMebibytes.Parse("1.5 GiB")  -> 1536
+//	UnitBytes.Parse("500 GB")       -> 500000000000
+//	UnitMebibytes.Parse("10 KiB")   -> returns FractionalValueError
+//	UnitMebibytes.Parse("10")       -> returns syntax error (missing unit)
+//	UnitNone.Parse("42")            -> 42
+//	UnitNone.Parse("42 MiB")        -> returns syntax error (unexpected unit)
 //
 func (u Unit) Parse(str string) (uint64, error) {
 	//for countable resources, expect a number only
@@ -106,24 +196,75 @@ func (u Unit) Parse(str string) (uint64, error) {
 	}
 
 	//for measured resources, expect a number plus unit
-	fields := strings.Fields(str)
-	if len(fields) != 2 {
+	match := measuredQuotaValueRx.FindStringSubmatch(str)
+	if match == nil {
 		return 0, fmt.Errorf("value %q does not match expected format \"<number> <unit>\"",
 			str)
 	}
 
-	number, err := strconv.ParseUint(fields[0], 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid value %q: %s",
-			str, err.Error())
+	//no need to validate unit string here; that will happen implicitly during conversion
+	return convertNumber(match[1], Unit(match[2]), u)
+}
+
+//ParseValueWithUnit parses a number (which may be fractional) in the given
+//unit. If the number is fractional, the result is converted into the
+//base unit of the given unit. An error is returned if the value cannot be
+//represented as an integer number of the base unit.
+//
+//	ParseValueWithUnit("10", UnitGibibytes)  -> {10, GiB}
+//	ParseValueWithUnit("1.5", UnitGibibytes) -> {1610612736, B}
+//	ParseValueWithUnit("0.5", UnitBytes)     -> returns FractionalValueError
+//
+func ParseValueWithUnit(number string, unit Unit) (ValueWithUnit, error) {
+	if !strings.Contains(number, ".") {
+		value, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return ValueWithUnit{}, fmt.Errorf("invalid value %q: %s", number, err.Error())
+		}
+		return ValueWithUnit{value, unit}, nil
+	}
+
+	base, _ := unit.Base()
+	value, err := convertNumber(number, unit, base)
+	return ValueWithUnit{value, base}, err
+}
+
+//convertNumber converts a decimal number (which may be fractional) from one
+//unit into another. Exact rational arithmetic is used to avoid rounding
+//errors.
+func convertNumber(number string, source, target Unit) (uint64, error) {
+	//fast path for integer values (this also gives the same error messages
+	//as ValueWithUnit.ConvertTo)
+	if !strings.Contains(number, ".") {
+		value, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q: %s", number+" "+string(source), err.Error())
+		}
+		converted, err := ValueWithUnit{value, source}.ConvertTo(target)
+		return converted.Value, err
+	}
+
+	sourceBase, sourceMultiple := source.Base()
+	targetBase, targetMultiple := target.Base()
+	if sourceBase != targetBase {
+		return 0, IncompatibleUnitsError{Source: source, Target: target}
+	}
+
+	value, ok := new(big.Rat).SetString(number)
+	if !ok {
+		return 0, fmt.Errorf("invalid value %q", number+" "+string(source))
 	}
-	value := ValueWithUnit{
-		Value: number,
-		//no need to validate unit string here; that will happen implicitly during .ConvertTo()
-		Unit: Unit(fields[1]),
+	value.Mul(value, new(big.Rat).SetInt(new(big.Int).SetUint64(sourceMultiple)))
+	value.Quo(value, new(big.Rat).SetInt(new(big.Int).SetUint64(targetMultiple)))
+	if !value.IsInt() {
+		return 0, fmt.Errorf("value of %s %s cannot be represented as integer number of %s",
+			number, source, target,
+		)
 	}
-	converted, err := value.ConvertTo(u)
-	return converted.Value, err
+	if !value.Num().IsUint64() {
+		return 0, fmt.Errorf("value of %s %s is too large", number, source)
+	}
+	return value.Num().Uint64(), nil
 }
 
 //ValueWithUnit is used to represent values with units in subresources.
@@ -137,6 +278,25 @@ func (v ValueWithUnit) String() string {
 	return v.Unit.Format(v.Value)
 }
 
+//IsKnown returns whether this unit is UnitNone or one of the measurable units
+//declared above.
+func (u Unit) IsKnown() bool {
+	return u == UnitNone || u.series() != nil
+}
+
+//series returns the series of units that this unit belongs to, or nil if it
+//does not belong to any (e.g. for UnitNone).
+func (u Unit) series() []Unit {
+	for _, series := range unitSeries {
+		for _, unit := range series {
+			if unit == u {
+				return series
+			}
+		}
+	}
+	return nil
+}
+
 //ConvertTo returns an equal value in the given Unit. IncompatibleUnitsError is
 //returned if the source unit cannot be converted into the target unit.
 //FractionalValueError is returned if the conversion does not yield an integer
@@ -57,3 +57,71 @@ func Test_ValueWithUnit_ConvertTo(t *testing.T) {
 		"value of 42 B cannot be represented as integer number of MiB",
 	)
 }
+
+func Test_Unit_Parse(t *testing.T) {
+	type testcase struct {
+		Unit     Unit
+		Input    string
+		Expected uint64
+		Error    string
+	}
+	testcases := []testcase{
+		{Unit: UnitMebibytes, Input: "10 GiB", Expected: 10240},
+		{Unit: UnitMebibytes, Input: "1.5 GiB", Expected: 1536},
+		{Unit: UnitMebibytes, Input: "1.5GiB", Expected: 1536},
+		{Unit: UnitBytes, Input: "500 GB", Expected: 500000000000},
+		{Unit: UnitMegabytes, Input: "0.25 TB", Expected: 250000},
+		{Unit: UnitSeconds, Input: "1.5 h", Expected: 5400},
+		{Unit: UnitMegabitsPerSecond, Input: "2.5 Gbit/s", Expected: 2500},
+		{Unit: UnitMebibytes, Input: "10 KiB", Error: "value of 10 KiB cannot be represented as integer number of MiB"},
+		{Unit: UnitMebibytes, Input: "1.3 MiB", Error: "value of 1.3 MiB cannot be represented as integer number of MiB"},
+		{Unit: UnitMebibytes, Input: "500 MB", Error: "value of 500 MB cannot be represented as integer number of MiB"},
+		{Unit: UnitMebibytes, Input: "1.5 h", Error: "cannot convert value from h to MiB because units are incompatible"},
+		{Unit: UnitMebibytes, Input: "10", Error: `value "10" does not match expected format "<number> <unit>"`},
+	}
+
+	for _, tc := range testcases {
+		actual, err := tc.Unit.Parse(tc.Input)
+		switch {
+		case tc.Error == "" && err != nil:
+			t.Errorf("unexpected error when parsing %q as %s: %s", tc.Input, tc.Unit, err.Error())
+		case tc.Error == "" && actual != tc.Expected:
+			t.Errorf("expected %q to parse into %d %s, but got %d", tc.Input, tc.Expected, tc.Unit, actual)
+		case tc.Error != "" && err == nil:
+			t.Errorf("expected error when parsing %q as %s, but got %d", tc.Input, tc.Unit, actual)
+		case tc.Error != "" && err.Error() != tc.Error:
+			t.Errorf("unexpected error when parsing %q as %s", tc.Input, tc.Unit)
+			t.Logf("  expected: %s", tc.Error)
+			t.Logf("    actual: %s", err.Error())
+		}
+	}
+}
+
+func Test_ValueWithUnit_String(t *testing.T) {
+	testcases := map[ValueWithUnit]string{
+		{1048576, UnitMebibytes}:      "1 TiB",
+		{1536, UnitMebibytes}:         "1536 MiB",
+		{1000, UnitMebibytes}:         "1000 MiB",
+		{0, UnitGibibytes}:            "0 GiB",
+		{1500, UnitMegabytes}:         "1500 MB",
+		{2000, UnitMegabytes}:         "2 GB",
+		{1024, UnitBytes}:             "1 KiB",
+		{1000, UnitBytes}:             "1000 B",
+		{120, UnitMinutes}:            "2 h",
+		{3000, UnitMegabitsPerSecond}: "3 Gbit/s",
+		{42, UnitNone}:                "42",
+	}
+	for input, expected := range testcases {
+		actual := input.String()
+		if actual != expected {
+			t.Errorf("expected %#v to format into %q, but got %q", input, expected, actual)
+		}
+		//the result must parse back into the same value
+		parsed, err := input.Unit.Parse(actual)
+		if err != nil {
+			t.Errorf("cannot parse %q: %s", actual, err.Error())
+		} else if parsed != input.Value {
+			t.Errorf("expected %q to parse into %d, but got %d", actual, input.Value, parsed)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+type barbicanPlugin struct {
+	cfg limes.ServiceConfiguration
+}
+
+var barbicanResources = []limes.ResourceInfo{
+	{
+		Name:    "cas",
+		Unit:    limes.UnitNone,
+		NoUsage: true,
+	},
+	{
+		Name:    "consumers",
+		Unit:    limes.UnitNone,
+		NoUsage: true,
+	},
+	{
+		Name:    "containers",
+		Unit:    limes.UnitNone,
+		NoUsage: true,
+	},
+	{
+		Name:    "orders",
+		Unit:    limes.UnitNone,
+		NoUsage: true,
+	},
+	{
+		Name:    "secrets",
+		Unit:    limes.UnitNone,
+		NoUsage: true,
+	},
+}
+
+func init() {
+	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
+		return &barbicanPlugin{c}
+	})
+}
+
+//Init implements the limes.QuotaPlugin interface.
+func (p *barbicanPlugin) Init(provider *gophercloud.ProviderClient) error {
+	return nil
+}
+
+//ServiceInfo implements the limes.QuotaPlugin interface.
+func (p *barbicanPlugin) ServiceInfo() limes.ServiceInfo {
+	return limes.ServiceInfo{
+		Type:        "key-manager",
+		ProductName: "barbican",
+		Area:        "security",
+	}
+}
+
+//Resources implements the limes.QuotaPlugin interface.
+func (p *barbicanPlugin) Resources() []limes.ResourceInfo {
+	return barbicanResources
+}
+
+//Client returns a client for the Barbican API (Gophercloud does not support
+//Barbican yet, so we build the client by hand).
+func (p *barbicanPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
+	serviceType := "key-manager"
+	eo := gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityPublic}
+	eo.ApplyDefaults(serviceType)
+
+	url, err := provider.EndpointLocator(eo)
+	if err != nil {
+		return nil, err
+	}
+	return &gophercloud.ServiceClient{
+		ProviderClient: provider,
+		Endpoint:       url,
+		ResourceBase:   url + "v1/",
+		Type:           serviceType,
+	}, nil
+}
+
+//Scrape implements the limes.QuotaPlugin interface.
+func (p *barbicanPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string) (map[string]limes.ResourceData, error) {
+	client, err := p.Client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	//query quotas
+	quotas, err := barbicanGetQuota(client, projectUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	//Barbican's list endpoints only show objects in the project that the
+	//request's token is scoped to, and there is no admin API for listing objects
+	//in other projects, so usage cannot be reported
+	return map[string]limes.ResourceData{
+		"cas":        {Quota: quotas.CAs},
+		"consumers":  {Quota: quotas.Consumers},
+		"containers": {Quota: quotas.Containers},
+		"orders":     {Quota: quotas.Orders},
+		"secrets":    {Quota: quotas.Secrets},
+	}, nil
+}
+
+//SetQuota implements the limes.QuotaPlugin interface.
+func (p *barbicanPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string, quotas map[string]uint64) error {
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	return barbicanSetQuota(client, projectUUID, &barbicanQuota{
+		CAs:        int64(quotas["cas"]),
+		Consumers:  int64(quotas["consumers"]),
+		Containers: int64(quotas["containers"]),
+		Orders:     int64(quotas["orders"]),
+		Secrets:    int64(quotas["secrets"]),
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// API requests to Barbican
+
+type barbicanQuota struct {
+	CAs        int64 `json:"cas"`
+	Consumers  int64 `json:"consumers"`
+	Containers int64 `json:"containers"`
+	Orders     int64 `json:"orders"`
+	Secrets    int64 `json:"secrets"`
+}
+
+func barbicanGetQuota(client *gophercloud.ServiceClient, projectUUID string) (*barbicanQuota, error) {
+	url := client.ServiceURL("project-quotas", projectUUID)
+
+	var result gophercloud.Result
+	var data struct {
+		Quotas barbicanQuota `json:"project_quotas"`
+	}
+	_, result.Err = client.Get(url, &result.Body, nil)
+	if _, ok := result.Err.(gophercloud.ErrDefault404); ok {
+		//no project-specific quotas have been set, so Barbican's configured
+		//defaults apply (which are unlimited unless configured otherwise)
+		return &barbicanQuota{CAs: -1, Consumers: -1, Containers: -1, Orders: -1, Secrets: -1}, nil
+	}
+	err := result.ExtractInto(&data)
+	return &data.Quotas, err
+}
+
+func barbicanSetQuota(client *gophercloud.ServiceClient, projectUUID string, quota *barbicanQuota) error {
+	url := client.ServiceURL("project-quotas", projectUUID)
+	requestData := map[string]*barbicanQuota{"project_quotas": quota}
+	_, err := client.Put(url, requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{204}})
+	return err
+}
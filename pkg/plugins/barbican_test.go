@@ -0,0 +1,157 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+//fakeBarbican is a fake Barbican API that serves project quotas for a single
+//project.
+type fakeBarbican struct {
+	ProjectUUID string
+	Quotas      *barbicanQuota //nil if no project quotas have been set
+}
+
+func (f *fakeBarbican) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") != "fake-token" {
+		http.Error(w, "unauthorized", 401)
+		return
+	}
+	//keystonemiddleware overwrites this header from the token, so requests
+	//that rely on it to select a project do not work with a real Barbican
+	if r.Header.Get("X-Project-Id") != "" {
+		http.Error(w, "X-Project-Id header cannot be used to select a project", 400)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v1/project-quotas/" + f.ProjectUUID:
+		switch r.Method {
+		case "GET":
+			if f.Quotas == nil {
+				http.Error(w, "not found", 404)
+				return
+			}
+			writeJSON(w, 200, map[string]interface{}{"project_quotas": f.Quotas})
+		case "PUT":
+			var data struct {
+				Quotas *barbicanQuota `json:"project_quotas"`
+			}
+			err := json.NewDecoder(r.Body).Decode(&data)
+			if err != nil || data.Quotas == nil {
+				http.Error(w, "bad request", 400)
+				return
+			}
+			f.Quotas = data.Quotas
+			w.WriteHeader(204)
+		default:
+			http.Error(w, "method not allowed", 405)
+		}
+	case "/v1/cas", "/v1/containers", "/v1/orders", "/v1/secrets":
+		//these only show objects in the project that the token is scoped to,
+		//i.e. the project of Limes' service user
+		http.Error(w, "listing objects is not supported for other projects", 400)
+	default:
+		http.Error(w, "forbidden", 403)
+	}
+}
+
+func setupBarbicanTest(t *testing.T, fake *fakeBarbican) (*httptest.Server, *gophercloud.ProviderClient, limes.QuotaPlugin) {
+	server, provider := newFakeProvider(t, "key-manager", fake)
+	plugin := &barbicanPlugin{}
+	err := plugin.Init(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, provider, plugin
+}
+
+func Test_BarbicanScrape(t *testing.T) {
+	fake := &fakeBarbican{
+		ProjectUUID: "uuid-for-berlin",
+		Quotas:      &barbicanQuota{CAs: 1, Consumers: 20, Containers: 10, Orders: 5, Secrets: 50},
+	}
+	server, provider, plugin := setupBarbicanTest(t, fake)
+	defer server.Close()
+
+	//usage is not available from Barbican, so only quotas are reported
+	data, err := plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "scraped data", data, map[string]limes.ResourceData{
+		"cas":        {Quota: 1},
+		"consumers":  {Quota: 20},
+		"containers": {Quota: 10},
+		"orders":     {Quota: 5},
+		"secrets":    {Quota: 50},
+	})
+	for _, res := range plugin.Resources() {
+		if !res.NoUsage {
+			t.Errorf("expected resource %s to be marked as NoUsage", res.Name)
+		}
+	}
+
+	//when no project quotas have been set, the quotas are reported as unlimited
+	fake.Quotas = nil
+	data, err = plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "scraped data", data, map[string]limes.ResourceData{
+		"cas":        {Quota: -1},
+		"consumers":  {Quota: -1},
+		"containers": {Quota: -1},
+		"orders":     {Quota: -1},
+		"secrets":    {Quota: -1},
+	})
+
+	//errors from Barbican are reported
+	_, err = plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-dresden")
+	if err == nil {
+		t.Error("expected Scrape() to fail for unknown project, but it succeeded")
+	}
+}
+
+func Test_BarbicanSetQuota(t *testing.T) {
+	fake := &fakeBarbican{ProjectUUID: "uuid-for-berlin"}
+	server, provider, plugin := setupBarbicanTest(t, fake)
+	defer server.Close()
+
+	err := plugin.SetQuota(provider, "west", "uuid-for-germany", "uuid-for-berlin", map[string]uint64{
+		"cas":        2,
+		"consumers":  30,
+		"containers": 15,
+		"orders":     10,
+		"secrets":    100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "quotas", fake.Quotas, &barbicanQuota{CAs: 2, Consumers: 30, Containers: 15, Orders: 10, Secrets: 100})
+}
@@ -91,12 +91,59 @@ func (p *capacityCinderPlugin) Scrape(provider *gophercloud.ProviderClient, clus
 
 	}
 
-	return map[string]map[string]limes.CapacityData{
-		"volumev2": {
-			"capacity": limes.CapacityData{Capacity: totalCapacity},
-			//NOTE: no estimates for no. of snapshots/volumes here; this depends highly on the backend
-			//(on SAP CC, we configure capacity for snapshots/volumes via the "manual" capacitor)
-		},
-	}, nil
+	capacities := map[string]limes.CapacityData{
+		"capacity": {Capacity: totalCapacity},
+		//NOTE: no estimates for no. of snapshots/volumes here; this depends highly on the backend
+		//(on SAP CC, we configure capacity for snapshots/volumes via the "manual" capacitor)
+	}
 
+	//report capacity per volume type (each volume type is mapped to its pools
+	//through the volume_backend_name extra spec)
+	volumeTypes, err := listCinderVolumeTypesWithBackends(client)
+	if err != nil {
+		return nil, err
+	}
+	for volumeType, backendName := range volumeTypes {
+		if backendName == "" {
+			util.LogDebug("Not reporting capacity for volume type %s without volume_backend_name", volumeType)
+			continue
+		}
+		var capacity uint64
+		for _, element := range limitData.Pools {
+			if element.Capabilities.VolumeBackendName == backendName {
+				capacity += uint64(element.Capabilities.TotalCapacity)
+			}
+		}
+		capacities["capacity_"+volumeType] = limes.CapacityData{Capacity: capacity}
+	}
+
+	return map[string]map[string]limes.CapacityData{"volumev2": capacities}, nil
+}
+
+//listCinderVolumeTypesWithBackends returns a mapping of volume type names to
+//the volume_backend_name in their extra specs (or "" if not set).
+func listCinderVolumeTypesWithBackends(client *gophercloud.ServiceClient) (map[string]string, error) {
+	var result gophercloud.Result
+	url := client.ServiceURL("types") + "?is_public=None"
+	_, err := client.Get(url, &result.Body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		VolumeTypes []struct {
+			Name       string            `json:"name"`
+			ExtraSpecs map[string]string `json:"extra_specs"`
+		} `json:"volume_types"`
+	}
+	err = result.ExtractInto(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeTypes := make(map[string]string, len(data.VolumeTypes))
+	for _, volumeType := range data.VolumeTypes {
+		volumeTypes[volumeType.Name] = volumeType.ExtraSpecs["volume_backend_name"]
+	}
+	return volumeTypes, nil
 }
@@ -21,6 +21,7 @@ package plugins
 
 import (
 	"errors"
+	"net/url"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -72,27 +73,10 @@ func (p *capacityManilaPlugin) Scrape(provider *gophercloud.ProviderClient, clus
 
 	//query Manila for known pools and hosts
 	//filtered by share-type 'default'
-	var data struct {
-		Pools []struct {
-			Host         string `json:"host"`
-			Capabilities struct {
-				TotalCapacityGB float64 `json:"total_capacity_gb"`
-			} `json:"capabilities"`
-		} `json:"pools"`
-	}
-	err = manilaGetPoolsDetailed(client).ExtractInto(&data)
+	poolCount, totalCapacityGB, err := manilaGetPoolCapacity(client, "default")
 	if err != nil {
 		return nil, err
 	}
-
-	//count hosts and pools, find total capacity
-	hosts := make(map[string]bool)
-	totalCapacityGB := float64(0)
-	for _, pool := range data.Pools {
-		hosts[pool.Host] = true
-		totalCapacityGB += pool.Capabilities.TotalCapacityGB
-	}
-	poolCount := uint64(len(data.Pools))
 	totalCapacityGB *= cfg.CapacityOvercommitFactor
 
 	//derive capacities
@@ -107,20 +91,62 @@ func (p *capacityManilaPlugin) Scrape(provider *gophercloud.ProviderClient, clus
 	//example, with CapacityBalance = 2, we allocate 2/3 of the total capacity to
 	//snapshots, and 1/3 to shares.
 	b := cfg.CapacityBalance
-	return map[string]map[string]limes.CapacityData{
-		"sharev2": {
-			"share_networks":    limes.CapacityData{Capacity: cfg.ShareNetworks},
-			"shares":            limes.CapacityData{Capacity: shareCount},
-			"share_snapshots":   limes.CapacityData{Capacity: cfg.SnapshotsPerShare * shareCount},
-			"share_capacity":    limes.CapacityData{Capacity: uint64(1 / (b + 1) * totalCapacityGB)},
-			"snapshot_capacity": limes.CapacityData{Capacity: uint64(b / (b + 1) * totalCapacityGB)},
-		},
-	}, nil
+	result := map[string]limes.CapacityData{
+		"share_networks":    {Capacity: cfg.ShareNetworks},
+		"shares":            {Capacity: shareCount},
+		"share_snapshots":   {Capacity: cfg.SnapshotsPerShare * shareCount},
+		"share_capacity":    {Capacity: uint64(1 / (b + 1) * totalCapacityGB)},
+		"snapshot_capacity": {Capacity: uint64(b / (b + 1) * totalCapacityGB)},
+	}
+
+	//the same calculation for each share type, using only the pools that
+	//support that share type (share networks are not per share type, so they
+	//are not subtracted from the share count here)
+	shareTypes, err := listManilaShareTypes(client)
+	if err != nil {
+		return nil, err
+	}
+	for _, shareType := range shareTypes {
+		poolCount, capacityGB, err := manilaGetPoolCapacity(client, shareType)
+		if err != nil {
+			return nil, err
+		}
+		capacityGB *= cfg.CapacityOvercommitFactor
+		shareCount := cfg.SharesPerPool * poolCount
+		result["shares_"+shareType] = limes.CapacityData{Capacity: shareCount}
+		result["share_snapshots_"+shareType] = limes.CapacityData{Capacity: cfg.SnapshotsPerShare * shareCount}
+		result["share_capacity_"+shareType] = limes.CapacityData{Capacity: uint64(1 / (b + 1) * capacityGB)}
+		result["snapshot_capacity_"+shareType] = limes.CapacityData{Capacity: uint64(b / (b + 1) * capacityGB)}
+	}
+
+	return map[string]map[string]limes.CapacityData{"sharev2": result}, nil
+}
+
+//manilaGetPoolCapacity returns the number of pools supporting the given share
+//type, and their total capacity in GiB.
+func manilaGetPoolCapacity(client *gophercloud.ServiceClient, shareType string) (poolCount uint64, totalCapacityGB float64, err error) {
+	var data struct {
+		Pools []struct {
+			Host         string `json:"host"`
+			Capabilities struct {
+				TotalCapacityGB float64 `json:"total_capacity_gb"`
+			} `json:"capabilities"`
+		} `json:"pools"`
+	}
+	err = manilaGetPoolsDetailed(client, shareType).ExtractInto(&data)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, pool := range data.Pools {
+		totalCapacityGB += pool.Capabilities.TotalCapacityGB
+	}
+	return uint64(len(data.Pools)), totalCapacityGB, nil
 }
 
-func manilaGetPoolsDetailed(client *gophercloud.ServiceClient) (result gophercloud.Result) {
+func manilaGetPoolsDetailed(client *gophercloud.ServiceClient, shareType string) (result gophercloud.Result) {
 	client.Microversion = "2.23" //required for filtering by share_type
-	url := client.ServiceURL("scheduler-stats", "pools", "detail") + "?share_type=default"
+	url := client.ServiceURL("scheduler-stats", "pools", "detail") + "?share_type=" + url.QueryEscape(shareType)
 	_, result.Err = client.Get(url, &result.Body, nil)
 	return
 }
@@ -20,6 +20,10 @@
 package plugins
 
 import (
+	"encoding/json"
+	"sort"
+	"strings"
+
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/v2/volumes"
@@ -30,6 +34,8 @@ import (
 type cinderPlugin struct {
 	cfg           limes.ServiceConfiguration
 	scrapeVolumes bool
+	volumeTypes   []string
+	resources     []limes.ResourceInfo
 }
 
 var cinderResources = []limes.ResourceInfo{
@@ -52,15 +58,85 @@ func init() {
 		return &cinderPlugin{
 			cfg:           c,
 			scrapeVolumes: scrapeSubresources["volumes"],
+			resources:     cinderResources,
 		}
 	})
 }
 
 //Init implements the limes.QuotaPlugin interface.
 func (p *cinderPlugin) Init(provider *gophercloud.ProviderClient) error {
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	//find per-volume-type resources
+	p.volumeTypes, err = listCinderVolumeTypes(client)
+	if err != nil {
+		return err
+	}
+	p.resources = cinderResources
+	for _, volumeType := range p.volumeTypes {
+		p.resources = append(p.resources,
+			limes.ResourceInfo{
+				Name:     "capacity_" + volumeType,
+				Category: "per_volume_type",
+				Unit:     limes.UnitGibibytes,
+			},
+			limes.ResourceInfo{
+				Name:     "snapshots_" + volumeType,
+				Category: "per_volume_type",
+				Unit:     limes.UnitNone,
+			},
+			limes.ResourceInfo{
+				Name:     "volumes_" + volumeType,
+				Category: "per_volume_type",
+				Unit:     limes.UnitNone,
+			},
+		)
+	}
+	sort.Slice(p.resources, func(i, j int) bool {
+		return p.resources[i].Name < p.resources[j].Name
+	})
+
 	return nil
 }
 
+//listCinderVolumeTypes returns the names of all volume types that have
+//separate quotas.
+func listCinderVolumeTypes(client *gophercloud.ServiceClient) ([]string, error) {
+	//look at quota class "default" to determine which quotas exist
+	url := client.ServiceURL("os-quota-class-sets", "default")
+	var result gophercloud.Result
+	_, err := client.Get(url, &result.Body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	//Cinder has the quotas "gigabytes_${TYPE}", "snapshots_${TYPE}" and
+	//"volumes_${TYPE}" for each volume type. We look at the "volumes_" prefix
+	//since the "gigabytes_" prefix is ambiguous (e.g. "gigabytes_per_volume"
+	//would not be a volume type).
+	var body struct {
+		//NOTE: cannot use map[string]int64 here because this object contains the
+		//field "id": "default"
+		QuotaClassSet map[string]interface{} `json:"quota_class_set"`
+	}
+	err = result.ExtractInto(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumeTypes []string
+	for key := range body.QuotaClassSet {
+		if strings.HasPrefix(key, "volumes_") {
+			volumeTypes = append(volumeTypes, strings.TrimPrefix(key, "volumes_"))
+		}
+	}
+	sort.Strings(volumeTypes)
+	return volumeTypes, nil
+}
+
 //ServiceInfo implements the limes.QuotaPlugin interface.
 func (p *cinderPlugin) ServiceInfo() limes.ServiceInfo {
 	return limes.ServiceInfo{
@@ -72,7 +148,7 @@ func (p *cinderPlugin) ServiceInfo() limes.ServiceInfo {
 
 //Resources implements the limes.QuotaPlugin interface.
 func (p *cinderPlugin) Resources() []limes.ResourceInfo {
-	return cinderResources
+	return p.resources
 }
 
 func (p *cinderPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
@@ -88,31 +164,40 @@ func (p *cinderPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, d
 		return nil, err
 	}
 
-	var result gophercloud.Result
+	var quotaSetResult gophercloud.Result
 	url := client.ServiceURL("os-quota-sets", projectUUID) + "?usage=True"
-	_, err = client.Get(url, &result.Body, nil)
+	_, err = client.Get(url, &quotaSetResult.Body, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	type field struct {
-		Quota int64  `json:"limit"`
-		Usage uint64 `json:"in_use"`
-	}
+	//NOTE: cannot decode into map[string]cinderQuotaSetField right away because
+	//this object contains the field "id": "$PROJECT_ID"
 	var data struct {
-		QuotaSet struct {
-			Capacity  field `json:"gigabytes"`
-			Snapshots field `json:"snapshots"`
-			Volumes   field `json:"volumes"`
-		} `json:"quota_set"`
+		QuotaSet map[string]json.RawMessage `json:"quota_set"`
 	}
-	err = result.ExtractInto(&data)
+	err = quotaSetResult.ExtractInto(&data)
 	if err != nil {
 		return nil, err
 	}
 
-	var volumeData []interface{}
+	result := make(map[string]limes.ResourceData, len(p.resources))
+	for _, res := range p.resources {
+		var field cinderQuotaSetField
+		if raw, exists := data.QuotaSet[cinderQuotaName(res.Name)]; exists {
+			err := json.Unmarshal(raw, &field)
+			if err != nil {
+				return nil, err
+			}
+		}
+		result[res.Name] = limes.ResourceData{
+			Quota: field.Quota,
+			Usage: field.Usage,
+		}
+	}
+
 	if p.scrapeVolumes {
+		var volumeData []interface{}
 		listOpts := cinderVolumeListOpts{
 			AllTenants: true,
 			ProjectID:  projectUUID,
@@ -140,34 +225,22 @@ func (p *cinderPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, d
 		if err != nil {
 			return nil, err
 		}
+
+		volumesData := result["volumes"]
+		volumesData.Subresources = volumeData
+		result["volumes"] = volumesData
 	}
 
-	return map[string]limes.ResourceData{
-		"capacity": {
-			Quota: data.QuotaSet.Capacity.Quota,
-			Usage: data.QuotaSet.Capacity.Usage,
-		},
-		"snapshots": {
-			Quota: data.QuotaSet.Snapshots.Quota,
-			Usage: data.QuotaSet.Snapshots.Usage,
-		},
-		"volumes": {
-			Quota:        data.QuotaSet.Volumes.Quota,
-			Usage:        data.QuotaSet.Volumes.Usage,
-			Subresources: volumeData,
-		},
-	}, nil
+	return result, nil
 }
 
 //SetQuota implements the limes.QuotaPlugin interface.
 func (p *cinderPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string, quotas map[string]uint64) error {
-	requestData := map[string]map[string]uint64{
-		"quota_set": {
-			"gigabytes": quotas["capacity"],
-			"snapshots": quotas["snapshots"],
-			"volumes":   quotas["volumes"],
-		},
+	quotaSet := make(map[string]uint64, len(quotas))
+	for resourceName, quota := range quotas {
+		quotaSet[cinderQuotaName(resourceName)] = quota
 	}
+	requestData := map[string]map[string]uint64{"quota_set": quotaSet}
 
 	client, err := p.Client(provider)
 	if err != nil {
@@ -179,6 +252,22 @@ func (p *cinderPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID,
 	return err
 }
 
+//cinderQuotaSetField is the representation of a single quota in a Cinder
+//quota set (when requested with "?usage=True").
+type cinderQuotaSetField struct {
+	Quota int64  `json:"limit"`
+	Usage uint64 `json:"in_use"`
+}
+
+//cinderQuotaName returns the name of the Cinder quota corresponding to the
+//given resource name (only the names of capacity resources differ).
+func cinderQuotaName(resourceName string) string {
+	if resourceName == "capacity" || strings.HasPrefix(resourceName, "capacity_") {
+		return "gigabytes" + strings.TrimPrefix(resourceName, "capacity")
+	}
+	return resourceName
+}
+
 type cinderVolumeListOpts struct {
 	AllTenants bool   `q:"all_tenants"`
 	ProjectID  string `q:"project_id"`
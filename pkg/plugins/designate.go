@@ -20,6 +20,9 @@
 package plugins
 
 import (
+	"sync"
+	"time"
+
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/gophercloud/gophercloud/openstack/dns/v2/zones"
@@ -28,9 +31,39 @@ import (
 )
 
 type designatePlugin struct {
-	cfg limes.ServiceConfiguration
+	cfg         limes.ServiceConfiguration
+	scrapeZones bool
+	//recordset counts for each zone, indexed by project UUID
+	zoneCache      map[string]dnsProjectZoneCache
+	zoneCacheMutex sync.Mutex
+}
+
+//dnsProjectZoneCache contains the cached recordset counts for the zones of a
+//single project, indexed by zone ID.
+type dnsProjectZoneCache struct {
+	Zones     map[string]dnsZoneCacheEntry
+	ScrapedAt time.Time
 }
 
+//dnsZoneCacheEntry is the number of recordsets in a zone. Since Designate
+//increments the zone's serial number whenever a recordset is changed, the
+//count can be reused for as long as the serial number does not change.
+type dnsZoneCacheEntry struct {
+	Serial         int
+	RecordsetCount uint64
+}
+
+const (
+	//when more than this many zones need to have their recordsets counted, list
+	//all recordsets in the project at once instead of counting them per zone
+	dnsRecordsetListingThreshold = 20
+	//how many zones may have their recordsets counted in parallel
+	dnsMaxConcurrentRequests = 5
+	//cached recordset counts are dropped for projects that were not scraped
+	//for this long (e.g. because they were deleted)
+	dnsZoneCacheRetention = 3 * time.Hour
+)
+
 var designateResources = []limes.ResourceInfo{
 	{
 		Name: "zones",
@@ -45,7 +78,11 @@ var designateResources = []limes.ResourceInfo{
 
 func init() {
 	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
-		return &designatePlugin{c}
+		return &designatePlugin{
+			cfg:         c,
+			scrapeZones: scrapeSubresources["zones"],
+			zoneCache:   make(map[string]dnsProjectZoneCache),
+		}
 	})
 }
 
@@ -88,31 +125,77 @@ func (p *designatePlugin) Scrape(provider *gophercloud.ProviderClient, clusterID
 	}
 
 	//to query usage, start by listing all zones
-	zoneIDs, err := dnsListZoneIDs(client, projectUUID)
+	allZones, err := dnsListZones(client, projectUUID)
 	if err != nil {
 		return nil, err
 	}
 
-	//query "recordsets per zone" usage by counting recordsets in each zone
-	//individually (we could count all recordsets over the all project at once,
-	//but that won't help since the quota applies per individual zone)
-	//TODO: this needs a lot of API requests for large projects; see if we can
-	//use Ceilometer instead
-	maxRecordsetsPerZone := uint64(0)
-	for _, zoneID := range zoneIDs {
-		count, err := dnsCountZoneRecordsets(client, projectUUID, zoneID)
-		if err != nil {
-			return nil, err
+	//the "recordsets" quota applies per zone, so we need the recordset count
+	//for each zone individually; reuse counts for zones that did not change
+	//since the last scrape
+	p.zoneCacheMutex.Lock()
+	cachedZones := p.zoneCache[projectUUID].Zones
+	p.zoneCacheMutex.Unlock()
+
+	recordsetCounts := make(map[string]uint64, len(allZones))
+	var staleZoneIDs []string
+	for _, zone := range allZones {
+		entry, exists := cachedZones[zone.ID]
+		if exists && entry.Serial == zone.Serial {
+			recordsetCounts[zone.ID] = entry.RecordsetCount
+		} else {
+			staleZoneIDs = append(staleZoneIDs, zone.ID)
 		}
+	}
+
+	//for a few zones, counting recordsets per zone is cheapest; for many zones,
+	//listing all recordsets in the project in one go needs fewer requests
+	var staleCounts map[string]uint64
+	if len(staleZoneIDs) > dnsRecordsetListingThreshold {
+		staleCounts, err = dnsCountRecordsetsByZone(client, projectUUID)
+	} else {
+		staleCounts, err = dnsCountZoneRecordsetsConcurrently(client, projectUUID, staleZoneIDs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, zoneID := range staleZoneIDs {
+		recordsetCounts[zoneID] = staleCounts[zoneID]
+	}
+
+	//rebuild the cache entry for this project to get rid of deleted zones
+	newCachedZones := make(map[string]dnsZoneCacheEntry, len(allZones))
+	maxRecordsetsPerZone := uint64(0)
+	var zoneData []interface{}
+	for _, zone := range allZones {
+		count := recordsetCounts[zone.ID]
+		newCachedZones[zone.ID] = dnsZoneCacheEntry{Serial: zone.Serial, RecordsetCount: count}
 		if maxRecordsetsPerZone < count {
 			maxRecordsetsPerZone = count
 		}
+		if p.scrapeZones {
+			zoneData = append(zoneData, map[string]interface{}{
+				"id":         zone.ID,
+				"name":       zone.Name,
+				"recordsets": count,
+			})
+		}
+	}
+	p.zoneCacheMutex.Lock()
+	now := time.Now()
+	p.zoneCache[projectUUID] = dnsProjectZoneCache{Zones: newCachedZones, ScrapedAt: now}
+	for uuid, entry := range p.zoneCache {
+		if now.Sub(entry.ScrapedAt) > dnsZoneCacheRetention {
+			delete(p.zoneCache, uuid)
+		}
 	}
+	p.zoneCacheMutex.Unlock()
 
 	return map[string]limes.ResourceData{
 		"zones": {
-			Quota: quotas.Zones,
-			Usage: uint64(len(zoneIDs)),
+			Quota:        quotas.Zones,
+			Usage:        uint64(len(allZones)),
+			Subresources: zoneData,
 		},
 		"recordsets": {
 			Quota: quotas.ZoneRecordsets,
@@ -170,35 +253,101 @@ func dnsSetQuota(client *gophercloud.ServiceClient, projectUUID string, quota *d
 	return err
 }
 
-func dnsListZoneIDs(client *gophercloud.ServiceClient, projectUUID string) ([]string, error) {
-	pager := zones.List(client, zones.ListOpts{})
-	pager.Headers = map[string]string{
+func dnsProjectHeaders(projectUUID string) map[string]string {
+	return map[string]string{
 		"X-Auth-All-Projects":    "false",
 		"X-Auth-Sudo-Project-Id": projectUUID,
 	}
+}
 
-	var ids []string
+func dnsListZones(client *gophercloud.ServiceClient, projectUUID string) ([]zones.Zone, error) {
+	pager := zones.List(client, zones.ListOpts{})
+	pager.Headers = dnsProjectHeaders(projectUUID)
+
+	var result []zones.Zone
 	err := pager.EachPage(func(page pagination.Page) (bool, error) {
 		zones, err := zones.ExtractZones(page)
 		if err != nil {
 			return false, err
 		}
-		for _, zone := range zones {
-			ids = append(ids, zone.ID)
-		}
+		result = append(result, zones...)
 		return true, nil
 	})
-	return ids, err
+	return result, err
+}
+
+//dnsCountRecordsetsByZone lists all recordsets in the given project, and
+//returns the number of recordsets in each zone, indexed by zone ID.
+func dnsCountRecordsetsByZone(client *gophercloud.ServiceClient, projectUUID string) (map[string]uint64, error) {
+	opts := gophercloud.RequestOpts{MoreHeaders: dnsProjectHeaders(projectUUID)}
+	url := client.ServiceURL("recordsets") + "?limit=1000"
+
+	counts := make(map[string]uint64)
+	for url != "" {
+		var result gophercloud.Result
+		var data struct {
+			Recordsets []struct {
+				ZoneID string `json:"zone_id"`
+			} `json:"recordsets"`
+			Links struct {
+				Next string `json:"next"`
+			} `json:"links"`
+		}
+		_, result.Err = client.Get(url, &result.Body, &opts)
+		err := result.ExtractInto(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, recordset := range data.Recordsets {
+			counts[recordset.ZoneID]++
+		}
+		url = data.Links.Next
+		if len(data.Recordsets) == 0 {
+			break
+		}
+	}
+	return counts, nil
+}
+
+//dnsCountZoneRecordsetsConcurrently counts the recordsets in each of the given
+//zones, with at most dnsMaxConcurrentRequests requests running at once.
+func dnsCountZoneRecordsetsConcurrently(client *gophercloud.ServiceClient, projectUUID string, zoneIDs []string) (map[string]uint64, error) {
+	type zoneResult struct {
+		ZoneID string
+		Count  uint64
+		Err    error
+	}
+	results := make(chan zoneResult, len(zoneIDs))
+	semaphore := make(chan struct{}, dnsMaxConcurrentRequests)
+
+	for _, zoneID := range zoneIDs {
+		go func(zoneID string) {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			count, err := dnsCountZoneRecordsets(client, projectUUID, zoneID)
+			results <- zoneResult{zoneID, count, err}
+		}(zoneID)
+	}
+
+	counts := make(map[string]uint64, len(zoneIDs))
+	var firstErr error
+	for range zoneIDs {
+		result := <-results
+		if result.Err != nil {
+			if firstErr == nil {
+				firstErr = result.Err
+			}
+			continue
+		}
+		counts[result.ZoneID] = result.Count
+	}
+	return counts, firstErr
 }
 
 func dnsCountZoneRecordsets(client *gophercloud.ServiceClient, projectUUID, zoneID string) (uint64, error) {
 	url := client.ServiceURL("zones", zoneID, "recordsets")
-	opts := gophercloud.RequestOpts{
-		MoreHeaders: map[string]string{
-			"X-Auth-All-Projects":    "false",
-			"X-Auth-Sudo-Project-Id": projectUUID,
-		},
-	}
+	opts := gophercloud.RequestOpts{MoreHeaders: dnsProjectHeaders(projectUUID)}
 
 	//do not need all data about all recordsets, just the total count
 	url += "?limit=1"
@@ -0,0 +1,224 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+type fakeDesignateZone struct {
+	ID             string
+	Serial         int
+	RecordsetCount int
+}
+
+//fakeDesignate is a fake Designate API that serves a single project.
+type fakeDesignate struct {
+	ProjectUUID string
+	Zones       []fakeDesignateZone
+	//counts the requests for recordsets, indexed by URL path
+	RecordsetRequests map[string]int
+}
+
+func (f *fakeDesignate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") != "fake-token" {
+		http.Error(w, "unauthorized", 401)
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", 405)
+		return
+	}
+
+	if r.URL.Path == "/v2/quotas/"+f.ProjectUUID {
+		writeJSON(w, 200, dnsQuota{Zones: 10, ZoneRecordsets: 1500, ZoneRecords: 30000})
+		return
+	}
+
+	//all other endpoints list objects in the project
+	if r.Header.Get("X-Auth-Sudo-Project-Id") != f.ProjectUUID {
+		http.Error(w, "wrong project", 403)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/v2/zones":
+		zones := []map[string]interface{}{}
+		for _, zone := range f.Zones {
+			zones = append(zones, map[string]interface{}{
+				"id":     zone.ID,
+				"name":   zone.ID + ".example.com.",
+				"serial": zone.Serial,
+			})
+		}
+		writeJSON(w, 200, map[string]interface{}{"zones": zones, "links": map[string]string{}})
+		return
+	case "/v2/recordsets":
+		f.RecordsetRequests[r.URL.Path]++
+		//enumerate recordsets across all zones, and serve the requested page
+		var zoneIDs []string
+		for _, zone := range f.Zones {
+			for idx := 0; idx < zone.RecordsetCount; idx++ {
+				zoneIDs = append(zoneIDs, zone.ID)
+			}
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		recordsets := []map[string]string{}
+		for idx := offset; idx < offset+limit && idx < len(zoneIDs); idx++ {
+			recordsets = append(recordsets, map[string]string{"zone_id": zoneIDs[idx]})
+		}
+		links := map[string]string{}
+		if offset+limit < len(zoneIDs) {
+			links["next"] = fmt.Sprintf("http://%s/v2/recordsets?limit=%d&offset=%d", r.Host, limit, offset+limit)
+		}
+		writeJSON(w, 200, map[string]interface{}{"recordsets": recordsets, "links": links})
+		return
+	}
+
+	for _, zone := range f.Zones {
+		if r.URL.Path == "/v2/zones/"+zone.ID+"/recordsets" {
+			f.RecordsetRequests[r.URL.Path]++
+			if r.URL.Query().Get("limit") != "1" {
+				http.Error(w, "expected limit=1", 400)
+				return
+			}
+			writeJSON(w, 200, map[string]interface{}{
+				"recordsets": []interface{}{},
+				"metadata":   map[string]int{"total_count": zone.RecordsetCount},
+			})
+			return
+		}
+	}
+	http.Error(w, "not found", 404)
+}
+
+func setupDesignateTest(t *testing.T, fake *fakeDesignate) (*httptest.Server, *gophercloud.ProviderClient, limes.QuotaPlugin) {
+	server, provider := newFakeProvider(t, "dns", fake)
+	plugin := &designatePlugin{
+		scrapeZones: true,
+		zoneCache:   make(map[string]dnsProjectZoneCache),
+	}
+	err := plugin.Init(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, provider, plugin
+}
+
+func (f *fakeDesignate) takeRecordsetRequests() []string {
+	var paths []string
+	for path, count := range f.RecordsetRequests {
+		for idx := 0; idx < count; idx++ {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	f.RecordsetRequests = make(map[string]int)
+	return paths
+}
+
+func Test_DesignateScrape(t *testing.T) {
+	fake := &fakeDesignate{
+		ProjectUUID: "uuid-for-berlin",
+		Zones: []fakeDesignateZone{
+			{ID: "zone1", Serial: 1, RecordsetCount: 3},
+			{ID: "zone2", Serial: 1, RecordsetCount: 7},
+		},
+		RecordsetRequests: make(map[string]int),
+	}
+	server, provider, plugin := setupDesignateTest(t, fake)
+	defer server.Close()
+
+	expectScrape := func(expectedUsage uint64, expectedZones []interface{}, expectedRequests []string) {
+		t.Helper()
+		data, err := plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-berlin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.AssertDeepEqual(t, "scraped data", data, map[string]limes.ResourceData{
+			"zones":      {Quota: 10, Usage: uint64(len(fake.Zones)), Subresources: expectedZones},
+			"recordsets": {Quota: 1500, Usage: expectedUsage},
+		})
+		test.AssertDeepEqual(t, "recordset requests", fake.takeRecordsetRequests(), expectedRequests)
+	}
+	zoneSubresource := func(id string, count uint64) interface{} {
+		return map[string]interface{}{"id": id, "name": id + ".example.com.", "recordsets": count}
+	}
+
+	//first scrape needs to count recordsets in all zones
+	expectScrape(7,
+		[]interface{}{zoneSubresource("zone1", 3), zoneSubresource("zone2", 7)},
+		[]string{"/v2/zones/zone1/recordsets", "/v2/zones/zone2/recordsets"},
+	)
+
+	//second scrape can reuse all counts since no serial changed
+	expectScrape(7,
+		[]interface{}{zoneSubresource("zone1", 3), zoneSubresource("zone2", 7)},
+		nil,
+	)
+
+	//when a zone changes, only that zone is counted again
+	fake.Zones[0] = fakeDesignateZone{ID: "zone1", Serial: 2, RecordsetCount: 9}
+	expectScrape(9,
+		[]interface{}{zoneSubresource("zone1", 9), zoneSubresource("zone2", 7)},
+		[]string{"/v2/zones/zone1/recordsets"},
+	)
+
+	//when many zones need to be counted, all recordsets in the project are
+	//listed at once (and with more than one page worth of recordsets, this
+	//also checks that paging works)
+	fake.Zones[1] = fakeDesignateZone{ID: "zone2", Serial: 2, RecordsetCount: 1200}
+	expectedZones := []interface{}{zoneSubresource("zone1", 9), zoneSubresource("zone2", 1200)}
+	for idx := 3; idx <= dnsRecordsetListingThreshold+3; idx++ {
+		id := fmt.Sprintf("zone%d", idx)
+		fake.Zones = append(fake.Zones, fakeDesignateZone{ID: id, Serial: 1, RecordsetCount: idx})
+		expectedZones = append(expectedZones, zoneSubresource(id, uint64(idx)))
+	}
+	expectScrape(1200, expectedZones, []string{"/v2/recordsets", "/v2/recordsets"})
+
+	//deleted zones disappear from the result
+	fake.Zones = fake.Zones[:1]
+	expectScrape(9, []interface{}{zoneSubresource("zone1", 9)}, nil)
+
+	//projects that have not been scraped for a while are evicted from the cache
+	zoneCache := plugin.(*designatePlugin).zoneCache
+	zoneCache["uuid-for-dresden"] = dnsProjectZoneCache{
+		Zones:     map[string]dnsZoneCacheEntry{"zone9": {Serial: 1, RecordsetCount: 1}},
+		ScrapedAt: time.Now().Add(-2 * dnsZoneCacheRetention),
+	}
+	expectScrape(9, []interface{}{zoneSubresource("zone1", 9)}, nil)
+	if _, exists := zoneCache["uuid-for-dresden"]; exists {
+		t.Error("expected stale zone cache entry to be evicted")
+	}
+	if _, exists := zoneCache["uuid-for-berlin"]; !exists {
+		t.Error("expected zone cache entry for scraped project to be retained")
+	}
+}
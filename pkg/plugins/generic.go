@@ -0,0 +1,294 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+type genericPlugin struct {
+	cfg        limes.ServiceConfiguration
+	resources  []limes.ResourceInfo
+	quotaPath  *template.Template
+	updatePath *template.Template
+	updateBody *template.Template
+}
+
+//genericTemplateData is the data that is available to the templates in the
+//generic plugin's configuration.
+type genericTemplateData struct {
+	DomainID  string
+	ProjectID string
+	//Quotas is only filled for the update_body template.
+	Quotas map[string]uint64
+}
+
+func init() {
+	limes.RegisterGenericQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
+		p := &genericPlugin{cfg: c}
+		for _, res := range c.Generic.Resources {
+			p.resources = append(p.resources, limes.ResourceInfo{
+				Name:     res.Name,
+				Unit:     res.Unit,
+				Category: res.Category,
+			})
+		}
+		return p
+	})
+}
+
+//Init implements the limes.QuotaPlugin interface.
+func (p *genericPlugin) Init(provider *gophercloud.ProviderClient) error {
+	//do not fail on first error; keep going and report all errors at once
+	cfg := p.cfg.Generic
+	success := true
+	fail := func(msg string, args ...interface{}) {
+		util.LogError("invalid configuration for service %s: "+msg, append([]interface{}{p.cfg.Type}, args...)...)
+		success = false
+	}
+	parseTemplate := func(key, text string) *template.Template {
+		if text == "" {
+			fail("missing generic.%s", key)
+			return nil
+		}
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			fail("cannot parse generic.%s: %s", key, err.Error())
+		}
+		return tmpl
+	}
+
+	if cfg.ProductName == "" {
+		fail("missing generic.product")
+	}
+	if cfg.Area == "" {
+		fail("missing generic.area")
+	}
+	p.quotaPath = parseTemplate("quota_path", cfg.QuotaPath)
+	p.updatePath = p.quotaPath
+	if cfg.UpdatePath != "" {
+		p.updatePath = parseTemplate("update_path", cfg.UpdatePath)
+	}
+	p.updateBody = parseTemplate("update_body", cfg.UpdateBody)
+
+	switch cfg.UpdateMethod {
+	case "":
+		cfg.UpdateMethod = "PUT" //default
+	case "PUT", "POST", "PATCH":
+		//acceptable
+	default:
+		fail("generic.update_method must be PUT, POST or PATCH, but is %q", cfg.UpdateMethod)
+	}
+
+	if len(cfg.Resources) == 0 {
+		fail("missing generic.resources[]")
+	}
+	isResourceName := make(map[string]bool)
+	for idx, res := range cfg.Resources {
+		if res.Name == "" {
+			fail("missing generic.resources[%d].name", idx)
+		} else if isResourceName[res.Name] {
+			fail("duplicate resource name in generic.resources[%d]: %s", idx, res.Name)
+		}
+		isResourceName[res.Name] = true
+		if !res.Unit.IsKnown() {
+			fail("unknown unit in generic.resources[%d]: %q", idx, res.Unit)
+		}
+		if res.QuotaField == "" {
+			fail("missing generic.resources[%d].quota_field", idx)
+		}
+		if res.UsageField == "" {
+			fail("missing generic.resources[%d].usage_field", idx)
+		}
+	}
+
+	//check that the templates can be executed, and that the update body is valid JSON
+	if success {
+		sampleData := genericTemplateData{
+			DomainID:  "domain-uuid",
+			ProjectID: "project-uuid",
+			Quotas:    make(map[string]uint64),
+		}
+		for _, res := range cfg.Resources {
+			sampleData.Quotas[res.Name] = 42
+		}
+		for _, tmpl := range []*template.Template{p.quotaPath, p.updatePath} {
+			_, err := genericRenderTemplate(tmpl, sampleData)
+			if err != nil {
+				fail("%s", err.Error())
+			}
+		}
+		body, err := genericRenderTemplate(p.updateBody, sampleData)
+		if err != nil {
+			fail("%s", err.Error())
+		} else if !json.Valid([]byte(body)) {
+			fail("generic.update_body does not render into a valid JSON document: %s", body)
+		}
+	}
+
+	if !success {
+		return errors.New("invalid configuration for generic quota plugin (see errors above)")
+	}
+	return nil
+}
+
+//ServiceInfo implements the limes.QuotaPlugin interface.
+func (p *genericPlugin) ServiceInfo() limes.ServiceInfo {
+	return limes.ServiceInfo{
+		Type:        p.cfg.Type,
+		ProductName: p.cfg.Generic.ProductName,
+		Area:        p.cfg.Generic.Area,
+	}
+}
+
+//Resources implements the limes.QuotaPlugin interface.
+func (p *genericPlugin) Resources() []limes.ResourceInfo {
+	return p.resources
+}
+
+func (p *genericPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
+	eo := gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityPublic}
+	eo.ApplyDefaults(p.cfg.Type)
+
+	url, err := provider.EndpointLocator(eo)
+	if err != nil {
+		return nil, err
+	}
+	return &gophercloud.ServiceClient{
+		ProviderClient: provider,
+		Endpoint:       url,
+		ResourceBase:   url,
+		Type:           p.cfg.Type,
+	}, nil
+}
+
+//Scrape implements the limes.QuotaPlugin interface.
+func (p *genericPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string) (map[string]limes.ResourceData, error) {
+	client, err := p.Client(provider)
+	if err != nil {
+		return nil, err
+	}
+	path, err := genericRenderTemplate(p.quotaPath, genericTemplateData{DomainID: domainUUID, ProjectID: projectUUID})
+	if err != nil {
+		return nil, err
+	}
+
+	var body json.RawMessage
+	_, err = client.Get(client.ServiceURL(path), &body, nil)
+	if err != nil {
+		return nil, err
+	}
+	//decode numbers as json.Number to avoid losing precision on large values
+	var document interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]limes.ResourceData, len(p.cfg.Generic.Resources))
+	for _, res := range p.cfg.Generic.Resources {
+		quota, err := genericExtractField(document, res.QuotaField)
+		if err != nil {
+			return nil, err
+		}
+		usage, err := genericExtractField(document, res.UsageField)
+		if err != nil {
+			return nil, err
+		}
+		if usage < 0 {
+			return nil, fmt.Errorf("field %q contains negative usage value: %d", res.UsageField, usage)
+		}
+		result[res.Name] = limes.ResourceData{
+			Quota: quota,
+			Usage: uint64(usage),
+		}
+	}
+	return result, nil
+}
+
+//SetQuota implements the limes.QuotaPlugin interface.
+func (p *genericPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string, quotas map[string]uint64) error {
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	data := genericTemplateData{DomainID: domainUUID, ProjectID: projectUUID, Quotas: quotas}
+	path, err := genericRenderTemplate(p.updatePath, data)
+	if err != nil {
+		return err
+	}
+	body, err := genericRenderTemplate(p.updateBody, data)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Request(p.cfg.Generic.UpdateMethod, client.ServiceURL(path), &gophercloud.RequestOpts{
+		JSONBody: json.RawMessage(body),
+		OkCodes:  []int{200, 201, 202, 204},
+	})
+	return err
+}
+
+func genericRenderTemplate(tmpl *template.Template, data genericTemplateData) (string, error) {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	if err != nil {
+		return "", fmt.Errorf("cannot render template %s: %s", tmpl.Name(), err.Error())
+	}
+	return buf.String(), nil
+}
+
+//genericExtractField finds the integer value at the given dot-separated path
+//(e.g. "quota_set.cores.limit") in the given JSON document.
+func genericExtractField(document interface{}, path string) (int64, error) {
+	value := document
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("cannot find field %q in response: expected object at %q", path, key)
+		}
+		value, ok = object[key]
+		if !ok {
+			return 0, fmt.Errorf("cannot find field %q in response", path)
+		}
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("field %q in response is not a number", path)
+	}
+	result, err := number.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("field %q in response is not an integer: %s", path, number.String())
+	}
+	return result, nil
+}
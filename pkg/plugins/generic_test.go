@@ -0,0 +1,172 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/test"
+)
+
+//fakeQuotaSets is a fake API with os-quota-sets style quota endpoints.
+type fakeQuotaSets struct {
+	ProjectUUID string
+	QuotaSet    map[string]map[string]int64
+}
+
+func (f *fakeQuotaSets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("X-Auth-Token") != "fake-token" {
+		http.Error(w, "unauthorized", 401)
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/v1/os-quota-sets/"+f.ProjectUUID+"/detail":
+		writeJSON(w, 200, map[string]interface{}{"quota_set": f.QuotaSet})
+	case r.Method == "PUT" && r.URL.Path == "/v1/os-quota-sets/"+f.ProjectUUID:
+		var data struct {
+			QuotaSet map[string]int64 `json:"quota_set"`
+		}
+		err := json.NewDecoder(r.Body).Decode(&data)
+		if err != nil {
+			http.Error(w, "bad request", 400)
+			return
+		}
+		for key, value := range data.QuotaSet {
+			if _, exists := f.QuotaSet[key]; !exists {
+				http.Error(w, "unknown quota: "+key, 400)
+				return
+			}
+			f.QuotaSet[key]["limit"] = value
+		}
+		writeJSON(w, 200, map[string]interface{}{"quota_set": data.QuotaSet})
+	default:
+		http.Error(w, "not found", 404)
+	}
+}
+
+func genericTestConfiguration() limes.ServiceConfiguration {
+	return limes.ServiceConfiguration{
+		Type: "foo",
+		Generic: &limes.GenericServiceConfiguration{
+			ProductName: "foobar",
+			Area:        "testing",
+			QuotaPath:   "v1/os-quota-sets/{{.ProjectID}}/detail",
+			UpdatePath:  "v1/os-quota-sets/{{.ProjectID}}",
+			UpdateBody:  `{"quota_set":{"things":{{.Quotas.things}},"capacity":{{.Quotas.capacity_mb}}}}`,
+			Resources: []limes.GenericResourceConfiguration{
+				{Name: "things", QuotaField: "quota_set.things.limit", UsageField: "quota_set.things.in_use"},
+				{Name: "capacity_mb", Unit: limes.UnitMebibytes, QuotaField: "quota_set.capacity.limit", UsageField: "quota_set.capacity.in_use"},
+			},
+		},
+	}
+}
+
+func setupGenericTest(t *testing.T, fake *fakeQuotaSets) (*httptest.Server, *gophercloud.ProviderClient, limes.QuotaPlugin) {
+	server, provider := newFakeProvider(t, "foo", fake)
+	plugin := &genericPlugin{cfg: genericTestConfiguration()}
+	err := plugin.Init(provider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return server, provider, plugin
+}
+
+func Test_GenericScrapeAndSetQuota(t *testing.T) {
+	fake := &fakeQuotaSets{
+		ProjectUUID: "uuid-for-berlin",
+		QuotaSet: map[string]map[string]int64{
+			"things":   {"limit": 10, "in_use": 2},
+			"capacity": {"limit": -1, "in_use": 1 << 40},
+		},
+	}
+	server, provider, plugin := setupGenericTest(t, fake)
+	defer server.Close()
+
+	data, err := plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "scraped data", data, map[string]limes.ResourceData{
+		"things":      {Quota: 10, Usage: 2},
+		"capacity_mb": {Quota: -1, Usage: 1 << 40},
+	})
+
+	err = plugin.SetQuota(provider, "west", "uuid-for-germany", "uuid-for-berlin", map[string]uint64{
+		"things":      20,
+		"capacity_mb": 1024,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "quota set", fake.QuotaSet, map[string]map[string]int64{
+		"things":   {"limit": 20, "in_use": 2},
+		"capacity": {"limit": 1024, "in_use": 1 << 40},
+	})
+
+	//fields that are missing in the response are reported as errors
+	delete(fake.QuotaSet, "capacity")
+	_, err = plugin.Scrape(provider, "west", "uuid-for-germany", "uuid-for-berlin")
+	expectedMsg := `cannot find field "quota_set.capacity.limit" in response`
+	if err == nil || err.Error() != expectedMsg {
+		t.Errorf("expected Scrape to fail with %q, but got: %v", expectedMsg, err)
+	}
+}
+
+func Test_GenericInitValidation(t *testing.T) {
+	cfg := genericTestConfiguration()
+	cfg.Generic.Area = ""
+	cfg.Generic.UpdateMethod = "DELETE"
+	cfg.Generic.Resources[0].Unit = "furlongs"
+	cfg.Generic.Resources[1].Name = "things"
+	plugin := &genericPlugin{cfg: cfg}
+	if plugin.Init(nil) == nil {
+		t.Error("expected Init to fail for invalid configuration, but it succeeded")
+	}
+
+	//update body must only refer to existing resources
+	cfg = genericTestConfiguration()
+	cfg.Generic.UpdateBody = `{"quota_set":{"things":{{.Quotas.stuff}}}}`
+	plugin = &genericPlugin{cfg: cfg}
+	if plugin.Init(nil) == nil {
+		t.Error("expected Init to fail for unknown resource in update body, but it succeeded")
+	}
+
+	//update body must be valid JSON
+	cfg = genericTestConfiguration()
+	cfg.Generic.UpdateBody = `{"quota_set":{"things":{{.Quotas.things}}}`
+	plugin = &genericPlugin{cfg: cfg}
+	if plugin.Init(nil) == nil {
+		t.Error("expected Init to fail for invalid update body, but it succeeded")
+	}
+
+	//default for update method is PUT
+	cfg = genericTestConfiguration()
+	plugin = &genericPlugin{cfg: cfg}
+	if err := plugin.Init(nil); err != nil {
+		t.Fatal(err)
+	}
+	test.AssertDeepEqual(t, "update method", cfg.Generic.UpdateMethod, "PUT")
+}
@@ -0,0 +1,322 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"fmt"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/gophercloud/gophercloud/pagination"
+	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
+)
+
+type glancePlugin struct {
+	cfg limes.ServiceConfiguration
+	//the Keystone unified limits that Glance uses for enforcement
+	serviceID string
+	//key = Glance resource name
+	registeredLimits map[string]keystoneRegisteredLimit
+	//if unified limits are not available, quotas are read-only, and this
+	//explains why
+	readOnlyReason string
+}
+
+//glanceResourceNames maps Limes resource names to the names of the
+//corresponding unified limits in Keystone.
+var glanceResourceNames = map[string]string{
+	"images":           "image_count_total",
+	"image_size_total": "image_size_total",
+}
+
+//Glance reports image sizes in bytes, but measures image_size_total in MiB.
+const glanceSizeUnit = 1 << 20
+
+var glanceResources = []limes.ResourceInfo{
+	{
+		Name: "image_size_total",
+		Unit: limes.UnitMebibytes,
+	},
+	{
+		Name: "images",
+		Unit: limes.UnitNone,
+	},
+}
+
+func init() {
+	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
+		return &glancePlugin{cfg: c}
+	})
+}
+
+//Init implements the limes.QuotaPlugin interface.
+func (p *glancePlugin) Init(provider *gophercloud.ProviderClient) error {
+	identityClient, err := p.IdentityClient(provider)
+	if err != nil {
+		return err
+	}
+
+	//find Glance's registered limits in Keystone; without them, Glance does not
+	//enforce quotas at all, so we can only report usage
+	p.readOnlyReason = ""
+	serviceID, err := keystoneFindServiceID(identityClient, "image")
+	if err != nil {
+		return err
+	}
+	if serviceID == "" {
+		p.setReadOnly("image service not found in Keystone")
+		return nil
+	}
+	registeredLimits, err := keystoneListRegisteredLimits(identityClient, serviceID)
+	if err != nil {
+		if _, ok := err.(gophercloud.ErrDefault404); ok {
+			p.setReadOnly("Keystone does not support unified limits")
+			return nil
+		}
+		return err
+	}
+	for _, glanceName := range glanceResourceNames {
+		if _, exists := registeredLimits[glanceName]; !exists {
+			p.setReadOnly(fmt.Sprintf("no registered limit for %s in Keystone", glanceName))
+			return nil
+		}
+	}
+
+	p.serviceID = serviceID
+	p.registeredLimits = registeredLimits
+	return nil
+}
+
+func (p *glancePlugin) setReadOnly(reason string) {
+	util.LogInfo("quotas for service image are read-only: %s", reason)
+	p.readOnlyReason = reason
+}
+
+//ServiceInfo implements the limes.QuotaPlugin interface.
+func (p *glancePlugin) ServiceInfo() limes.ServiceInfo {
+	return limes.ServiceInfo{
+		Type:        "image",
+		ProductName: "glance",
+		Area:        "storage",
+	}
+}
+
+//Resources implements the limes.QuotaPlugin interface.
+func (p *glancePlugin) Resources() []limes.ResourceInfo {
+	return glanceResources
+}
+
+func (p *glancePlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
+	return openstack.NewImageServiceV2(provider,
+		gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityPublic},
+	)
+}
+
+func (p *glancePlugin) IdentityClient(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
+	return openstack.NewIdentityV3(provider,
+		gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityPublic},
+	)
+}
+
+//Scrape implements the limes.QuotaPlugin interface.
+func (p *glancePlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string) (map[string]limes.ResourceData, error) {
+	client, err := p.Client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	//calculate usage by listing all images owned by the project
+	var imageCount, imageSizeBytes uint64
+	err = images.List(client, images.ListOpts{Owner: projectUUID}).EachPage(func(page pagination.Page) (bool, error) {
+		imgs, err := images.ExtractImages(page)
+		if err != nil {
+			return false, err
+		}
+		for _, img := range imgs {
+			imageCount++
+			imageSizeBytes += uint64(img.SizeBytes)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	//without unified limits, there is no quota enforcement
+	getLimit := func(glanceName string) int64 { return -1 }
+	if p.readOnlyReason == "" {
+		identityClient, err := p.IdentityClient(provider)
+		if err != nil {
+			return nil, err
+		}
+		projectLimits, err := keystoneListProjectLimits(identityClient, p.serviceID, projectUUID)
+		if err != nil {
+			return nil, err
+		}
+		getLimit = func(glanceName string) int64 {
+			if limit, exists := projectLimits[glanceName]; exists {
+				return limit.ResourceLimit
+			}
+			return p.registeredLimits[glanceName].DefaultLimit
+		}
+	}
+
+	return map[string]limes.ResourceData{
+		"images": {
+			Quota: getLimit(glanceResourceNames["images"]),
+			Usage: imageCount,
+		},
+		"image_size_total": {
+			Quota: getLimit(glanceResourceNames["image_size_total"]),
+			//round up to avoid understating the usage
+			Usage: (imageSizeBytes + glanceSizeUnit - 1) / glanceSizeUnit,
+		},
+	}, nil
+}
+
+//SetQuota implements the limes.QuotaPlugin interface.
+func (p *glancePlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string, quotas map[string]uint64) error {
+	if p.readOnlyReason != "" {
+		return fmt.Errorf("quotas for service image are read-only: %s", p.readOnlyReason)
+	}
+
+	identityClient, err := p.IdentityClient(provider)
+	if err != nil {
+		return err
+	}
+	projectLimits, err := keystoneListProjectLimits(identityClient, p.serviceID, projectUUID)
+	if err != nil {
+		return err
+	}
+
+	for limesName, glanceName := range glanceResourceNames {
+		quota, exists := quotas[limesName]
+		if !exists {
+			continue
+		}
+
+		if limit, exists := projectLimits[glanceName]; exists {
+			err = keystoneUpdateLimit(identityClient, limit.ID, int64(quota))
+		} else {
+			err = keystoneCreateLimit(identityClient, keystoneLimit{
+				ServiceID:     p.serviceID,
+				RegionID:      p.registeredLimits[glanceName].RegionID,
+				ProjectID:     projectUUID,
+				ResourceName:  glanceName,
+				ResourceLimit: int64(quota),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// API requests to Keystone (unified limits)
+
+type keystoneRegisteredLimit struct {
+	ResourceName string  `json:"resource_name"`
+	RegionID     *string `json:"region_id"`
+	DefaultLimit int64   `json:"default_limit"`
+}
+
+type keystoneLimit struct {
+	ID            string  `json:"id,omitempty"`
+	ServiceID     string  `json:"service_id"`
+	RegionID      *string `json:"region_id,omitempty"`
+	ProjectID     string  `json:"project_id"`
+	ResourceName  string  `json:"resource_name"`
+	ResourceLimit int64   `json:"resource_limit"`
+}
+
+//keystoneFindServiceID returns the ID of the service with the given type, or
+//"" if there is no such service.
+func keystoneFindServiceID(client *gophercloud.ServiceClient, serviceType string) (string, error) {
+	url := client.ServiceURL("services") + "?type=" + serviceType
+
+	var result gophercloud.Result
+	var data struct {
+		Services []struct {
+			ID string `json:"id"`
+		} `json:"services"`
+	}
+	_, result.Err = client.Get(url, &result.Body, nil)
+	err := result.ExtractInto(&data)
+	if err != nil || len(data.Services) == 0 {
+		return "", err
+	}
+	return data.Services[0].ID, nil
+}
+
+func keystoneListRegisteredLimits(client *gophercloud.ServiceClient, serviceID string) (map[string]keystoneRegisteredLimit, error) {
+	url := client.ServiceURL("registered_limits") + "?service_id=" + serviceID
+
+	var result gophercloud.Result
+	var data struct {
+		RegisteredLimits []keystoneRegisteredLimit `json:"registered_limits"`
+	}
+	_, result.Err = client.Get(url, &result.Body, nil)
+	err := result.ExtractInto(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]keystoneRegisteredLimit, len(data.RegisteredLimits))
+	for _, limit := range data.RegisteredLimits {
+		limits[limit.ResourceName] = limit
+	}
+	return limits, nil
+}
+
+func keystoneListProjectLimits(client *gophercloud.ServiceClient, serviceID, projectUUID string) (map[string]keystoneLimit, error) {
+	url := client.ServiceURL("limits") + "?service_id=" + serviceID + "&project_id=" + projectUUID
+
+	var result gophercloud.Result
+	var data struct {
+		Limits []keystoneLimit `json:"limits"`
+	}
+	_, result.Err = client.Get(url, &result.Body, nil)
+	err := result.ExtractInto(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := make(map[string]keystoneLimit, len(data.Limits))
+	for _, limit := range data.Limits {
+		limits[limit.ResourceName] = limit
+	}
+	return limits, nil
+}
+
+func keystoneCreateLimit(client *gophercloud.ServiceClient, limit keystoneLimit) error {
+	requestData := map[string][]keystoneLimit{"limits": {limit}}
+	_, err := client.Post(client.ServiceURL("limits"), requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{201}})
+	return err
+}
+
+func keystoneUpdateLimit(client *gophercloud.ServiceClient, limitID string, value int64) error {
+	requestData := map[string]map[string]int64{"limit": {"resource_limit": value}}
+	_, err := client.Patch(client.ServiceURL("limits", limitID), requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	return err
+}
@@ -21,6 +21,8 @@ package plugins
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -31,7 +33,9 @@ import (
 )
 
 type manilaPlugin struct {
-	cfg limes.ServiceConfiguration
+	cfg        limes.ServiceConfiguration
+	shareTypes []string
+	resources  []limes.ResourceInfo
 }
 
 var manilaResources = []limes.ResourceInfo{
@@ -59,15 +63,81 @@ var manilaResources = []limes.ResourceInfo{
 
 func init() {
 	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
-		return &manilaPlugin{c}
+		return &manilaPlugin{cfg: c, resources: manilaResources}
 	})
 }
 
 //Init implements the limes.QuotaPlugin interface.
 func (p *manilaPlugin) Init(provider *gophercloud.ProviderClient) error {
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	//find per-share-type resources
+	p.shareTypes, err = listManilaShareTypes(client)
+	if err != nil {
+		return err
+	}
+	p.resources = manilaResources
+	for _, shareType := range p.shareTypes {
+		p.resources = append(p.resources,
+			limes.ResourceInfo{
+				Name:     "share_capacity_" + shareType,
+				Category: "per_share_type",
+				Unit:     limes.UnitGibibytes,
+			},
+			limes.ResourceInfo{
+				Name:     "share_snapshots_" + shareType,
+				Category: "per_share_type",
+				Unit:     limes.UnitNone,
+			},
+			limes.ResourceInfo{
+				Name:     "shares_" + shareType,
+				Category: "per_share_type",
+				Unit:     limes.UnitNone,
+			},
+			limes.ResourceInfo{
+				Name:     "snapshot_capacity_" + shareType,
+				Category: "per_share_type",
+				Unit:     limes.UnitGibibytes,
+			},
+		)
+	}
+	sort.Slice(p.resources, func(i, j int) bool {
+		return p.resources[i].Name < p.resources[j].Name
+	})
+
 	return nil
 }
 
+//listManilaShareTypes returns the names of all share types.
+func listManilaShareTypes(client *gophercloud.ServiceClient) ([]string, error) {
+	var result gophercloud.Result
+	url := client.ServiceURL("types") + "?is_public=all"
+	_, err := client.Get(url, &result.Body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data struct {
+		ShareTypes []struct {
+			Name string `json:"name"`
+		} `json:"share_types"`
+	}
+	err = result.ExtractInto(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	shareTypes := make([]string, len(data.ShareTypes))
+	for idx, shareType := range data.ShareTypes {
+		shareTypes[idx] = shareType.Name
+	}
+	sort.Strings(shareTypes)
+	return shareTypes, nil
+}
+
 //ServiceInfo implements the limes.QuotaPlugin interface.
 func (p *manilaPlugin) ServiceInfo() limes.ServiceInfo {
 	return limes.ServiceInfo{
@@ -79,7 +149,7 @@ func (p *manilaPlugin) ServiceInfo() limes.ServiceInfo {
 
 //Resources implements the limes.QuotaPlugin interface.
 func (p *manilaPlugin) Resources() []limes.ResourceInfo {
-	return manilaResources
+	return p.resources
 }
 
 func (p *manilaPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
@@ -172,9 +242,7 @@ func (p *manilaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, d
 		return true, nil
 	})
 
-	util.LogDebug("Scraped quota and usage for service: sharev2.")
-
-	return map[string]limes.ResourceData{
+	resultData := map[string]limes.ResourceData{
 		"shares": {
 			Quota: manilaQuotaData.QuotaSet.Shares,
 			Usage: uint64(len(manilaShareUsageData.Shares)),
@@ -195,7 +263,42 @@ func (p *manilaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, d
 			Quota: manilaQuotaData.QuotaSet.SnapshotGigabytes,
 			Usage: uint64(totalSnapshotUsage),
 		},
-	}, err
+	}
+
+	//Get quota and usage per share type
+	for _, shareType := range p.shareTypes {
+		type field struct {
+			Quota int64  `json:"limit"`
+			Usage uint64 `json:"in_use"`
+		}
+		var shareTypeData struct {
+			QuotaSet struct {
+				Gigabytes         field `json:"gigabytes"`
+				Shares            field `json:"shares"`
+				SnapshotGigabytes field `json:"snapshot_gigabytes"`
+				Snapshots         field `json:"snapshots"`
+			} `json:"quota_set"`
+		}
+		err = manilaGetShareTypeQuotaSetDetail(client, projectUUID, shareType).ExtractInto(&shareTypeData)
+		if err != nil {
+			return nil, err
+		}
+		qs := shareTypeData.QuotaSet
+		resultData["shares_"+shareType] = limes.ResourceData{Quota: qs.Shares.Quota, Usage: qs.Shares.Usage}
+		resultData["share_snapshots_"+shareType] = limes.ResourceData{Quota: qs.Snapshots.Quota, Usage: qs.Snapshots.Usage}
+		resultData["share_capacity_"+shareType] = limes.ResourceData{Quota: qs.Gigabytes.Quota, Usage: qs.Gigabytes.Usage}
+		resultData["snapshot_capacity_"+shareType] = limes.ResourceData{Quota: qs.SnapshotGigabytes.Quota, Usage: qs.SnapshotGigabytes.Usage}
+	}
+
+	util.LogDebug("Scraped quota and usage for service: sharev2.")
+	return resultData, nil
+}
+
+func manilaGetShareTypeQuotaSetDetail(client *gophercloud.ServiceClient, projectUUID, shareType string) (result gophercloud.Result) {
+	client.Microversion = "2.39" //required for share type quotas
+	url := client.ServiceURL("os-quota-sets", projectUUID, "detail") + "?share_type=" + url.QueryEscape(shareType)
+	_, result.Err = client.Get(url, &result.Body, nil)
+	return
 }
 
 //SetQuota implements the limes.QuotaPlugin interface.
@@ -215,8 +318,29 @@ func (p *manilaPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID,
 		},
 	}
 
-	url := client.ServiceURL("os-quota-sets", projectUUID)
-	_, err = client.Put(url, requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	_, err = client.Put(client.ServiceURL("os-quota-sets", projectUUID), requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+	if err != nil {
+		return err
+	}
 
-	return err
+	//share type quotas are set separately (after the project quotas, since
+	//Manila does not accept share type quotas that exceed the project quotas)
+	client.Microversion = "2.39" //required for share type quotas
+	for _, shareType := range p.shareTypes {
+		requestData := map[string]map[string]uint64{
+			"quota_set": {
+				"gigabytes":          quotas["share_capacity_"+shareType],
+				"snapshots":          quotas["share_snapshots_"+shareType],
+				"snapshot_gigabytes": quotas["snapshot_capacity_"+shareType],
+				"shares":             quotas["shares_"+shareType],
+			},
+		}
+		url := client.ServiceURL("os-quota-sets", projectUUID) + "?share_type=" + url.QueryEscape(shareType)
+		_, err = client.Put(url, requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{200}})
+		if err != nil {
+			return fmt.Errorf("cannot set quotas for share type %s: %s", shareType, err.Error())
+		}
+	}
+
+	return nil
 }
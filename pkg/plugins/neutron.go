@@ -26,10 +26,15 @@ import (
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
 	"github.com/sapcc/limes/pkg/limes"
+	"github.com/sapcc/limes/pkg/util"
 )
 
 type neutronPlugin struct {
-	cfg limes.ServiceConfiguration
+	cfg          limes.ServiceConfiguration
+	resources    []limes.ResourceInfo
+	resourceMeta []neutronResourceMetadata
+	//whether Neutron supports the "quota_details" API extension
+	hasQuotaDetails bool
 }
 
 var neutronResources = []limes.ResourceInfo{
@@ -113,12 +118,46 @@ var neutronResources = []limes.ResourceInfo{
 
 func init() {
 	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
-		return &neutronPlugin{c}
+		return &neutronPlugin{
+			cfg:          c,
+			resources:    neutronResources,
+			resourceMeta: neutronResourceMeta,
+		}
 	})
 }
 
 //Init implements the limes.QuotaPlugin interface.
 func (p *neutronPlugin) Init(provider *gophercloud.ProviderClient) error {
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	//check if we can get quota and usage in one request per project
+	p.hasQuotaDetails, err = neutronHasExtension(client, "quota_details")
+	if err != nil {
+		return err
+	}
+	if !p.hasQuotaDetails {
+		util.LogInfo("Neutron does not support the quota_details extension; usage will be counted by listing resources")
+	}
+
+	//when Octavia is used instead of Neutron LBaaS, the LBaaS resources are
+	//managed by the "load-balancer" service instead
+	if p.cfg.Network.DisableLBaaS {
+		p.resources = nil
+		for _, res := range neutronResources {
+			if res.Category != "loadbalancing" {
+				p.resources = append(p.resources, res)
+			}
+		}
+		p.resourceMeta = nil
+		for _, res := range neutronResourceMeta {
+			if len(res.EndpointPath) == 0 || res.EndpointPath[0] != "lbaas" {
+				p.resourceMeta = append(p.resourceMeta, res)
+			}
+		}
+	}
 	return nil
 }
 
@@ -133,7 +172,7 @@ func (p *neutronPlugin) ServiceInfo() limes.ServiceInfo {
 
 //Resources implements the limes.QuotaPlugin interface.
 func (p *neutronPlugin) Resources() []limes.ResourceInfo {
-	return neutronResources
+	return p.resources
 }
 
 func (p *neutronPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
@@ -250,6 +289,30 @@ func (p *neutronPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID,
 
 	data := make(map[string]limes.ResourceData)
 
+	//if supported, get quota, usage and reservations in one request
+	if p.hasQuotaDetails {
+		details, err := neutronGetQuotaDetails(client, projectUUID)
+		if err != nil {
+			return nil, err
+		}
+		for _, res := range p.resourceMeta {
+			//resources that are not registered with Neutron's quota engine (e.g.
+			//LBaaS resources, depending on the LBaaS version) are missing here and
+			//will be counted by hand below
+			detail, exists := details[res.NeutronName]
+			if exists {
+				data[res.LimesName] = limes.ResourceData{
+					Quota:    detail.Quota,
+					Usage:    detail.Usage,
+					Reserved: detail.Reserved,
+				}
+			}
+		}
+		if len(data) == len(p.resourceMeta) {
+			return data, nil
+		}
+	}
+
 	//query quotas
 	var result gophercloud.Result
 	url := client.ServiceURL("quotas", projectUUID)
@@ -272,7 +335,10 @@ func (p *neutronPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID,
 	if err != nil {
 		return nil, err
 	}
-	for _, res := range neutronResourceMeta {
+	for _, res := range p.resourceMeta {
+		if _, exists := data[res.LimesName]; exists {
+			continue
+		}
 		url := client.ServiceURL(res.EndpointPath...) + query.String()
 		count, err := countNeutronThings(client, url)
 		if err != nil {
@@ -295,7 +361,7 @@ func (p *neutronPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID
 		Quotas map[string]uint64 `json:"quota"`
 	}
 	requestData.Quotas = make(map[string]uint64)
-	for _, res := range neutronResourceMeta {
+	for _, res := range p.resourceMeta {
 		quota, exists := quotas[res.LimesName]
 		if exists {
 			requestData.Quotas[res.NeutronName] = quota
@@ -312,6 +378,35 @@ func (p *neutronPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID
 	return err
 }
 
+type neutronQuotaDetail struct {
+	Quota    int64  `json:"limit"`
+	Usage    uint64 `json:"used"`
+	Reserved uint64 `json:"reserved"`
+}
+
+//neutronGetQuotaDetails returns quota, usage and reservations for all
+//resources in the given project, indexed by Neutron resource name.
+func neutronGetQuotaDetails(client *gophercloud.ServiceClient, projectUUID string) (map[string]neutronQuotaDetail, error) {
+	url := client.ServiceURL("quotas", projectUUID, "details")
+
+	var result gophercloud.Result
+	var data struct {
+		Details map[string]neutronQuotaDetail `json:"quota"`
+	}
+	_, result.Err = client.Get(url, &result.Body, nil)
+	err := result.ExtractInto(&data)
+	return data.Details, err
+}
+
+//neutronHasExtension checks whether the given API extension is enabled.
+func neutronHasExtension(client *gophercloud.ServiceClient, alias string) (bool, error) {
+	_, err := client.Get(client.ServiceURL("extensions", alias), nil, nil)
+	if _, ok := err.(gophercloud.ErrDefault404); ok {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 //I know that gophercloud has a pagination implementation, but it would lead to
 //a ton of code duplication because Gophercloud insists on using different
 //types for each resource.
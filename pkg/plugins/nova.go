@@ -24,6 +24,7 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -58,8 +59,16 @@ type novaPlugin struct {
 	//caches
 	flavorInfo     map[string]novaFlavorInfo
 	osTypeForImage map[string]string
+	//largest number of members in any server group, indexed by project UUID
+	serverGroupMembers          map[string]uint64
+	serverGroupMembersScrapedAt time.Time
 }
 
+//Server groups can only be listed for all projects at once, so the listing is
+//reused for this long (which matches the interval in which the collector
+//scrapes each project).
+const novaServerGroupCacheDuration = 30 * time.Minute
+
 type novaFlavorInfo struct {
 	Flavor     *flavors.Flavor
 	ExtraSpecs map[string]string
@@ -78,6 +87,15 @@ var novaDefaultResources = []limes.ResourceInfo{
 		Name: "ram",
 		Unit: limes.UnitMebibytes,
 	},
+	{
+		//this quota means "members per server group", not "members per project"!
+		Name: "server_group_members",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "server_groups",
+		Unit: limes.UnitNone,
+	},
 }
 
 var novaInstanceCountGauge = prometheus.NewGaugeVec(
@@ -191,12 +209,15 @@ func (p *novaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, dom
 	var limitsData struct {
 		Limits struct {
 			Absolute struct {
-				MaxTotalCores      int64  `json:"maxTotalCores"`
-				MaxTotalInstances  int64  `json:"maxTotalInstances"`
-				MaxTotalRAMSize    int64  `json:"maxTotalRAMSize"`
-				TotalCoresUsed     uint64 `json:"totalCoresUsed"`
-				TotalInstancesUsed uint64 `json:"totalInstancesUsed"`
-				TotalRAMUsed       uint64 `json:"totalRAMUsed"`
+				MaxTotalCores         int64  `json:"maxTotalCores"`
+				MaxTotalInstances     int64  `json:"maxTotalInstances"`
+				MaxTotalRAMSize       int64  `json:"maxTotalRAMSize"`
+				MaxServerGroups       int64  `json:"maxServerGroups"`
+				MaxServerGroupMembers int64  `json:"maxServerGroupMembers"`
+				TotalCoresUsed        uint64 `json:"totalCoresUsed"`
+				TotalInstancesUsed    uint64 `json:"totalInstancesUsed"`
+				TotalRAMUsed          uint64 `json:"totalRAMUsed"`
+				TotalServerGroupsUsed uint64 `json:"totalServerGroupsUsed"`
 			} `json:"absolute"`
 			AbsolutePerFlavor map[string]struct {
 				MaxTotalInstances  int64  `json:"maxTotalInstances"`
@@ -209,6 +230,16 @@ func (p *novaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, dom
 		return nil, err
 	}
 
+	//the limits API does not report usage for "members per server group", so
+	//we need to look at the server groups themselves (unless there are none)
+	maxServerGroupMembers := uint64(0)
+	if limitsData.Limits.Absolute.TotalServerGroupsUsed > 0 {
+		maxServerGroupMembers, err = p.getMaxServerGroupMembers(client, projectUUID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	result := map[string]*limes.ResourceData{
 		"cores": {
 			Quota: limitsData.Limits.Absolute.MaxTotalCores,
@@ -222,6 +253,14 @@ func (p *novaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, dom
 			Quota: limitsData.Limits.Absolute.MaxTotalRAMSize,
 			Usage: limitsData.Limits.Absolute.TotalRAMUsed,
 		},
+		"server_group_members": {
+			Quota: limitsData.Limits.Absolute.MaxServerGroupMembers,
+			Usage: maxServerGroupMembers,
+		},
+		"server_groups": {
+			Quota: limitsData.Limits.Absolute.MaxServerGroups,
+			Usage: limitsData.Limits.Absolute.TotalServerGroupsUsed,
+		},
 	}
 	if limitsData.Limits.AbsolutePerFlavor != nil {
 		for flavorName, flavorLimits := range limitsData.Limits.AbsolutePerFlavor {
@@ -471,6 +510,59 @@ func (opts novaServerListOpts) ToServerListQuery() (string, error) {
 	return q.String(), err
 }
 
+//getMaxServerGroupMembers returns the largest number of members in any of the
+//project's server groups.
+func (p *novaPlugin) getMaxServerGroupMembers(client *gophercloud.ServiceClient, projectUUID string) (uint64, error) {
+	if p.serverGroupMembers == nil || time.Since(p.serverGroupMembersScrapedAt) > novaServerGroupCacheDuration {
+		scrapedAt := time.Now()
+		result, err := novaListServerGroupMembers(client)
+		if err != nil {
+			return 0, err
+		}
+		p.serverGroupMembers = result
+		p.serverGroupMembersScrapedAt = scrapedAt
+	}
+	return p.serverGroupMembers[projectUUID], nil
+}
+
+//novaListServerGroupMembers returns the largest number of members in any
+//server group for each project.
+func novaListServerGroupMembers(client *gophercloud.ServiceClient) (map[string]uint64, error) {
+	//Nova can only list the server groups of either the current project or of
+	//all projects, so we have to list all of them and sort them by project
+	pageSize := 1000
+	maxMembers := make(map[string]uint64)
+	for offset := 0; ; offset += pageSize {
+		url := client.ServiceURL("os-server-groups") + fmt.Sprintf("?all_projects=True&limit=%d&offset=%d", pageSize, offset)
+
+		var result gophercloud.Result
+		var data struct {
+			ServerGroups []struct {
+				ProjectID string   `json:"project_id"`
+				Members   []string `json:"members"`
+			} `json:"server_groups"`
+		}
+		_, result.Err = client.Get(url, &result.Body, nil)
+		err := result.ExtractInto(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range data.ServerGroups {
+			if maxMembers[group.ProjectID] < uint64(len(group.Members)) {
+				maxMembers[group.ProjectID] = uint64(len(group.Members))
+			}
+		}
+		if len(data.ServerGroups) < pageSize {
+			return maxMembers, nil
+		}
+	}
+}
+
+//novaQuotaUpdateOpts maps resource names to Nova quota names. Since all
+//resource names (including "instances_${FLAVOR_NAME}", "server_groups" and
+//"server_group_members") are identical to the respective Nova quota names, no
+//translation is necessary.
 type novaQuotaUpdateOpts map[string]uint64
 
 func (opts novaQuotaUpdateOpts) ToComputeQuotaUpdateMap() (map[string]interface{}, error) {
@@ -0,0 +1,194 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/sapcc/limes/pkg/limes"
+)
+
+type octaviaPlugin struct {
+	cfg limes.ServiceConfiguration
+}
+
+var octaviaResources = []limes.ResourceInfo{
+	{
+		Name: "healthmonitors",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "l7policies",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "listeners",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "loadbalancers",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "pools",
+		Unit: limes.UnitNone,
+	},
+}
+
+func init() {
+	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
+		return &octaviaPlugin{c}
+	})
+}
+
+//Init implements the limes.QuotaPlugin interface.
+func (p *octaviaPlugin) Init(provider *gophercloud.ProviderClient) error {
+	return nil
+}
+
+//ServiceInfo implements the limes.QuotaPlugin interface.
+func (p *octaviaPlugin) ServiceInfo() limes.ServiceInfo {
+	return limes.ServiceInfo{
+		Type:        "load-balancer",
+		ProductName: "octavia",
+		Area:        "network",
+	}
+}
+
+//Resources implements the limes.QuotaPlugin interface.
+func (p *octaviaPlugin) Resources() []limes.ResourceInfo {
+	return octaviaResources
+}
+
+func (p *octaviaPlugin) Client(provider *gophercloud.ProviderClient) (*gophercloud.ServiceClient, error) {
+	return openstack.NewLoadBalancerV2(provider,
+		gophercloud.EndpointOpts{Availability: gophercloud.AvailabilityPublic},
+	)
+}
+
+type octaviaResourceMetadata struct {
+	LimesName    string
+	OctaviaName  string
+	EndpointPath []string
+}
+
+var octaviaResourceMeta = []octaviaResourceMetadata{
+	{
+		LimesName:    "loadbalancers",
+		OctaviaName:  "load_balancer",
+		EndpointPath: []string{"lbaas", "loadbalancers"},
+	},
+	{
+		LimesName:    "listeners",
+		OctaviaName:  "listener",
+		EndpointPath: []string{"lbaas", "listeners"},
+	},
+	{
+		LimesName:    "pools",
+		OctaviaName:  "pool",
+		EndpointPath: []string{"lbaas", "pools"},
+	},
+	{
+		LimesName:    "healthmonitors",
+		OctaviaName:  "health_monitor",
+		EndpointPath: []string{"lbaas", "healthmonitors"},
+	},
+	{
+		LimesName:    "l7policies",
+		OctaviaName:  "l7policy",
+		EndpointPath: []string{"lbaas", "l7policies"},
+	},
+}
+
+type octaviaQueryOpts struct {
+	Fields      string `q:"fields"`
+	ProjectUUID string `q:"project_id"`
+}
+
+//Scrape implements the limes.QuotaPlugin interface.
+func (p *octaviaPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string) (map[string]limes.ResourceData, error) {
+	client, err := p.Client(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	//query quotas
+	var result gophercloud.Result
+	url := client.ServiceURL("lbaas", "quotas", projectUUID)
+	_, err = client.Get(url, &result.Body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var quotas struct {
+		Values map[string]int64 `json:"quota"`
+	}
+	quotas.Values = make(map[string]int64)
+	err = result.ExtractInto(&quotas)
+	if err != nil {
+		return nil, err
+	}
+
+	//calculate usage by counting resources by hand (the list responses have the
+	//same structure as in Neutron)
+	query, err := gophercloud.BuildQueryString(octaviaQueryOpts{Fields: "id", ProjectUUID: projectUUID})
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]limes.ResourceData)
+	for _, res := range octaviaResourceMeta {
+		url := client.ServiceURL(res.EndpointPath...) + query.String()
+		count, err := countNeutronThings(client, url)
+		if err != nil {
+			return nil, err
+		}
+
+		data[res.LimesName] = limes.ResourceData{
+			Quota: quotas.Values[res.OctaviaName],
+			Usage: uint64(count),
+		}
+	}
+
+	return data, nil
+}
+
+//SetQuota implements the limes.QuotaPlugin interface.
+func (p *octaviaPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID, domainUUID, projectUUID string, quotas map[string]uint64) error {
+	//map resource names from Limes to Octavia
+	var requestData struct {
+		Quotas map[string]uint64 `json:"quota"`
+	}
+	requestData.Quotas = make(map[string]uint64)
+	for _, res := range octaviaResourceMeta {
+		quota, exists := quotas[res.LimesName]
+		if exists {
+			requestData.Quotas[res.OctaviaName] = quota
+		}
+	}
+
+	client, err := p.Client(provider)
+	if err != nil {
+		return err
+	}
+
+	url := client.ServiceURL("lbaas", "quotas", projectUUID)
+	_, err = client.Put(url, requestData, nil, &gophercloud.RequestOpts{OkCodes: []int{202}})
+	return err
+}
@@ -0,0 +1,55 @@
+/*******************************************************************************
+*
+* Copyright 2018 SAP SE
+*
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You should have received a copy of the License along with this
+* program. If not, you may obtain a copy of the License at
+*
+*     http://www.apache.org/licenses/LICENSE-2.0
+*
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*
+*******************************************************************************/
+
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+//newFakeProvider starts a test server with the given handler, and returns a
+//ProviderClient that locates the endpoint for the given service type on this
+//server. The caller must close the server when done.
+func newFakeProvider(t *testing.T, serviceType string, handler http.Handler) (*httptest.Server, *gophercloud.ProviderClient) {
+	server := httptest.NewServer(handler)
+	provider := &gophercloud.ProviderClient{
+		TokenID: "fake-token",
+		EndpointLocator: func(opts gophercloud.EndpointOpts) (string, error) {
+			if opts.Type != serviceType {
+				t.Errorf("expected endpoint lookup for service type %q, but got %q", serviceType, opts.Type)
+				return "", fmt.Errorf("unexpected service type: %s", opts.Type)
+			}
+			return server.URL + "/", nil
+		},
+	}
+	return server, provider
+}
+
+//writeJSON is used by fake API handlers to respond with a JSON body.
+func writeJSON(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
@@ -20,7 +20,10 @@
 package plugins
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -31,7 +34,8 @@ import (
 )
 
 type swiftPlugin struct {
-	cfg limes.ServiceConfiguration
+	cfg       limes.ServiceConfiguration
+	resources []limes.ResourceInfo
 }
 
 var swiftResources = []limes.ResourceInfo{
@@ -39,16 +43,45 @@ var swiftResources = []limes.ResourceInfo{
 		Name: "capacity",
 		Unit: limes.UnitBytes,
 	},
+	{
+		Name: "containers",
+		Unit: limes.UnitNone,
+	},
+	{
+		Name: "objects",
+		Unit: limes.UnitNone,
+	},
 }
 
+const (
+	//the container count quota is stored in account metadata; upstream Swift
+	//does not enforce it, so this requires a middleware that does
+	swiftContainersQuotaHeader = "X-Account-Meta-Quota-Containers"
+	//the object count quota is enforced by the account_quotas middleware since
+	//Swift 2.32.0
+	swiftObjectsQuotaHeader = "X-Account-Quota-Count"
+)
+
 func init() {
 	limes.RegisterQuotaPlugin(func(c limes.ServiceConfiguration, scrapeSubresources map[string]bool) limes.QuotaPlugin {
-		return &swiftPlugin{c}
+		return &swiftPlugin{cfg: c, resources: swiftResources}
 	})
 }
 
 //Init implements the limes.QuotaPlugin interface.
 func (p *swiftPlugin) Init(provider *gophercloud.ProviderClient) error {
+	//add per-storage-policy resources (if configured)
+	p.resources = swiftResources
+	for _, policy := range p.cfg.ObjectStore.StoragePolicies {
+		p.resources = append(p.resources, limes.ResourceInfo{
+			Name:     "capacity_" + policy,
+			Category: "per_storage_policy",
+			Unit:     limes.UnitBytes,
+		})
+	}
+	sort.Slice(p.resources, func(i, j int) bool {
+		return p.resources[i].Name < p.resources[j].Name
+	})
 	return nil
 }
 
@@ -63,7 +96,7 @@ func (p *swiftPlugin) ServiceInfo() limes.ServiceInfo {
 
 //Resources implements the limes.QuotaPlugin interface.
 func (p *swiftPlugin) Resources() []limes.ResourceInfo {
-	return swiftResources
+	return p.resources
 }
 
 func (p *swiftPlugin) Account(provider *gophercloud.ProviderClient, projectUUID string) (*schwift.Account, error) {
@@ -77,8 +110,34 @@ func (p *swiftPlugin) Account(provider *gophercloud.ProviderClient, projectUUID
 	if err != nil {
 		return nil, err
 	}
-	//TODO Make Auth prefix configurable
-	return resellerAccount.SwitchAccount("AUTH_" + projectUUID), nil
+	resellerPrefix := p.cfg.ObjectStore.ResellerPrefix
+	if resellerPrefix == "" {
+		resellerPrefix = "AUTH_"
+	}
+	return resellerAccount.SwitchAccount(resellerPrefix + projectUUID), nil
+}
+
+//swiftStoragePolicyQuotaHeader returns the header containing the capacity
+//quota for the given storage policy. This header is enforced by the
+//account_quotas middleware since Swift 2.32.0.
+func swiftStoragePolicyQuotaHeader(policy string) string {
+	return "X-Account-Quota-Bytes-Policy-" + policy
+}
+
+//swiftStoragePolicyUsageHeader returns the header containing the capacity
+//usage for the given storage policy.
+func swiftStoragePolicyUsageHeader(policy string) string {
+	return "X-Account-Storage-Policy-" + policy + "-Bytes-Used"
+}
+
+//swiftGetQuota reads a quota from the given header, or returns -1 if no quota
+//is set.
+func swiftGetQuota(headers schwift.AccountHeaders, key string) int64 {
+	value, err := strconv.ParseInt(headers.Get(key), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return value
 }
 
 //Scrape implements the limes.QuotaPlugin interface.
@@ -91,12 +150,11 @@ func (p *swiftPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, do
 	headers, err := account.Headers()
 	if schwift.Is(err, http.StatusNotFound) || schwift.Is(err, http.StatusGone) {
 		//Swift account does not exist or was deleted and not yet reaped, but the keystone project exist
-		return map[string]limes.ResourceData{
-			"capacity": {
-				Quota: 0,
-				Usage: 0,
-			},
-		}, nil
+		result := make(map[string]limes.ResourceData, len(p.resources))
+		for _, res := range p.resources {
+			result[res.Name] = limes.ResourceData{Quota: 0, Usage: 0}
+		}
+		return result, nil
 	} else if err != nil {
 		return nil, err
 	}
@@ -108,7 +166,33 @@ func (p *swiftPlugin) Scrape(provider *gophercloud.ProviderClient, clusterID, do
 	if !headers.BytesUsedQuota().Exists() {
 		data.Quota = -1
 	}
-	return map[string]limes.ResourceData{"capacity": data}, nil
+	result := map[string]limes.ResourceData{
+		"capacity": data,
+		"containers": {
+			Usage: headers.ContainerCount().Get(),
+			Quota: swiftGetQuota(headers, swiftContainersQuotaHeader),
+		},
+		"objects": {
+			Usage: headers.ObjectCount().Get(),
+			Quota: swiftGetQuota(headers, swiftObjectsQuotaHeader),
+		},
+	}
+	for _, policy := range p.cfg.ObjectStore.StoragePolicies {
+		//the header is missing if the account does not use this policy yet
+		var usage uint64
+		usageHeader := swiftStoragePolicyUsageHeader(policy)
+		if usageStr := headers.Get(usageHeader); usageStr != "" {
+			usage, err = strconv.ParseUint(usageStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %s: %s", usageHeader, err.Error())
+			}
+		}
+		result["capacity_"+policy] = limes.ResourceData{
+			Usage: usage,
+			Quota: swiftGetQuota(headers, swiftStoragePolicyQuotaHeader(policy)),
+		}
+	}
+	return result, nil
 }
 
 //SetQuota implements the limes.QuotaPlugin interface.
@@ -120,6 +204,11 @@ func (p *swiftPlugin) SetQuota(provider *gophercloud.ProviderClient, clusterID,
 
 	headers := schwift.NewAccountHeaders()
 	headers.BytesUsedQuota().Set(quotas["capacity"])
+	headers.Set(swiftContainersQuotaHeader, strconv.FormatUint(quotas["containers"], 10))
+	headers.Set(swiftObjectsQuotaHeader, strconv.FormatUint(quotas["objects"], 10))
+	for _, policy := range p.cfg.ObjectStore.StoragePolicies {
+		headers.Set(swiftStoragePolicyQuotaHeader(policy), strconv.FormatUint(quotas["capacity_"+policy], 10))
+	}
 	//this header brought to you by https://github.com/sapcc/swift-addons
 	headers.Set("X-Account-Project-Domain-Id-Override", domainUUID)
 
@@ -54,11 +54,12 @@ type ClusterService struct {
 //a single resource.
 type ClusterResource struct {
 	limes.ResourceInfo
-	Capacity      *uint64         `json:"capacity,omitempty"`
-	Comment       string          `json:"comment,omitempty"`
-	DomainsQuota  uint64          `json:"domains_quota,keepempty"`
-	Usage         uint64          `json:"usage,keepempty"`
-	Subcapacities util.JSONString `json:"subcapacities,omitempty"`
+	Capacity            *uint64         `json:"capacity,omitempty"`
+	Comment             string          `json:"comment,omitempty"`
+	DomainsQuota        uint64          `json:"domains_quota,keepempty"`
+	Usage               uint64          `json:"usage,keepempty"`
+	Subcapacities       util.JSONString `json:"subcapacities,omitempty"`
+	UnitConversionError string          `json:"unit_conversion_error,omitempty"`
 }
 
 //ClusterServices provides fast lookup of services using a map, but serializes
@@ -411,6 +412,16 @@ func GetClusters(config limes.Configuration, clusterID *string, localQuotaUsageO
 
 	}
 
+	//convert values into the requested units (if any)
+	for _, cluster := range clusters {
+		for serviceType, service := range cluster.Services {
+			for resourceName, resource := range service.Resources {
+				resource.UnitConversionError = filter.convertUnits(serviceType, resourceName, &resource.Unit,
+					resource.Capacity, &resource.DomainsQuota, &resource.Usage)
+			}
+		}
+	}
+
 	//flatten result (with stable order to keep the tests happy)
 	ids := make([]string, 0, len(clusters))
 	for id := range clusters {
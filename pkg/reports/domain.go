@@ -36,6 +36,9 @@ type Domain struct {
 	UUID     string         `json:"id"`
 	Name     string         `json:"name"`
 	Services DomainServices `json:"services,keepempty"`
+	//These are only shown for domains whose quotas are locked.
+	QuotaLocked     bool   `json:"quota_locked,omitempty"`
+	QuotaLockReason string `json:"quota_lock_reason,omitempty"`
 }
 
 //DomainService is a substructure of Domain containing data for
@@ -57,6 +60,11 @@ type DomainResource struct {
 	//These are pointers to values to enable precise control over whether this field is rendered in output.
 	BackendQuota         *uint64 `json:"backend_quota,omitempty"`
 	InfiniteBackendQuota *bool   `json:"infinite_backend_quota,omitempty"`
+	//These are only shown for temporary quota raises.
+	QuotaExpiresAt      *int64  `json:"quota_expires_at,omitempty"`
+	PreviousQuota       *uint64 `json:"previous_quota,omitempty"`
+	QuotaComment        string  `json:"quota_comment,omitempty"`
+	UnitConversionError string  `json:"unit_conversion_error,omitempty"`
 }
 
 //DomainServices provides fast lookup of services using a map, but serializes
@@ -108,7 +116,7 @@ var domainReportQuery1 = `
 `
 
 var domainReportQuery2 = `
-	SELECT d.uuid, d.name, ds.type, dr.name, dr.quota
+	SELECT d.uuid, d.name, d.quota_locked, d.quota_lock_reason, ds.type, dr.name, dr.quota, dr.quota_expires_at, dr.previous_quota, dr.quota_comment
 	  FROM domains d
 	  LEFT OUTER JOIN domain_services ds ON ds.domain_id = d.id {{AND ds.type = $service_type}}
 	  LEFT OUTER JOIN domain_resources dr ON dr.service_id = ds.id {{AND dr.name = $resource_name}}
@@ -188,14 +196,20 @@ func GetDomains(cluster *limes.Cluster, domainID *int64, dbi db.Interface, filte
 	whereStr, whereArgs = db.BuildSimpleWhereClause(fields, len(joinArgs))
 	err = db.ForeachRow(db.DB, fmt.Sprintf(queryStr, whereStr), append(joinArgs, whereArgs...), func(rows *sql.Rows) error {
 		var (
-			domainUUID   string
-			domainName   string
-			serviceType  *string
-			resourceName *string
-			quota        *uint64
+			domainUUID       string
+			domainName       string
+			domainLocked     bool
+			domainLockReason string
+			serviceType      *string
+			resourceName     *string
+			quota            *uint64
+			quotaExpiresAt   *util.Time
+			previousQuota    *uint64
+			quotaComment     *string
 		)
 		err := rows.Scan(
-			&domainUUID, &domainName, &serviceType, &resourceName, &quota,
+			&domainUUID, &domainName, &domainLocked, &domainLockReason,
+			&serviceType, &resourceName, &quota, &quotaExpiresAt, &previousQuota, &quotaComment,
 		)
 		if err != nil {
 			return err
@@ -204,9 +218,19 @@ func GetDomains(cluster *limes.Cluster, domainID *int64, dbi db.Interface, filte
 		domain, _, resource := domains.Find(cluster, domainUUID, serviceType, resourceName)
 
 		domain.Name = domainName
+		domain.QuotaLocked = domainLocked
+		domain.QuotaLockReason = domainLockReason
 
 		if resource != nil && quota != nil {
 			resource.DomainQuota = *quota
+			if quotaComment != nil {
+				resource.QuotaComment = *quotaComment
+			}
+			if quotaExpiresAt != nil {
+				expiresAt := time.Time(*quotaExpiresAt).Unix()
+				resource.QuotaExpiresAt = &expiresAt
+				resource.PreviousQuota = previousQuota
+			}
 		}
 
 		return nil
@@ -215,6 +239,16 @@ func GetDomains(cluster *limes.Cluster, domainID *int64, dbi db.Interface, filte
 		return nil, err
 	}
 
+	//convert values into the requested units (if any)
+	for _, domain := range domains {
+		for serviceType, service := range domain.Services {
+			for resourceName, resource := range service.Resources {
+				resource.UnitConversionError = filter.convertUnits(serviceType, resourceName, &resource.Unit,
+					&resource.DomainQuota, &resource.ProjectsQuota, &resource.Usage, resource.BackendQuota, resource.PreviousQuota)
+			}
+		}
+	}
+
 	//flatten result (with stable order to keep the tests happy)
 	uuids := make([]string, 0, len(domains))
 	for uuid := range domains {
@@ -22,6 +22,7 @@ package reports
 import (
 	"net/http"
 	"regexp"
+	"strings"
 
 	"github.com/sapcc/limes/pkg/db"
 	"github.com/sapcc/limes/pkg/limes"
@@ -32,6 +33,10 @@ import (
 type Filter struct {
 	serviceTypes  []string
 	resourceNames []string
+	//from ?unit=<unit>
+	defaultUnit limes.Unit
+	//from ?unit=<service>/<resource>:<unit>
+	resourceUnits map[string]limes.Unit
 }
 
 //ReadFilter extracts a Filter from the given Request.
@@ -79,9 +84,66 @@ func ReadFilter(r *http.Request) Filter {
 		}
 	}
 
+	for _, value := range queryValues["unit"] {
+		idx := strings.Index(value, ":")
+		if idx == -1 {
+			f.defaultUnit = limes.Unit(value)
+			continue
+		}
+		if f.resourceUnits == nil {
+			f.resourceUnits = make(map[string]limes.Unit)
+		}
+		f.resourceUnits[value[:idx]] = limes.Unit(value[idx+1:])
+	}
+
 	return f
 }
 
+//convertUnits converts the given values of a single resource into the unit
+//requested for this resource via ?unit=, and updates the resource's unit
+//accordingly. Either all values are converted, or none of them: If one of the
+//values cannot be represented exactly in the requested unit, all values stay
+//in the resource's native unit, and an error message is returned that explains
+//why the conversion was not done.
+//
+//A unit given via ?unit=<unit> only applies to resources with a compatible
+//unit. A unit given via ?unit=<service>/<resource>:<unit> applies to that
+//resource only, and an incompatible unit is reported as an error message.
+func (f Filter) convertUnits(serviceType, resourceName string, unit *limes.Unit, values ...*uint64) string {
+	targetUnit, exists := f.resourceUnits[serviceType+"/"+resourceName]
+	if !exists {
+		targetUnit = f.defaultUnit
+		sourceBase, _ := unit.Base()
+		targetBase, _ := targetUnit.Base()
+		if targetUnit == limes.UnitNone || *unit == limes.UnitNone || sourceBase != targetBase {
+			return ""
+		}
+	}
+	if targetUnit == *unit {
+		return ""
+	}
+
+	converted := make([]uint64, len(values))
+	for idx, value := range values {
+		if value == nil {
+			continue
+		}
+		result, err := limes.ValueWithUnit{Value: *value, Unit: *unit}.ConvertTo(targetUnit)
+		if err != nil {
+			return err.Error()
+		}
+		converted[idx] = result.Value
+	}
+
+	for idx, value := range values {
+		if value != nil {
+			*value = converted[idx]
+		}
+	}
+	*unit = targetUnit
+	return ""
+}
+
 var filterPrepareRx = regexp.MustCompile(`{{AND ([a-z.]+) = \$(service_type|resource_name)}}`)
 
 //PrepareQuery takes a SQL query string, and replaces the following
@@ -37,6 +37,12 @@ type Project struct {
 	Name       string          `json:"name"`
 	ParentUUID string          `json:"parent_id"`
 	Services   ProjectServices `json:"services,keepempty"`
+	//These are only shown for projects that a quota class was applied to.
+	QuotaClass        string `json:"quota_class,omitempty"`
+	QuotaClassDrifted bool   `json:"quota_class_drifted,omitempty"`
+	//These are only shown for projects whose quotas are locked.
+	QuotaLocked     bool   `json:"quota_locked,omitempty"`
+	QuotaLockReason string `json:"quota_lock_reason,omitempty"`
 }
 
 //ProjectService is a substructure of Project containing data for
@@ -53,9 +59,35 @@ type ProjectResource struct {
 	limes.ResourceInfo
 	Quota uint64 `json:"quota,keepempty"`
 	Usage uint64 `json:"usage,keepempty"`
+	//This is only shown if the backend reports quota reservations.
+	Reserved uint64 `json:"reserved,omitempty"`
 	//This is a pointer to a value to enable precise control over whether this field is rendered in output.
 	BackendQuota *int64          `json:"backend_quota,omitempty"`
 	Subresources util.JSONString `json:"subresources,omitempty"`
+	//These are only shown for temporary quota raises.
+	QuotaExpiresAt *int64  `json:"quota_expires_at,omitempty"`
+	PreviousQuota  *uint64 `json:"previous_quota,omitempty"`
+	//This is only shown when a comment was given for the last quota change.
+	QuotaComment string `json:"quota_comment,omitempty"`
+	//This is only shown when the quota differs from the project's quota class.
+	ClassQuota          *uint64 `json:"class_quota,omitempty"`
+	UnitConversionError string  `json:"unit_conversion_error,omitempty"`
+}
+
+func (r *ProjectResource) convertUnits(filter Filter, serviceType, resourceName string) {
+	//the backend quota may be negative (for infinite quota), and those values
+	//do not need to be converted
+	var backendQuota *uint64
+	if r.BackendQuota != nil && *r.BackendQuota >= 0 {
+		value := uint64(*r.BackendQuota)
+		backendQuota = &value
+	}
+
+	r.UnitConversionError = filter.convertUnits(serviceType, resourceName, &r.Unit, &r.Quota, &r.Usage, &r.Reserved, backendQuota, r.PreviousQuota, r.ClassQuota)
+	if backendQuota != nil {
+		value := int64(*backendQuota)
+		r.BackendQuota = &value
+	}
 }
 
 //ProjectServices provides fast lookup of services using a map, but serializes
@@ -97,7 +129,7 @@ func (r ProjectResources) MarshalJSON() ([]byte, error) {
 }
 
 var projectReportQuery = `
-	SELECT p.uuid, p.name, COALESCE(p.parent_uuid, ''), ps.type, ps.scraped_at, pr.name, pr.quota, pr.usage, pr.backend_quota, pr.subresources
+	SELECT p.uuid, p.name, COALESCE(p.parent_uuid, ''), p.quota_class, p.quota_locked, p.quota_lock_reason, ps.type, ps.scraped_at, pr.name, pr.quota, pr.usage, pr.reserved, pr.backend_quota, pr.subresources, pr.quota_expires_at, pr.previous_quota, pr.quota_comment
 	  FROM projects p
 	  LEFT OUTER JOIN project_services ps ON ps.project_id = p.id {{AND ps.type = $service_type}}
 	  LEFT OUTER JOIN project_resources pr ON pr.service_id = ps.id {{AND pr.name = $resource_name}}
@@ -131,18 +163,27 @@ func GetProjects(cluster *limes.Cluster, domainID int64, projectID *int64, dbi d
 			projectUUID       string
 			projectName       string
 			projectParentUUID string
+			projectQuotaClass string
+			projectLocked     bool
+			projectLockReason string
 			serviceType       *string
 			scrapedAt         *util.Time
 			resourceName      *string
 			quota             *uint64
 			usage             *uint64
+			reserved          *uint64
 			backendQuota      *int64
 			subresources      *string
+			quotaExpiresAt    *util.Time
+			previousQuota     *uint64
+			quotaComment      *string
 		)
 		err := rows.Scan(
-			&projectUUID, &projectName, &projectParentUUID,
+			&projectUUID, &projectName, &projectParentUUID, &projectQuotaClass,
+			&projectLocked, &projectLockReason,
 			&serviceType, &scrapedAt, &resourceName,
-			&quota, &usage, &backendQuota, &subresources,
+			&quota, &usage, &reserved, &backendQuota, &subresources,
+			&quotaExpiresAt, &previousQuota, &quotaComment,
 		)
 		if err != nil {
 			rows.Close()
@@ -152,10 +193,13 @@ func GetProjects(cluster *limes.Cluster, domainID int64, projectID *int64, dbi d
 		project, exists := projects[projectUUID]
 		if !exists {
 			project = &Project{
-				UUID:       projectUUID,
-				Name:       projectName,
-				ParentUUID: projectParentUUID,
-				Services:   make(ProjectServices),
+				UUID:            projectUUID,
+				Name:            projectName,
+				ParentUUID:      projectParentUUID,
+				Services:        make(ProjectServices),
+				QuotaClass:      projectQuotaClass,
+				QuotaLocked:     projectLocked,
+				QuotaLockReason: projectLockReason,
 			}
 			projects[projectUUID] = project
 		}
@@ -200,12 +244,23 @@ func GetProjects(cluster *limes.Cluster, domainID int64, projectID *int64, dbi d
 		if usage != nil {
 			resource.Usage = *usage
 		}
+		if reserved != nil {
+			resource.Reserved = *reserved
+		}
 		if quota != nil {
 			resource.Quota = *quota
 			if backendQuota != nil && (*backendQuota < 0 || uint64(*backendQuota) != *quota) {
 				resource.BackendQuota = backendQuota
 			}
 		}
+		if quotaExpiresAt != nil {
+			expiresAt := time.Time(*quotaExpiresAt).Unix()
+			resource.QuotaExpiresAt = &expiresAt
+			resource.PreviousQuota = previousQuota
+		}
+		if quotaComment != nil {
+			resource.QuotaComment = *quotaComment
+		}
 		service.Resources[*resourceName] = resource
 	}
 	err = rows.Err()
@@ -218,6 +273,34 @@ func GetProjects(cluster *limes.Cluster, domainID int64, projectID *int64, dbi d
 		return nil, err
 	}
 
+	//check whether projects have drifted from their quota class (this must
+	//happen before the unit conversion since the class quotas are in the
+	//resources' native units)
+	for _, project := range projects {
+		class, exists := cluster.QuotaClasses[project.QuotaClass]
+		if !exists {
+			continue
+		}
+		for serviceType, service := range project.Services {
+			for resourceName, resource := range service.Resources {
+				classQuota, exists := class[serviceType][resourceName]
+				if exists && classQuota != resource.Quota {
+					resource.ClassQuota = &classQuota
+					project.QuotaClassDrifted = true
+				}
+			}
+		}
+	}
+
+	//convert values into the requested units (if any)
+	for _, project := range projects {
+		for serviceType, service := range project.Services {
+			for resourceName, resource := range service.Resources {
+				resource.convertUnits(filter, serviceType, resourceName)
+			}
+		}
+	}
+
 	//flatten result (with stable order to keep the tests happy)
 	uuids := make([]string, 0, len(projects))
 	for uuid := range projects {
@@ -97,8 +97,9 @@ func (p *Plugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainU
 	if exists {
 		for resourceName, quota := range data {
 			result[resourceName] = limes.ResourceData{
-				Quota: int64(quota),
-				Usage: result[resourceName].Usage,
+				Quota:    int64(quota),
+				Usage:    result[resourceName].Usage,
+				Reserved: result[resourceName].Reserved,
 			}
 		}
 	}
@@ -114,6 +115,7 @@ func (p *Plugin) Scrape(provider *gophercloud.ProviderClient, clusterID, domainU
 	result["things"] = limes.ResourceData{
 		Quota:        result["things"].Quota,
 		Usage:        result["things"].Usage,
+		Reserved:     result["things"].Reserved,
 		Subresources: subres,
 	}
 